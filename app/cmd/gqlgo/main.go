@@ -0,0 +1,538 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/config"
+	"github.com/RoGogDBD/GQLGo/internal/config/migrate"
+	"github.com/RoGogDBD/GQLGo/internal/filter"
+	"github.com/RoGogDBD/GQLGo/internal/handler"
+	"github.com/RoGogDBD/GQLGo/internal/lifecycle"
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+	"github.com/RoGogDBD/GQLGo/internal/metrics"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/qraphql/graph"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+	"github.com/RoGogDBD/GQLGo/internal/service"
+	"github.com/RoGogDBD/GQLGo/internal/storage"
+	"github.com/RoGogDBD/GQLGo/internal/tracing"
+	"github.com/RoGogDBD/GQLGo/internal/trusted"
+)
+
+const (
+	msgNoDSNConfig  = "config error: отсутствует DSN"
+	msgNoAddrConfig = "config error: отсутствует ADDR"
+
+	usage = "использование: gqlgo [флаги] [migrate ...], флаги: gqlgo -h"
+
+	schedulerInterval = 15 * time.Second
+)
+
+// devSeedUsers демо-пользователи памяти для разработки, те же что в миграции users.
+// Добавляются только с MEMORY_SEED_USERS.
+var devSeedUsers = []*models.User{
+	{ID: "11111111-1111-1111-1111-111111111111", Username: "ASDASd"},
+	{ID: "22222222-2222-2222-2222-222222222222", Username: "asd"},
+	{ID: "33333333-3333-3333-3333-333333333333", Username: "wevbwb"},
+}
+
+func main() {
+	// ===================== Кофигурация =====================
+	cfg, args, cfgErr := config.Load(os.Args[1:])
+	if errors.Is(cfgErr, flag.ErrHelp) {
+		return
+	}
+	if args.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			cfgErr = errors.Join(cfgErr, err)
+		}
+		if cfgErr != nil {
+			_, _ = fmt.Fprintln(os.Stderr, cfgErr)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// ===================== Логгер =====================
+	logger, cleanup, err := config.NewLogger(cfg.Log)
+	if err != nil {
+		// Неверный LOG_* попадет в ошибку конфига, пишем ее логгером по умолчанию.
+		logger, cleanup, err = config.NewLogger(config.LogConfig{})
+	}
+	if err != nil {
+		_, _ = os.Stdout.WriteString("ошибка при инициализации логера\n")
+		os.Exit(1)
+	}
+	defer cleanup()
+
+	if cfgErr != nil {
+		if errors.Is(cfgErr, config.ErrNoDSN) {
+			logger.Errorf(msgNoDSNConfig)
+		}
+		if errors.Is(cfgErr, config.ErrNoAddress) {
+			logger.Errorf(msgNoAddrConfig)
+		}
+		logger.Errorf("error: %v", cfgErr)
+		os.Exit(1)
+	}
+
+	// ===================== Миграции =====================
+	if len(args.Rest) > 0 {
+		if args.Rest[0] != "migrate" {
+			logger.Errorf("неизвестная команда %q, %s", args.Rest[0], usage)
+			os.Exit(2)
+		}
+		if err := runMigrate(cfg, args.Rest[1:], os.Stdout); err != nil {
+			logger.Errorf("migrate: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// ===================== Запуск сервера =====================
+	if err := run(cfg, logger); err != nil {
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("error: %v", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(cfg config.Config, logger logger.Logger) error {
+	// ===================== Хранилище =====================
+	var (
+		userRepo    repository.UserRepo
+		postRepo    repository.PostRepo
+		commentRepo repository.CommentRepo
+		tagRepo     repository.TagRepo
+		notifRepo   repository.NotificationRepo
+		followRepo  repository.FollowRepo
+		bookmarks   repository.BookmarkRepo
+		readState   repository.ReadStateRepo
+		reportRepo  repository.ReportRepo
+		banRepo     repository.BanRepo
+		auditRepo   repository.AuditRepo
+		transactor  repository.Transactor
+		cleanup     func() error
+	)
+
+	// ===================== Трассировка =====================
+	flushTraces, err := tracing.Setup(context.Background(), tracingConfig(cfg.Tracing), func(err error) {
+		logger.Errorf("tracing: %v", err)
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := flushTraces(ctx); err != nil {
+			logger.Errorf("tracing: %v", err)
+		}
+	}()
+	traceSQL := cfg.Tracing.Exporter != tracing.ExporterNone
+
+	health := handler.NewHealth()
+	var stats *metrics.Metrics
+	if cfg.Metrics.Enabled {
+		stats = metrics.New(cfg.Metrics.SlowField)
+	}
+
+	if cfg.DB.AutoMigrate && (cfg.Storage == config.StoragePostgres || cfg.Storage == config.StorageSQLite) {
+		if err := applyMigrations(cfg); err != nil {
+			return fmt.Errorf("run migrations: %w", err)
+		}
+	}
+
+	switch cfg.Storage {
+	case config.StorageSQLite:
+		st, err := storage.NewSQLiteStorage(cfg.SQLite.Path, cfg.DB.Pool)
+		if err != nil {
+			return err
+		}
+		cleanup = st.Close
+		health.AddCheck("db", st.Ping)
+		health.AddCheck("migrations", schemaCheck(st.DB().DB, migrate.CurrentSQLite))
+		if err := dbMetrics(stats, st); err != nil {
+			return err
+		}
+		dbLogging(st, logger)
+		if traceSQL {
+			dbTracing(st)
+		}
+		userRepo, err = repository.NewSQLiteUserRepo(st.DB())
+		if err != nil {
+			return err
+		}
+		postRepo, err = repository.NewSQLitePostRepo(st.DB())
+		if err != nil {
+			return err
+		}
+		commentRepo, err = repository.NewSQLiteCommentRepo(st.DB())
+		if err != nil {
+			return err
+		}
+		tagRepo, err = repository.NewSQLiteTagRepo(st.DB())
+		if err != nil {
+			return err
+		}
+		notifRepo, err = repository.NewSQLiteNotificationRepo(st.DB())
+		if err != nil {
+			return err
+		}
+		followRepo, err = repository.NewSQLiteFollowRepo(st.DB())
+		if err != nil {
+			return err
+		}
+		bookmarks, err = repository.NewSQLiteBookmarkRepo(st.DB())
+		if err != nil {
+			return err
+		}
+		readState, err = repository.NewSQLiteReadStateRepo(st.DB())
+		if err != nil {
+			return err
+		}
+		reportRepo, err = repository.NewSQLiteReportRepo(st.DB())
+		if err != nil {
+			return err
+		}
+		banRepo, err = repository.NewSQLiteBanRepo(st.DB())
+		if err != nil {
+			return err
+		}
+		auditRepo, err = repository.NewSQLiteAuditRepo(st.DB())
+		if err != nil {
+			return err
+		}
+		transactor = repository.NewSQLiteTransactor(st.DB())
+	case config.StoragePostgres:
+		st, err := storage.NewDataStorage(cfg.DB.DSN, cfg.DB.Pool)
+		if err != nil {
+			return err
+		}
+		cleanup = st.Close
+		if cfg.DB.ReplicaDSN != "" {
+			if err := st.AttachReplica(cfg.DB.ReplicaDSN); err != nil {
+				_ = st.Close()
+				return err
+			}
+		}
+		health.AddCheck("db", st.Ping)
+		health.AddCheck("migrations", schemaCheck(st.DB().DB, migrate.CurrentPostgres))
+		if err := dbMetrics(stats, st); err != nil {
+			return err
+		}
+		dbLogging(st, logger)
+		if traceSQL {
+			dbTracing(st)
+		}
+		replica := repository.WithReplica(st.Replica())
+		userRepo, err = repository.NewPostgresUserRepo(st.DB(), replica)
+		if err != nil {
+			return err
+		}
+		postRepo, err = repository.NewPostgresPostRepo(st.DB(), replica)
+		if err != nil {
+			return err
+		}
+		commentRepo, err = repository.NewPostgresCommentRepo(st.DB(), replica)
+		if err != nil {
+			return err
+		}
+		tagRepo, err = repository.NewPostgresTagRepo(st.DB(), replica)
+		if err != nil {
+			return err
+		}
+		notifRepo, err = repository.NewPostgresNotificationRepo(st.DB(), replica)
+		if err != nil {
+			return err
+		}
+		followRepo, err = repository.NewPostgresFollowRepo(st.DB(), replica)
+		if err != nil {
+			return err
+		}
+		bookmarks, err = repository.NewPostgresBookmarkRepo(st.DB(), replica)
+		if err != nil {
+			return err
+		}
+		readState, err = repository.NewPostgresReadStateRepo(st.DB(), replica)
+		if err != nil {
+			return err
+		}
+		reportRepo, err = repository.NewPostgresReportRepo(st.DB(), replica)
+		if err != nil {
+			return err
+		}
+		banRepo, err = repository.NewPostgresBanRepo(st.DB())
+		if err != nil {
+			return err
+		}
+		auditRepo, err = repository.NewPostgresAuditRepo(st.DB(), replica)
+		if err != nil {
+			return err
+		}
+		transactor = repository.NewPostgresTransactor(st.DB())
+	default:
+		st, err := repository.NewMemoryStorage(memoryOptions(cfg.Memory, logger)...)
+		if err != nil {
+			return err
+		}
+		if cfg.Memory.SeedUsers {
+			logger.Warnf("MEMORY_SEED_USERS: добавлены демо-пользователи, только для разработки")
+			st.SeedUsers(devSeedUsers...)
+		}
+		userRepo = repository.NewMemoryUserRepo(st)
+		postRepo = repository.NewMemoryPostRepo(st)
+		commentRepo = repository.NewMemoryCommentRepo(st)
+		tagRepo = repository.NewMemoryTagRepo(st)
+		notifRepo = repository.NewMemoryNotificationRepo(st)
+		followRepo = repository.NewMemoryFollowRepo(st)
+		bookmarks = repository.NewMemoryBookmarkRepo(st)
+		readState = repository.NewMemoryReadStateRepo(st)
+		reportRepo = repository.NewMemoryReportRepo(st)
+		banRepo = repository.NewMemoryBanRepo(st)
+		auditRepo = repository.NewMemoryAuditRepo(repository.DefaultAuditCapacity)
+		transactor = repository.NewMemoryTransactor(st)
+		cleanup = st.Close
+	}
+	// ===================== Остановка =====================
+	lc := lifecycle.New(lifecycle.Config{
+		MutationTimeout:     cfg.Server.ShutdownTimeout,
+		SubscriptionTimeout: cfg.Server.SubscriptionsTimeout,
+		JobTimeout:          cfg.Server.JobsTimeout,
+	}, logger)
+	// На любом выходе из run, после сигнала остановки повторный вызов ничего не делает.
+	defer func() {
+		if err := lc.Shutdown(); err != nil {
+			logger.Errorf("остановка: %v", err)
+		}
+	}()
+	lc.OnClose("storage", cleanup)
+
+	notificationService := service.NewNotificationService(notifRepo, userRepo, logger)
+	moderationService := service.NewModerationService(reportRepo, banRepo, postRepo, commentRepo, userRepo, transactor, newContentFilters(cfg.Filters), logger)
+	postService := service.NewPostService(postRepo, tagRepo, transactor, service.NewNotifier[*models.Post](), notificationService, moderationService)
+	commentNotifier := service.NewCommentNotifier(logger)
+	lc.OnClose("notifier", func() error {
+		commentNotifier.Close()
+		return nil
+	})
+	if stats != nil {
+		if err := stats.AddSubscriptions(commentNotifier); err != nil {
+			return err
+		}
+	}
+	resolver := &graph.Resolver{
+		UserRepo:        userRepo,
+		PostRepo:        postRepo,
+		CommentRepo:     commentRepo,
+		TagRepo:         tagRepo,
+		CommentNotifier: commentNotifier,
+		Logger:          logger,
+		PostService:     postService,
+		CommentService:  service.NewCommentService(postService, commentRepo, transactor, commentNotifier, notificationService, moderationService, logger),
+
+		NotificationService: notificationService,
+		FollowService:       service.NewFollowService(followRepo, userRepo),
+		ReaderService:       service.NewReaderService(postService, bookmarks, readState),
+		ModerationService:   moderationService,
+		AuditService:        service.NewAuditService(auditRepo, transactor, postRepo, userRepo, reportRepo),
+	}
+
+	// ===================== Фоновые задачи =====================
+	lc.Go("post scheduler", service.NewPostScheduler(postService, schedulerInterval, logger).Run)
+
+	var manifest *trusted.Manifest
+	if mode := trusted.Mode(cfg.TrustedDocuments.Mode); mode == trusted.ModeReport || mode == trusted.ModeEnforce {
+		if manifest, err = trusted.Load(cfg.TrustedDocuments.Manifest); err != nil {
+			return fmt.Errorf("доверенные документы: %w", err)
+		}
+		logger.Infof("доверенные документы: %s, режим %s", cfg.TrustedDocuments.Manifest, mode)
+	}
+	if stats != nil {
+		stats.AddOperations(cfg.Metrics.Operations...)
+		if manifest != nil {
+			stats.AddOperations(manifest.OperationNames()...)
+		}
+	}
+
+	router := handler.NewRouter(resolver, handler.RouterConfig{
+		QueryTimeout:         cfg.DB.QueryTimeout,
+		ComplexityLimit:      cfg.Limits.QueryComplexity,
+		QueryCacheSize:       cfg.Limits.QueryCacheSize,
+		APQCacheSize:         cfg.Limits.APQCacheSize,
+		RoleToken:            cfg.Auth.RoleToken,
+		DisableSubscriptions: !cfg.Subscriptions.Enabled,
+		KeepAlive:            cfg.Subscriptions.KeepAlive,
+		Health:               health,
+		Metrics:              stats,
+		MetricsElsewhere:     cfg.Metrics.Addr != "",
+		TraceResolvers:       cfg.Tracing.Resolvers,
+		Lifecycle:            lc,
+		CORS: handler.CORSConfig{
+			Origins:     cfg.HTTP.CORSOrigins,
+			Headers:     cfg.HTTP.CORSHeaders,
+			Credentials: cfg.HTTP.CORSCredentials,
+			MaxAge:      cfg.HTTP.CORSMaxAge,
+		},
+		WebsocketOrigins:     cfg.HTTP.WebsocketOrigins,
+		TrustedProxies:       cfg.HTTP.TrustedProxies,
+		MaxBodySize:          int64(cfg.Limits.MaxBodySize),
+		MaxBatchSize:         cfg.Limits.MaxBatchSize,
+		HSTS:                 cfg.HTTP.HSTS,
+		DisableIntrospection: !cfg.HTTP.Introspection,
+		DisablePlayground:    !cfg.HTTP.Playground,
+		TrustedDocuments:     manifest,
+		TrustedMode:          trusted.Mode(cfg.TrustedDocuments.Mode),
+	})
+	if cfg.HTTP.Playground {
+		logger.Infof("connect to %s for GraphQL playground", cfg.Server.Addr)
+	} else {
+		logger.Infof("listening on %s", cfg.Server.Addr)
+	}
+
+	srv := &http.Server{
+		Addr:    cfg.Server.Addr,
+		Handler: router,
+	}
+
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	// /metrics на внутреннем адресе: состав подписок и имена операций не видны снаружи.
+	var metricsSrv *http.Server
+	if stats != nil && cfg.Metrics.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", stats.Handler())
+		metricsSrv = &http.Server{Addr: cfg.Metrics.Addr, Handler: mux}
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("метрики: %w", err)
+			}
+		}()
+		logger.Infof("metrics on %s", cfg.Metrics.Addr)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-stop:
+		logger.Infof("shutdown signal received")
+		// Сначала /readyz отвечает 503, и только после паузы сервер перестает принимать запросы.
+		health.Drain()
+		if cfg.Server.DrainDelay > 0 {
+			time.Sleep(cfg.Server.DrainDelay)
+		}
+		// Новые подписки и мутации по websocket отклоняются, пока сервер дожидается HTTP запросов.
+		lc.Drain()
+		timeout := cfg.Server.ShutdownTimeout
+		if timeout <= 0 {
+			timeout = config.DefaultShutdownTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if metricsSrv != nil {
+			_ = metricsSrv.Shutdown(ctx)
+		}
+		if err := srv.Shutdown(ctx); err != nil {
+			return err
+		}
+		err := <-errCh
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		// Мутации, websocket соединения, фоновые задачи, рассылка и хранилище.
+		return lc.Shutdown()
+	}
+}
+
+// schemaCheck проверка готовности: схема без dirty и непримененных миграций.
+func schemaCheck(db *sql.DB, current func(context.Context, *sql.DB) (migrate.Status, error)) handler.Check {
+	return func(ctx context.Context) error {
+		st, err := current(ctx, db)
+		if err != nil {
+			return err
+		}
+		return st.Err()
+	}
+}
+
+// dbMetrics пул основной базы и реплики в метриках.
+func dbMetrics(stats *metrics.Metrics, st *storage.DBStorage) error {
+	if stats == nil {
+		return nil
+	}
+	if err := stats.AddDB("primary", st.DB().DB); err != nil {
+		return err
+	}
+	if replica := st.Replica(); replica != nil {
+		return stats.AddDB("replica", replica.DB)
+	}
+	return nil
+}
+
+// dbLogging SQL запросы основной базы и реплики в лог.
+func dbLogging(st *storage.DBStorage, logger logger.Logger) {
+	st.DB().AddQueryHook(repository.QueryLogHook{Logger: logger})
+	if replica := st.Replica(); replica != nil {
+		replica.AddQueryHook(repository.QueryLogHook{Logger: logger.With("db", "replica")})
+	}
+}
+
+// dbTracing спаны SQL запросов основной базы и реплики.
+func dbTracing(st *storage.DBStorage) {
+	st.DB().AddQueryHook(tracing.QueryHook{})
+	if replica := st.Replica(); replica != nil {
+		replica.AddQueryHook(tracing.QueryHook{})
+	}
+}
+
+// tracingConfig трассировка из конфига.
+func tracingConfig(cfg config.TracingConfig) tracing.Config {
+	return tracing.Config{
+		Exporter:    cfg.Exporter,
+		Endpoint:    cfg.Endpoint,
+		ServiceName: cfg.ServiceName,
+		SampleRatio: cfg.SampleRatio,
+	}
+}
+
+// newContentFilters фильтры контента из конфига, каждый срабатывает с весом 1.
+func newContentFilters(cfg config.FilterConfig) *filter.Pipeline {
+	return filter.NewPipeline(filter.Mode(cfg.Mode), cfg.Threshold,
+		filter.NewBlockList(cfg.BlockList, 1),
+		filter.NewLinkLimit(cfg.MaxLinks, 1),
+		filter.NewDuplicate(cfg.DuplicateWindow, 1),
+	)
+}
+
+// memoryOptions с MEMORY_DIR данные сохраняются на диск и не устаревают.
+func memoryOptions(cfg config.MemoryConfig, logger logger.Logger) []repository.MemoryOption {
+	if cfg.Dir == "" {
+		return nil
+	}
+	return []repository.MemoryOption{
+		repository.WithTTL(0),
+		repository.WithPersistence(repository.PersistConfig{
+			Dir:             cfg.Dir,
+			Fsync:           repository.FsyncPolicy(cfg.Fsync),
+			CompactInterval: cfg.CompactInterval,
+			OnError:         func(err error) { logger.Errorf("хранилище: %v", err) },
+		}),
+	}
+}
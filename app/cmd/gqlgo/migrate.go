@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/RoGogDBD/GQLGo/internal/config"
+	"github.com/RoGogDBD/GQLGo/internal/config/migrate"
+)
+
+const migrateUsage = "использование: gqlgo [флаги] migrate up|down|to N|status|force N"
+
+var (
+	errMigrateUsage   = errors.New(migrateUsage)
+	errMigrateStorage = errors.New("миграции есть только у STORAGE=postgres и sqlite")
+)
+
+// runMigrate подкоманда migrate: миграции отдельным шагом деплоя.
+func runMigrate(cfg config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+	cmd, args := args[0], args[1:]
+
+	m, err := newMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = m.Close() }()
+
+	switch {
+	case cmd == "up" && len(args) == 0:
+		err = m.Up()
+	case cmd == "down" && len(args) == 0:
+		err = m.Down()
+	case cmd == "to" && len(args) == 1:
+		v, perr := strconv.ParseUint(args[0], 10, 32)
+		if perr != nil {
+			return fmt.Errorf("версия %q: %w", args[0], errMigrateUsage)
+		}
+		err = m.To(uint(v))
+	case cmd == "force" && len(args) == 1:
+		v, perr := strconv.Atoi(args[0])
+		if perr != nil || v < -1 {
+			return fmt.Errorf("версия %q: %w", args[0], errMigrateUsage)
+		}
+		err = m.Force(v)
+	case cmd == "status" && len(args) == 0:
+	default:
+		return errMigrateUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	st, err := m.Status()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, formatStatus(st))
+	return err
+}
+
+// applyMigrations автоматические миграции при старте сервера.
+func applyMigrations(cfg config.Config) error {
+	m, err := newMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	return errors.Join(m.Up(), m.Close())
+}
+
+// newMigrator миграции выбранного хранилища.
+func newMigrator(cfg config.Config) (*migrate.Migrator, error) {
+	switch cfg.Storage {
+	case config.StoragePostgres:
+		return migrate.NewPostgres(cfg.DB.DSN)
+	case config.StorageSQLite:
+		return migrate.NewSQLite(cfg.SQLite.Path)
+	default:
+		return nil, errMigrateStorage
+	}
+}
+
+func formatStatus(st migrate.Status) string {
+	s := fmt.Sprintf("версия %d из %d", st.Version, st.Latest)
+	if st.Dirty {
+		s += ", dirty: исправьте схему и выполните force"
+	} else if st.Pending() {
+		s += ", есть непримененные миграции"
+	}
+	return s
+}
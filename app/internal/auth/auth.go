@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/RoGogDBD/GQLGo/internal/models"
+)
+
+// Заголовок и ключ init payload с id пользователя.
+const (
+	HeaderUserID      = "X-User-ID"
+	InitPayloadUserID = "userId"
+)
+
+// Заголовок и ключ init payload с токеном ролей.
+const (
+	HeaderRoleToken      = "X-Role-Token"
+	InitPayloadRoleToken = "roleToken"
+)
+
+// Viewer текущий пользователь запроса.
+type Viewer struct {
+	UserID string
+	Role   models.Role
+}
+
+type viewerKey struct{}
+
+// WithViewer кладет пользователя в контекст.
+func WithViewer(ctx context.Context, v Viewer) context.Context {
+	v.UserID = strings.TrimSpace(v.UserID)
+	return context.WithValue(ctx, viewerKey{}, v)
+}
+
+// ViewerFrom достает пользователя из контекста, ok=false для анонима.
+func ViewerFrom(ctx context.Context) (Viewer, bool) {
+	v, _ := ctx.Value(viewerKey{}).(Viewer)
+	return v, v.UserID != ""
+}
+
+// IsAuthor проверяет, что пользователь - автор.
+func (v Viewer) IsAuthor(authorID string) bool {
+	return v.UserID != "" && v.UserID == authorID
+}
+
+// IsModerator модераторы и администраторы видят скрытый контент и очередь жалоб.
+func (v Viewer) IsModerator() bool {
+	return v.UserID != "" && (v.Role == models.RoleModerator || v.Role == models.RoleAdmin)
+}
+
+// Elevated роль модератора или администратора.
+func Elevated(role models.Role) bool {
+	return role == models.RoleModerator || role == models.RoleAdmin
+}
+
+// CheckRoleToken сверяет токен ролей клиента с секретом сервера.
+// Без секрета токен не подходит никогда.
+func CheckRoleToken(secret, token string) bool {
+	return secret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(token)) == 1
+}
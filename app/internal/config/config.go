@@ -3,53 +3,275 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/storage"
+)
+
+// Хранилища, которые можно выбрать через STORAGE.
+const (
+	StorageMemory   = "memory"
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
 )
 
+// Экспортеры спанов для TRACING_EXPORTER.
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+// Режимы доверенных документов для TRUSTED_DOCUMENTS_MODE.
+const (
+	TrustedOff     = "off"
+	TrustedReport  = "report"
+	TrustedEnforce = "enforce"
+)
+
+const defaultSQLitePath = "gqlgo.db"
+
+// Config настройки сервиса. Источники по возрастанию приоритета: значения
+// по умолчанию, файл (--config или CONFIG_FILE), env, флаги командной строки.
+// Тег cfg - ключ в файле, env - переменная окружения, из нее же имя флага
+// (DB_MAX_OPEN_CONNS -> --db-max-open-conns), secret - значение скрывается при печати.
 type Config struct {
-	Server      ServerConfig
-	DB          DataBase
-	UsePostgres bool
+	Server ServerConfig `cfg:"server"`
+	DB     DataBase     `cfg:"db"`
+	// Storage memory, postgres или sqlite.
+	Storage string `cfg:"storage" env:"STORAGE"`
+	// UsePostgres совпадает с Storage == postgres, оставлен для совместимости.
+	UsePostgres   bool
+	SQLite        SQLiteConfig        `cfg:"sqlite"`
+	Filters       FilterConfig        `cfg:"filters"`
+	Memory        MemoryConfig        `cfg:"memory"`
+	Limits        LimitsConfig        `cfg:"limits"`
+	Log           LogConfig           `cfg:"log"`
+	Auth          AuthConfig          `cfg:"auth"`
+	Subscriptions SubscriptionsConfig `cfg:"subscriptions"`
+	Metrics       MetricsConfig       `cfg:"metrics"`
+	Tracing       TracingConfig       `cfg:"tracing"`
+	HTTP          HTTPConfig          `cfg:"http"`
+	// TrustedDocuments список одобренных операций.
+	TrustedDocuments TrustedDocumentsConfig `cfg:"trusted_documents"`
+
+	// envErrs ошибки разбора источников, возвращаются из Validate.
+	envErrs []error
 }
 
 type (
 	ServerConfig struct {
-		Addr string
+		Addr string `cfg:"addr" env:"ADDR"`
+		// ShutdownTimeout ожидание активных запросов при остановке, 0 - по умолчанию.
+		ShutdownTimeout time.Duration `cfg:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+		// DrainDelay пауза между переводом /readyz в 503 и остановкой сервера,
+		// чтобы балансировщик успел убрать инстанс.
+		DrainDelay time.Duration `cfg:"drain_delay" env:"SHUTDOWN_DRAIN_DELAY"`
+		// SubscriptionsTimeout ожидание закрытия websocket соединений при остановке.
+		SubscriptionsTimeout time.Duration `cfg:"subscriptions_timeout" env:"SHUTDOWN_SUBSCRIPTIONS_TIMEOUT"`
+		// JobsTimeout ожидание фоновых задач при остановке.
+		JobsTimeout time.Duration `cfg:"jobs_timeout" env:"SHUTDOWN_JOBS_TIMEOUT"`
 	}
 	DataBase struct {
-		DSN string
+		DSN string `cfg:"dsn" env:"DSN" secret:"true"`
+		// ReplicaDSN реплика Postgres для списков и счетчиков, пусто - все в DSN.
+		ReplicaDSN string `cfg:"replica_dsn" env:"DB_REPLICA_DSN" secret:"true"`
+		// AutoMigrate применять миграции Postgres и SQLite при старте сервера.
+		AutoMigrate bool               `cfg:"auto_migrate" env:"AUTO_MIGRATE"`
+		Pool        storage.PoolConfig `cfg:"pool"`
+		// QueryTimeout дедлайн каждой операции Query и Mutation, 0 - без дедлайна.
+		QueryTimeout time.Duration `cfg:"query_timeout" env:"DB_QUERY_TIMEOUT"`
+	}
+	SQLiteConfig struct {
+		// Path файл базы, создается при первом запуске.
+		Path string `cfg:"path" env:"SQLITE_PATH"`
+	}
+
+	// LimitsConfig ограничения на запросы GraphQL, 0 - по умолчанию.
+	LimitsConfig struct {
+		// QueryComplexity предел сложности запроса, 0 - без предела.
+		QueryComplexity int `cfg:"query_complexity" env:"LIMIT_QUERY_COMPLEXITY"`
+		// QueryCacheSize сколько разобранных запросов держать в кэше.
+		QueryCacheSize int `cfg:"query_cache_size" env:"LIMIT_QUERY_CACHE_SIZE"`
+		// APQCacheSize сколько automatic persisted queries держать в кэше.
+		APQCacheSize int `cfg:"apq_cache_size" env:"LIMIT_APQ_CACHE_SIZE"`
+		// MaxBodySize предел тела запроса в байтах, 0 - по умолчанию.
+		MaxBodySize int `cfg:"max_body_size" env:"LIMIT_MAX_BODY_SIZE"`
+		// MaxBatchSize сколько операций можно прислать массивом в одном POST, 0 - пакеты выключены.
+		MaxBatchSize int `cfg:"max_batch_size" env:"LIMIT_MAX_BATCH_SIZE"`
+	}
+
+	LogConfig struct {
+		// Level debug, info, warn или error.
+		Level string `cfg:"level" env:"LOG_LEVEL"`
+		// Format console или json.
+		Format string `cfg:"format" env:"LOG_FORMAT"`
+	}
+
+	AuthConfig struct {
+		// RoleToken секрет X-Role-Token, без него роли модератора и администратора не действуют.
+		RoleToken string `cfg:"role_token" env:"AUTH_ROLE_TOKEN" secret:"true"`
+	}
+
+	SubscriptionsConfig struct {
+		// Enabled принимать подписки по websocket.
+		Enabled bool `cfg:"enabled" env:"SUBSCRIPTIONS_ENABLED"`
+		// KeepAlive интервал ping соединения, 0 - по умолчанию.
+		KeepAlive time.Duration `cfg:"keep_alive" env:"SUBSCRIPTIONS_KEEP_ALIVE"`
+	}
+
+	MetricsConfig struct {
+		// Enabled собирать метрики Prometheus и отдавать их на /metrics.
+		Enabled bool `cfg:"enabled" env:"METRICS_ENABLED"`
+		// Addr отдельный внутренний адрес для /metrics, пусто - /metrics на основном адресе.
+		Addr string `cfg:"addr" env:"METRICS_ADDR"`
+		// Operations имена операций для метки operation сверх манифеста доверенных документов,
+		// остальные пишутся как other.
+		Operations []string `cfg:"operations" env:"METRICS_OPERATIONS"`
+		// SlowField с какой длительности резолвер считается медленным, 0 - по умолчанию.
+		SlowField time.Duration `cfg:"slow_field" env:"METRICS_SLOW_FIELD"`
+	}
+
+	TracingConfig struct {
+		// Exporter none, stdout или otlp.
+		Exporter string `cfg:"exporter" env:"TRACING_EXPORTER"`
+		// Endpoint URL OTLP/HTTP коллектора, пусто - из OTEL_EXPORTER_OTLP_ENDPOINT.
+		Endpoint    string `cfg:"endpoint" env:"TRACING_ENDPOINT"`
+		ServiceName string `cfg:"service_name" env:"TRACING_SERVICE_NAME"`
+		// SampleRatio доля новых трасс от 0 до 1.
+		SampleRatio float64 `cfg:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+		// Resolvers спан на каждый резолвер поля.
+		Resolvers bool `cfg:"resolvers" env:"TRACING_RESOLVERS"`
+	}
+
+	// HTTPConfig что доступно браузеру и снаружи.
+	HTTPConfig struct {
+		// CORSOrigins источники браузерных приложений вида https://app.example.com, * - любой, пусто - CORS выключен.
+		CORSOrigins []string `cfg:"cors_origins" env:"HTTP_CORS_ORIGINS"`
+		// CORSHeaders заголовки запроса сверх стандартных (Content-Type, X-Request-ID, X-User-ID, X-Role-Token, traceparent).
+		CORSHeaders []string `cfg:"cors_headers" env:"HTTP_CORS_HEADERS"`
+		// CORSCredentials разрешить cookie и Authorization, несовместимо с *.
+		CORSCredentials bool          `cfg:"cors_credentials" env:"HTTP_CORS_CREDENTIALS"`
+		CORSMaxAge      time.Duration `cfg:"cors_max_age" env:"HTTP_CORS_MAX_AGE"`
+		// WebsocketOrigins источники подписок, пусто - как CORSOrigins. Тот же хост разрешен всегда.
+		WebsocketOrigins []string `cfg:"websocket_origins" env:"HTTP_WEBSOCKET_ORIGINS"`
+		// TrustedProxies адреса и подсети прокси, которым верим X-Forwarded-For, пусто - никому.
+		TrustedProxies []string `cfg:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES"`
+		// HSTS max-age заголовка Strict-Transport-Security, 0 - без заголовка.
+		HSTS time.Duration `cfg:"hsts" env:"HTTP_HSTS"`
+		// Introspection отвечать на __schema и __type.
+		Introspection bool `cfg:"introspection" env:"HTTP_INTROSPECTION"`
+		// Playground GraphQL playground на /.
+		Playground bool `cfg:"playground" env:"HTTP_PLAYGROUND"`
 	}
 
-	// TODO: Другие шняги JWT и т.д.
+	TrustedDocumentsConfig struct {
+		// Mode off, report (чужие операции только в лог) или enforce (отклоняются).
+		Mode string `cfg:"mode" env:"TRUSTED_DOCUMENTS_MODE"`
+		// Manifest JSON файл с одобренными операциями из сборки фронтенда.
+		Manifest string `cfg:"manifest" env:"TRUSTED_DOCUMENTS_MANIFEST"`
+	}
 )
 
-func LoadFromEnv() Config {
-	cfg := Config{
+// Значения по умолчанию для нулевых настроек.
+const (
+	DefaultShutdownTimeout = 5 * time.Second
+	DefaultQueryCacheSize  = 1000
+	DefaultAPQCacheSize    = 1000
+	DefaultLogLevel        = "info"
+	DefaultLogFormat       = "console"
+	DefaultKeepAlive       = 10 * time.Second
+	DefaultMaxBodySize     = 1 << 20
+	DefaultCORSMaxAge      = 10 * time.Minute
+	// DefaultSubscriptionsTimeout и DefaultJobsTimeout этапы остановки.
+	DefaultSubscriptionsTimeout = 2 * time.Second
+	DefaultJobsTimeout          = 10 * time.Second
+	// DefaultSlowField с какой длительности резолвер считается медленным.
+	DefaultSlowField = 100 * time.Millisecond
+)
+
+// Default конфиг без внешних источников.
+func Default() Config {
+	return Config{
 		Server: ServerConfig{
-			Addr: "localhost:8080",
+			Addr:                 "localhost:8080",
+			ShutdownTimeout:      DefaultShutdownTimeout,
+			SubscriptionsTimeout: DefaultSubscriptionsTimeout,
+			JobsTimeout:          DefaultJobsTimeout,
 		},
+		DB:          DataBase{AutoMigrate: true, Pool: storage.DefaultPoolConfig()},
+		Storage:     StorageMemory,
 		UsePostgres: false,
+		SQLite:      SQLiteConfig{Path: defaultSQLitePath},
+		Filters:     defaultFilterConfig(),
+		Memory:      defaultMemoryConfig(),
+		Limits: LimitsConfig{
+			QueryCacheSize: DefaultQueryCacheSize,
+			APQCacheSize:   DefaultAPQCacheSize,
+			MaxBodySize:    DefaultMaxBodySize,
+		},
+		Log:              LogConfig{Level: DefaultLogLevel, Format: DefaultLogFormat},
+		Subscriptions:    SubscriptionsConfig{Enabled: true, KeepAlive: DefaultKeepAlive},
+		Metrics:          MetricsConfig{SlowField: DefaultSlowField},
+		Tracing:          TracingConfig{Exporter: TracingNone, ServiceName: "gqlgo", SampleRatio: 1},
+		HTTP:             HTTPConfig{CORSMaxAge: DefaultCORSMaxAge, Introspection: true, Playground: true},
+		TrustedDocuments: TrustedDocumentsConfig{Mode: TrustedOff},
 	}
+}
 
-	if v := os.Getenv("DSN"); v != "" {
-		cfg.DB.DSN = v
+// LoadFromEnv значения по умолчанию, переопределенные env.
+func LoadFromEnv() Config {
+	cfg := Default()
+	cfg.envErrs = applyEnv(&cfg)
+	cfg.normalize()
+	return cfg
+}
+
+// Args разбор командной строки помимо настроек.
+type Args struct {
+	// PrintConfig вывести итоговый конфиг и выйти.
+	PrintConfig bool
+	// Rest аргументы после флагов.
+	Rest []string
+}
+
+// Load конфиг из всех источников и валидация.
+// Конфиг возвращается и с ошибкой, чтобы --print-config показал, что получилось.
+func Load(args []string) (Config, Args, error) {
+	cfg := Default()
+	fl, err := parseFlags(&cfg, args)
+	if err != nil {
+		return cfg, fl.Args, err
 	}
-	if v := os.Getenv("ADDR"); v != "" {
-		cfg.Server.Addr = v
+
+	path := os.Getenv("CONFIG_FILE")
+	if fl.ConfigFile != "" {
+		path = fl.ConfigFile
 	}
-	if v := os.Getenv("USE_POSTGRES"); v != "" {
-		cfg.UsePostgres = v == "true" || v == "1" || v == "yes" || v == "y"
-	} else if v := os.Getenv("POSTGRES"); v != "" {
-		cfg.UsePostgres = v == "true" || v == "1" || v == "yes" || v == "y"
+	if path != "" {
+		if err := applyFile(&cfg, path); err != nil {
+			return cfg, fl.Args, fmt.Errorf("файл конфига: %w", err)
+		}
 	}
+	cfg.envErrs = applyEnv(&cfg)
+	cfg.envErrs = append(cfg.envErrs, fl.apply()...)
+	cfg.normalize()
 
-	return cfg
-}
-
-// Load конфиг из env и валидация.
-func Load() (Config, error) {
-	cfg := LoadFromEnv()
 	if err := cfg.Validate(); err != nil {
-		return Config{}, fmt.Errorf("загрузка конфига env: %w", err)
+		return cfg, fl.Args, fmt.Errorf("загрузка конфига: %w", err)
 	}
-	return cfg, nil
+	return cfg, fl.Args, nil
+}
+
+// normalize регистр перечислений и совместимость UsePostgres.
+func (c *Config) normalize() {
+	c.Storage = strings.ToLower(c.Storage)
+	c.UsePostgres = c.Storage == StoragePostgres
+	c.Filters.Mode = strings.ToLower(c.Filters.Mode)
+	c.Memory.Fsync = strings.ToLower(c.Memory.Fsync)
+	c.Log.Level = strings.ToLower(c.Log.Level)
+	c.Log.Format = strings.ToLower(c.Log.Format)
+	c.Tracing.Exporter = strings.ToLower(c.Tracing.Exporter)
+	c.TrustedDocuments.Mode = strings.ToLower(c.TrustedDocuments.Mode)
 }
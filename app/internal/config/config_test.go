@@ -1,7 +1,20 @@
 package config
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/filter"
+	"github.com/RoGogDBD/GQLGo/internal/lifecycle"
+	"github.com/RoGogDBD/GQLGo/internal/metrics"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+	"github.com/RoGogDBD/GQLGo/internal/tracing"
+	"github.com/RoGogDBD/GQLGo/internal/trusted"
 )
 
 // Тест конфиг из env.
@@ -60,3 +73,192 @@ func TestLoadFromEnv(t *testing.T) {
 		})
 	}
 }
+
+// Тест на порядок источников: файл < env < флаги.
+func TestLoad_Layers(t *testing.T) {
+	files := map[string]string{
+		"app.yaml": `
+server:
+  addr: 127.0.0.1:9000
+  shutdown_timeout: 20s
+storage: sqlite
+sqlite:
+  path: /tmp/file.db
+filters:
+  max_links: 3
+  blocklist:
+    ru: [казино, ставки]
+log:
+  level: warn
+http:
+  cors_origins: [https://app.example.com]
+`,
+		"app.toml": `
+storage = "sqlite"
+
+[server]
+addr = "127.0.0.1:9000"
+shutdown_timeout = "20s"
+
+[sqlite]
+path = "/tmp/file.db"
+
+[filters]
+max_links = 3
+blocklist = { ru = ["казино", "ставки"] }
+
+[log]
+level = "warn"
+
+[http]
+cors_origins = ["https://app.example.com"]
+`,
+	}
+
+	for name, data := range files {
+		name, data := name, data
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("CONFIG_FILE", path)
+			t.Setenv("SQLITE_PATH", "/tmp/env.db")
+			t.Setenv("LOG_LEVEL", "error")
+
+			cfg, args, err := Load([]string{"--log-level", "debug", "--subscriptions-enabled=false", "migrate", "up"})
+			if err != nil {
+				t.Fatalf("ошибка не ожидалась: %v", err)
+			}
+			checks := []struct {
+				what      string
+				got, want any
+			}{
+				{"адрес из файла", cfg.Server.Addr, "127.0.0.1:9000"},
+				{"таймаут из файла", cfg.Server.ShutdownTimeout, 20 * time.Second},
+				{"хранилище из файла", cfg.Storage, StorageSQLite},
+				{"лимит ссылок из файла", cfg.Filters.MaxLinks, 3},
+				{"слова из файла", cfg.Filters.BlockList, map[string][]string{"ru": {"казино", "ставки"}}},
+				{"источники из файла", cfg.HTTP.CORSOrigins, []string{"https://app.example.com"}},
+				{"путь из env", cfg.SQLite.Path, "/tmp/env.db"},
+				{"уровень из флага", cfg.Log.Level, "debug"},
+				{"подписки из флага", cfg.Subscriptions.Enabled, false},
+				{"fsync по умолчанию", cfg.Memory.Fsync, "always"},
+				{"демо-пользователи выключены", cfg.Memory.SeedUsers, false},
+				{"аргументы", args.Rest, []string{"migrate", "up"}},
+			}
+			for _, c := range checks {
+				if !reflect.DeepEqual(c.got, c.want) {
+					t.Fatalf("%s: ожидалось %v, а получили %v", c.what, c.want, c.got)
+				}
+			}
+		})
+	}
+}
+
+// Тест на ошибки источников: каждая указывает на ключ, переменную или флаг.
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{name: "Неизвестный ключ", file: "server:\n  adr: :8080\n", want: "server.adr"},
+		{name: "Неверный тип в файле", file: "db:\n  pool:\n    max_open_conns: много\n", want: "db.pool.max_open_conns"},
+		{name: "Неверный env", env: map[string]string{"LIMIT_APQ_CACHE_SIZE": "x"}, want: "LIMIT_APQ_CACHE_SIZE"},
+		{name: "Неверный флаг", args: []string{"--shutdown-timeout", "5"}, want: "--shutdown-timeout"},
+		{name: "Неизвестный флаг", args: []string{"--nope"}, want: "nope"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.file != "" {
+				path := filepath.Join(t.TempDir(), "app.yml")
+				if err := os.WriteFile(path, []byte(tc.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				tc.args = append([]string{"--config", path}, tc.args...)
+			}
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			_, _, err := Load(tc.args)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("ожидалась ошибка про %q, а получили %v", tc.want, err)
+			}
+		})
+	}
+}
+
+// Тест на печать конфига: секреты скрыты, ключи как в файле.
+func TestConfig_Print(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		want    string
+		secrets []string
+	}{
+		{name: "URL", dsn: "postgres://user:s3cret@db:5432/app?sslmode=disable", want: "postgres://user:xxxxx@db:5432/app", secrets: []string{"s3cret"}},
+		{name: "Ключ-значение", dsn: "host=db user=app password=s3cret dbname=app", want: "password=xxxxx dbname=app", secrets: []string{"s3cret"}},
+		{name: "Без пароля", dsn: "postgres://db/app", want: "postgres://db/app"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.DB.DSN = tc.dsn
+			cfg.DB.ReplicaDSN = tc.dsn
+
+			var buf bytes.Buffer
+			if err := cfg.Print(&buf); err != nil {
+				t.Fatalf("печать: %v", err)
+			}
+			out := buf.String()
+			if !strings.Contains(out, tc.want) {
+				t.Fatalf("ожидалось %q в\n%s", tc.want, out)
+			}
+			for _, secret := range tc.secrets {
+				if strings.Contains(out, secret) {
+					t.Fatalf("секрет %q не скрыт:\n%s", secret, out)
+				}
+			}
+			for _, key := range []string{"shutdown_timeout: 5s", "max_open_conns:", "keep_alive: 10s"} {
+				if !strings.Contains(out, key) {
+					t.Fatalf("ожидалось %q в\n%s", key, out)
+				}
+			}
+		})
+	}
+}
+
+// Тест на значения конфига, продублированные из пакетов: config от пакетов не зависит,
+// поэтому расхождение ловится здесь.
+func TestDefaults_MatchPackages(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{name: "SHUTDOWN_SUBSCRIPTIONS_TIMEOUT", got: DefaultSubscriptionsTimeout, want: lifecycle.DefaultSubscriptionTimeout},
+		{name: "SHUTDOWN_JOBS_TIMEOUT", got: DefaultJobsTimeout, want: lifecycle.DefaultJobTimeout},
+		{name: "METRICS_SLOW_FIELD", got: DefaultSlowField, want: metrics.DefaultSlowField},
+		{name: "MEMORY_COMPACT_INTERVAL", got: DefaultCompactInterval, want: repository.DefaultCompactInterval},
+		{name: "FILTER_THRESHOLD", got: DefaultFilterThreshold, want: filter.DefaultThreshold},
+		{name: "MEMORY_FSYNC", got: []string{FsyncAlways, FsyncInterval, FsyncNever}, want: []string{string(repository.FsyncAlways), string(repository.FsyncInterval), string(repository.FsyncNever)}},
+		{name: "FILTER_MODE", got: []string{FilterOff, FilterReject, FilterScore}, want: []string{string(filter.ModeOff), string(filter.ModeReject), string(filter.ModeScore)}},
+		{name: "TRACING_EXPORTER", got: []string{TracingNone, TracingStdout, TracingOTLP}, want: []string{tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP}},
+		{name: "TRUSTED_DOCUMENTS_MODE", got: []string{TrustedOff, TrustedReport, TrustedEnforce}, want: []string{string(trusted.ModeOff), string(trusted.ModeReport), string(trusted.ModeEnforce)}},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tc.got, tc.want) {
+				t.Fatalf("ожидалось %v, а получили %v", tc.want, tc.got)
+			}
+		})
+	}
+}
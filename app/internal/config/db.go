@@ -0,0 +1,17 @@
+package config
+
+import "github.com/RoGogDBD/GQLGo/internal/storage"
+
+// validatePool проверяет пул: неотрицательные значения, простаивающих не больше открытых.
+func validatePool(p storage.PoolConfig) []error {
+	var errs []error
+	if p.MaxOpenConns < 0 || p.MaxIdleConns < 0 {
+		errs = append(errs, ErrDBPool)
+	} else if p.MaxOpenConns > 0 && p.MaxIdleConns > p.MaxOpenConns {
+		errs = append(errs, ErrDBIdleConns)
+	}
+	if p.ConnMaxLifetime < 0 || p.ConnMaxIdleTime < 0 || p.StatementTimeout < 0 || p.ConnectTimeout < 0 {
+		errs = append(errs, ErrDBTimeouts)
+	}
+	return errs
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Режимы фильтров для FILTER_MODE.
+const (
+	FilterOff    = "off"
+	FilterReject = "reject"
+	FilterScore  = "score"
+)
+
+// DefaultFilterThreshold сумма очков, с которой контент подозрительный.
+const DefaultFilterThreshold = 1.0
+
+// FilterConfig настройки фильтров контента.
+type FilterConfig struct {
+	// Mode off, reject или score.
+	Mode string `cfg:"mode" env:"FILTER_MODE"`
+	// Threshold сумма очков фильтров, с которой контент подозрительный.
+	Threshold float64 `cfg:"threshold" env:"FILTER_THRESHOLD"`
+	// BlockList запрещенные слова по языкам (ru, en, * - любой).
+	BlockList map[string][]string `cfg:"blocklist" env:"FILTER_BLOCKLIST"`
+	// MaxLinks ссылок в тексте, 0 - без ограничения.
+	MaxLinks int `cfg:"max_links" env:"FILTER_MAX_LINKS"`
+	// DuplicateWindow окно поиска повторов, 0 - выключено.
+	DuplicateWindow time.Duration `cfg:"duplicate_window" env:"FILTER_DUPLICATE_WINDOW"`
+}
+
+func defaultFilterConfig() FilterConfig {
+	return FilterConfig{
+		Mode:            FilterScore,
+		Threshold:       DefaultFilterThreshold,
+		MaxLinks:        5,
+		DuplicateWindow: time.Minute,
+	}
+}
+
+// ParseBlockList разбирает списки вида "ru:казино,ставки;en:casino;*:spam".
+func ParseBlockList(s string) (map[string][]string, error) {
+	lists := map[string][]string{}
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lang, words, ok := strings.Cut(part, ":")
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if !ok || lang == "" {
+			return nil, fmt.Errorf("ожидалось язык:слова, а получили %q", part)
+		}
+		for _, w := range strings.Split(words, ",") {
+			if w = strings.TrimSpace(w); w != "" {
+				lists[lang] = append(lists[lang], w)
+			}
+		}
+	}
+	return lists, nil
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"context"
+
 	"go.uber.org/zap"
 
 	"github.com/RoGogDBD/GQLGo/internal/logger"
@@ -10,13 +12,38 @@ type Logger struct {
 	*zap.SugaredLogger
 }
 
-func (l Logger) Infof(format string, args ...any) { l.SugaredLogger.Infof(format, args...) }
-
-// func (l Logger) Warnf(format string, args ...any)  { l.SugaredLogger.Warnf(format, args...) }
+func (l Logger) Debugf(format string, args ...any) { l.SugaredLogger.Debugf(format, args...) }
+func (l Logger) Infof(format string, args ...any)  { l.SugaredLogger.Infof(format, args...) }
+func (l Logger) Warnf(format string, args ...any)  { l.SugaredLogger.Warnf(format, args...) }
 func (l Logger) Errorf(format string, args ...any) { l.SugaredLogger.Errorf(format, args...) }
 
-func NewLogger() (logger.Logger, func(), error) {
-	zapLogger, err := zap.NewDevelopment()
+func (l Logger) With(kv ...any) logger.Logger {
+	return Logger{l.SugaredLogger.With(kv...)}
+}
+
+func (l Logger) Ctx(ctx context.Context) logger.Logger {
+	kv := logger.ContextFields(ctx)
+	if len(kv) == 0 {
+		return l
+	}
+	return l.With(kv...)
+}
+
+// NewLogger логгер с уровнем и форматом из конфига: console для разработки, json для сбора логов.
+func NewLogger(cfg LogConfig) (logger.Logger, func(), error) {
+	zapCfg := zap.NewDevelopmentConfig()
+	if cfg.Format == "json" {
+		zapCfg = zap.NewProductionConfig()
+	}
+	if cfg.Level != "" {
+		level, err := zap.ParseAtomicLevel(cfg.Level)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		zapCfg.Level = level
+	}
+	// Пропускаем обертки Logger, чтобы caller указывал на место вызова.
+	zapLogger, err := zapCfg.Build(zap.AddCallerSkip(1))
 	if err != nil {
 		return nil, func() {}, err
 	}
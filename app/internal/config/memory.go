@@ -0,0 +1,32 @@
+package config
+
+import "time"
+
+// Политики fsync журнала для MEMORY_FSYNC.
+const (
+	FsyncAlways   = "always"
+	FsyncInterval = "interval"
+	FsyncNever    = "never"
+)
+
+// DefaultCompactInterval как часто журнал сворачивается в снимок.
+const DefaultCompactInterval = 5 * time.Minute
+
+// MemoryConfig сохранение хранилища в памяти на диск.
+type MemoryConfig struct {
+	// Dir каталог снимка и журнала, пустой - данные живут до перезапуска.
+	Dir string `cfg:"dir" env:"MEMORY_DIR"`
+	// Fsync always, interval или never.
+	Fsync string `cfg:"fsync" env:"MEMORY_FSYNC"`
+	// CompactInterval как часто журнал сворачивается в снимок, 0 - по умолчанию.
+	CompactInterval time.Duration `cfg:"compact_interval" env:"MEMORY_COMPACT_INTERVAL"`
+	// SeedUsers только для разработки: при старте добавить демо-пользователей с известными id.
+	SeedUsers bool `cfg:"seed_users" env:"MEMORY_SEED_USERS"`
+}
+
+func defaultMemoryConfig() MemoryConfig {
+	return MemoryConfig{
+		Fsync:           FsyncAlways,
+		CompactInterval: DefaultCompactInterval,
+	}
+}
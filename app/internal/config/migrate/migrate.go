@@ -1,29 +1,222 @@
 package migrate
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 
+	"github.com/RoGogDBD/GQLGo/migrations"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-const migrationsPath = "file://./migrations"
+var (
+	ErrDirty   = errors.New("схема dirty: исправьте схему и выполните force")
+	ErrPending = errors.New("есть непримененные миграции")
+)
 
-// RunMigrations запуск миграций из папки migrations.
-func RunMigrations(dsn string) error {
-	m, err := migrate.New(migrationsPath, dsn)
+// Каталоги миграций внутри migrations.FS.
+const (
+	postgresDir = "."
+	sqliteDir   = "sqlite"
+)
+
+// Status состояние схемы: примененная и последняя доступная версии.
+type Status struct {
+	// Version 0, если ни одна миграция не применена.
+	Version uint
+	Latest  uint
+	// Dirty миграция упала на середине, нужен force.
+	Dirty bool
+}
+
+// Pending есть непримененные миграции.
+func (s Status) Pending() bool {
+	return s.Version < s.Latest
+}
+
+// Err ErrDirty или ErrPending, если схема не готова к работе.
+// Схема новее бинарника не ошибка: так бывает при откате релиза.
+func (s Status) Err() error {
+	switch {
+	case s.Dirty:
+		return fmt.Errorf("версия %d: %w", s.Version, ErrDirty)
+	case s.Pending():
+		return fmt.Errorf("версия %d из %d: %w", s.Version, s.Latest, ErrPending)
+	}
+	return nil
+}
+
+// Migrator миграции одной базы из встроенных файлов.
+type Migrator struct {
+	m      *migrate.Migrate
+	latest uint
+}
+
+// NewPostgres миграции Postgres по dsn.
+func NewPostgres(dsn string) (*Migrator, error) {
+	return newMigrator(migrations.FS, postgresDir, func(src source.Driver) (*migrate.Migrate, error) {
+		return migrate.NewWithSourceInstance("iofs", src, dsn)
+	})
+}
+
+// NewSQLite миграции файла SQLite.
+// Файл открывается напрямую: sqlite:// URL искажает пути с не-ASCII символами.
+func NewSQLite(path string) (*Migrator, error) {
+	return newMigrator(migrations.FS, sqliteDir, func(src source.Driver) (*migrate.Migrate, error) {
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			return nil, err
+		}
+		drv, err := sqlite.WithInstance(db, &sqlite.Config{})
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		return migrate.NewWithInstance("iofs", src, "sqlite", drv)
+	})
+}
+
+func newMigrator(fsys fs.FS, dir string, open func(source.Driver) (*migrate.Migrate, error)) (*Migrator, error) {
+	src, err := iofs.New(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("migrate source: %w", err)
+	}
+	latest, err := latestVersion(src)
 	if err != nil {
-		return fmt.Errorf("migrate new: %w", err)
+		_ = src.Close()
+		return nil, err
 	}
+	m, err := open(src)
+	if err != nil {
+		_ = src.Close()
+		return nil, fmt.Errorf("migrate new: %w", err)
+	}
+	return &Migrator{m: m, latest: latest}, nil
+}
 
-	if err := m.Up(); err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			return nil
+// latestVersion последняя версия в источнике.
+func latestVersion(src source.Driver) (uint, error) {
+	v, err := src.First()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
 		}
-		return fmt.Errorf("migrate up: %w", err)
+		return 0, fmt.Errorf("migrate source: %w", err)
 	}
+	for {
+		next, err := src.Next(v)
+		if errors.Is(err, os.ErrNotExist) {
+			return v, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("migrate source: %w", err)
+		}
+		v = next
+	}
+}
+
+// Up применяет все новые миграции.
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up(), "migrate up")
+}
+
+// Down откатывает последнюю примененную миграцию.
+func (m *Migrator) Down() error {
+	return ignoreNoChange(m.m.Steps(-1), "migrate down")
+}
 
+// To приводит схему к версии version вверх или вниз.
+func (m *Migrator) To(version uint) error {
+	return ignoreNoChange(m.m.Migrate(version), "migrate to")
+}
+
+// Force записывает версию без выполнения миграций, -1 - ни одной.
+// Нужен, чтобы снять dirty после ручного исправления схемы.
+func (m *Migrator) Force(version int) error {
+	if err := m.m.Force(version); err != nil {
+		return fmt.Errorf("migrate force: %w", err)
+	}
 	return nil
 }
+
+// Status текущая версия схемы.
+func (m *Migrator) Status() (Status, error) {
+	v, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return Status{}, fmt.Errorf("migrate version: %w", err)
+	}
+	return Status{Version: v, Latest: m.latest, Dirty: dirty}, nil
+}
+
+// Close закрывает источник и подключение к базе.
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// CurrentPostgres состояние схемы Postgres по уже открытому подключению.
+// В отличие от Migrator не берет блокировку миграций, подходит для проверок готовности.
+func CurrentPostgres(ctx context.Context, db *sql.DB) (Status, error) {
+	return current(ctx, db, postgresDir)
+}
+
+// CurrentSQLite состояние схемы SQLite по уже открытому подключению.
+func CurrentSQLite(ctx context.Context, db *sql.DB) (Status, error) {
+	return current(ctx, db, sqliteDir)
+}
+
+// current читает таблицу версий golang-migrate, одинаковую для Postgres и SQLite.
+func current(ctx context.Context, db *sql.DB, dir string) (Status, error) {
+	src, err := iofs.New(migrations.FS, dir)
+	if err != nil {
+		return Status{}, fmt.Errorf("migrate source: %w", err)
+	}
+	defer func() { _ = src.Close() }()
+	latest, err := latestVersion(src)
+	if err != nil {
+		return Status{}, err
+	}
+
+	st := Status{Latest: latest}
+	var version int64
+	err = db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &st.Dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Status{}, fmt.Errorf("migrate version: %w", err)
+	}
+	if version > 0 {
+		st.Version = uint(version)
+	}
+	return st, nil
+}
+
+// RunMigrations применяет миграции Postgres.
+func RunMigrations(dsn string) error {
+	m, err := NewPostgres(dsn)
+	if err != nil {
+		return err
+	}
+	return errors.Join(m.Up(), m.Close())
+}
+
+// RunSQLiteMigrations применяет миграции SQLite.
+func RunSQLiteMigrations(path string) error {
+	m, err := NewSQLite(path)
+	if err != nil {
+		return err
+	}
+	return errors.Join(m.Up(), m.Close())
+}
+
+func ignoreNoChange(err error, op string) error {
+	if err == nil || errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
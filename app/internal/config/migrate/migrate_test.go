@@ -0,0 +1,124 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// Тест на команды миграций по встроенным файлам SQLite.
+func TestMigrator_SQLite(t *testing.T) {
+	m, err := NewSQLite(filepath.Join(t.TempDir(), "тест.db"))
+	if err != nil {
+		t.Fatalf("открытие: %v", err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+
+	st, err := m.Status()
+	if err != nil {
+		t.Fatalf("статус: %v", err)
+	}
+	if st.Version != 0 || st.Latest == 0 || !st.Pending() {
+		t.Fatalf("ожидалась пустая схема с миграциями, а получили %+v", st)
+	}
+	latest := st.Latest
+
+	tests := []struct {
+		name string
+		run  func() error
+		want Status
+	}{
+		{name: "Up", run: m.Up, want: Status{Version: latest, Latest: latest}},
+		{name: "Повторный up", run: m.Up, want: Status{Version: latest, Latest: latest}},
+		{name: "Down", run: m.Down, want: Status{Version: latest - 1, Latest: latest}},
+		{name: "To", run: func() error { return m.To(latest) }, want: Status{Version: latest, Latest: latest}},
+		{name: "Force", run: func() error { return m.Force(-1) }, want: Status{Latest: latest}},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.run(); err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			st, err := m.Status()
+			if err != nil {
+				t.Fatalf("статус: %v", err)
+			}
+			if st != tc.want {
+				t.Fatalf("ожидалось %+v, а получили %+v", tc.want, st)
+			}
+		})
+	}
+}
+
+// Тест на чтение версии схемы по открытому подключению для проверки готовности.
+func TestCurrentSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	m, err := NewSQLite(path)
+	if err != nil {
+		t.Fatalf("открытие: %v", err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("открытие: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr error
+	}{
+		{name: "Непримененные миграции", run: m.Down, wantErr: ErrPending},
+		{name: "Актуальная схема", run: m.Up},
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.run(); err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			st, err := CurrentSQLite(context.Background(), db)
+			if err != nil {
+				t.Fatalf("статус: %v", err)
+			}
+			want, _ := m.Status()
+			if st != want {
+				t.Fatalf("ожидалось %+v, а получили %+v", want, st)
+			}
+			if !errors.Is(st.Err(), tc.wantErr) {
+				t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, st.Err())
+			}
+		})
+	}
+}
+
+// Тест на готовность схемы по статусу.
+func TestStatus_Err(t *testing.T) {
+	tests := []struct {
+		name string
+		st   Status
+		want error
+	}{
+		{name: "Актуальная", st: Status{Version: 3, Latest: 3}},
+		{name: "Новее бинарника", st: Status{Version: 4, Latest: 3}},
+		{name: "Отстает", st: Status{Version: 2, Latest: 3}, want: ErrPending},
+		{name: "Dirty", st: Status{Version: 3, Latest: 3, Dirty: true}, want: ErrDirty},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.st.Err(); !errors.Is(err, tc.want) {
+				t.Fatalf("ожидалась ошибка %v, а получили %v", tc.want, err)
+			}
+		})
+	}
+}
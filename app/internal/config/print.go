@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// redacted заменяет секреты при печати конфига.
+const redacted = "xxxxx"
+
+var dsnPassword = regexp.MustCompile(`(?i)(password=)('[^']*'|\S+)`)
+
+// Print выводит конфиг в YAML в формате файла конфига, секреты скрыты.
+func (c Config) Print(w io.Writer) error {
+	out, err := yaml.Marshal(section(reflect.ValueOf(c)))
+	if err != nil {
+		return fmt.Errorf("печать конфига: %w", err)
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// section секция конфига с ключами в порядке объявления полей.
+func section(v reflect.Value) yaml.MapSlice {
+	var out yaml.MapSlice
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, ok := sf.Tag.Lookup("cfg")
+		if !ok {
+			continue
+		}
+		var value any
+		switch fv := v.Field(i); {
+		case sf.Type.Kind() == reflect.Struct:
+			value = section(fv)
+		case sf.Type == durationType:
+			value = time.Duration(fv.Int()).String()
+		case sf.Tag.Get("secret") == "true":
+			value = redact(fv.String())
+		default:
+			value = fv.Interface()
+		}
+		out = append(out, yaml.MapItem{Key: key, Value: value})
+	}
+	return out
+}
+
+// redact скрывает пароль в DSN вида URL или key=value.
+func redact(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && strings.Contains(dsn, "://") {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+		q := u.Query()
+		if q.Has("password") {
+			q.Set("password", redacted)
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
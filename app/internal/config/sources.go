@@ -0,0 +1,322 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// field одна настройка конфига.
+type field struct {
+	// key полный ключ в файле, например db.pool.max_open_conns.
+	key   string
+	env   string
+	value reflect.Value
+}
+
+// flagName имя флага из переменной окружения: DB_MAX_OPEN_CONNS -> db-max-open-conns.
+func (f field) flagName() string {
+	return strings.ToLower(strings.ReplaceAll(f.env, "_", "-"))
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fields настройки конфига в порядке объявления, вложенные секции раскрываются.
+func fields(cfg *Config) []field {
+	return walk(reflect.ValueOf(cfg).Elem(), "")
+}
+
+func walk(v reflect.Value, prefix string) []field {
+	var out []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, ok := sf.Tag.Lookup("cfg")
+		if !ok {
+			continue
+		}
+		key = prefix + key
+		if sf.Type.Kind() == reflect.Struct {
+			out = append(out, walk(v.Field(i), key+".")...)
+			continue
+		}
+		out = append(out, field{
+			key:   key,
+			env:   sf.Tag.Get("env"),
+			value: v.Field(i),
+		})
+	}
+	return out
+}
+
+// set значение настройки из строки env, флага или файла.
+func (f field) set(s string) error {
+	v := f.value
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := parseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		// Список через запятую: HTTP_CORS_ORIGINS=https://a.example,https://b.example.
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case reflect.Map:
+		list, err := ParseBlockList(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("неподдерживаемый тип %s", v.Type())
+	}
+	return nil
+}
+
+// setRaw значение настройки из файла: скаляр, список строк или, для списков слов, таблица язык -> слова.
+func (f field) setRaw(raw any) error {
+	switch r := raw.(type) {
+	case nil:
+		return nil
+	case string:
+		return f.set(r)
+	case map[string]any:
+		if f.value.Kind() != reflect.Map {
+			return errors.New("ожидалось значение, а получили секцию")
+		}
+		lists := make(map[string][]string, len(r))
+		for lang, words := range r {
+			lang = strings.ToLower(strings.TrimSpace(lang))
+			switch w := words.(type) {
+			case string:
+				for _, word := range strings.Split(w, ",") {
+					if word = strings.TrimSpace(word); word != "" {
+						lists[lang] = append(lists[lang], word)
+					}
+				}
+			case []any:
+				for _, word := range w {
+					s, ok := word.(string)
+					if !ok {
+						return fmt.Errorf("%s: ожидалась строка, а получили %v", lang, word)
+					}
+					lists[lang] = append(lists[lang], s)
+				}
+			default:
+				return fmt.Errorf("%s: ожидался список слов", lang)
+			}
+		}
+		f.value.Set(reflect.ValueOf(lists))
+		return nil
+	case []any:
+		if f.value.Kind() != reflect.Slice {
+			return errors.New("ожидалось значение, а получили список")
+		}
+		list := make([]string, 0, len(r))
+		for _, item := range r {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("ожидалась строка, а получили %v", item)
+			}
+			list = append(list, s)
+		}
+		f.value.Set(reflect.ValueOf(list))
+		return nil
+	default:
+		// Числа и bool файла сводятся к строке и разбираются так же, как env.
+		return f.set(fmt.Sprint(r))
+	}
+}
+
+// parseBool как в старых USE_POSTGRES и AUTO_MIGRATE, плюс явные значения false.
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true", "1", "yes", "y", "on":
+		return true, nil
+	case "false", "0", "no", "n", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("ожидалось true или false, а получили %q", s)
+}
+
+// ===================== Файл =====================
+
+// applyFile настройки из YAML (.yaml, .yml) или TOML (.toml) файла.
+// Неизвестные ключи - ошибка, чтобы опечатка не терялась молча.
+func applyFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("%s: неизвестный формат, ожидался .yaml, .yml или .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	byKey := map[string]field{}
+	for _, f := range fields(cfg) {
+		byKey[f.key] = f
+	}
+	return errors.Join(applyTable(byKey, raw, "")...)
+}
+
+func applyTable(byKey map[string]field, table map[string]any, prefix string) []error {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, k := range keys {
+		key := prefix + k
+		if f, ok := byKey[key]; ok {
+			if err := f.setRaw(table[k]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			}
+			continue
+		}
+		if sub, ok := table[k].(map[string]any); ok {
+			errs = append(errs, applyTable(byKey, sub, key+".")...)
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s: неизвестный ключ", key))
+	}
+	return errs
+}
+
+// ===================== Env =====================
+
+// applyEnv настройки из переменных окружения, ошибки разбора возвращает.
+func applyEnv(cfg *Config) []error {
+	var errs []error
+
+	// Старые USE_POSTGRES/POSTGRES, STORAGE ниже главнее.
+	v := os.Getenv("USE_POSTGRES")
+	if v == "" {
+		v = os.Getenv("POSTGRES")
+	}
+	if b, _ := parseBool(v); b {
+		cfg.Storage = StoragePostgres
+	}
+
+	for _, f := range fields(cfg) {
+		if f.env == "" {
+			continue
+		}
+		if v := os.Getenv(f.env); v != "" {
+			if err := f.set(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+			}
+		}
+	}
+	return errs
+}
+
+// ===================== Флаги =====================
+
+// flagValue флаг настройки: значение запоминается и применяется после env.
+type flagValue struct {
+	f      field
+	values *[]flagSetting
+}
+
+type flagSetting struct {
+	f     field
+	value string
+}
+
+func (v flagValue) String() string { return "" }
+
+func (v flagValue) Set(s string) error {
+	*v.values = append(*v.values, flagSetting{f: v.f, value: s})
+	return nil
+}
+
+// IsBoolFlag позволяет писать --auto-migrate без значения.
+func (v flagValue) IsBoolFlag() bool { return v.f.value.Kind() == reflect.Bool }
+
+// flags разобранная командная строка.
+type flags struct {
+	Args
+	// ConfigFile путь из --config.
+	ConfigFile string
+	settings   []flagSetting
+}
+
+// parseFlags разбирает флаги, сами настройки применяются позже через apply.
+func parseFlags(cfg *Config, args []string) (*flags, error) {
+	fl := &flags{}
+	fs := flag.NewFlagSet("gqlgo", flag.ContinueOnError)
+	fs.StringVar(&fl.ConfigFile, "config", "", "файл конфига .yaml или .toml (или CONFIG_FILE)")
+	fs.BoolVar(&fl.PrintConfig, "print-config", false, "вывести итоговый конфиг без секретов и выйти")
+	for _, f := range fields(cfg) {
+		if f.env == "" {
+			continue
+		}
+		fs.Var(flagValue{f: f, values: &fl.settings}, f.flagName(), fmt.Sprintf("%s (или %s)", f.key, f.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return fl, err
+	}
+	fl.Rest = fs.Args()
+	return fl, nil
+}
+
+// apply значения флагов поверх файла и env.
+func (fl *flags) apply() []error {
+	var errs []error
+	for _, s := range fl.settings {
+		if err := s.f.set(s.value); err != nil {
+			errs = append(errs, fmt.Errorf("--%s: %w", s.f.flagName(), err))
+		}
+	}
+	return errs
+}
@@ -2,25 +2,180 @@ package config
 
 import (
 	"errors"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
 )
 
+// minRoleTokenLength короче токен ролей легко подобрать.
+const minRoleTokenLength = 16
+
 var (
-	ErrNoDSN     = errors.New("dsn не установлен")
-	ErrNoAddress = errors.New("addr не установлен")
+	ErrNoDSN        = errors.New("dsn не установлен")
+	ErrNoAddress    = errors.New("addr не установлен")
+	ErrFilterMode   = errors.New("неверный FILTER_MODE (off, reject, score)")
+	ErrFilterLimits = errors.New("лимиты фильтров должны быть >= 0")
+	ErrMemoryFsync  = errors.New("неверный MEMORY_FSYNC (always, interval, never)")
+	ErrMemoryLimits = errors.New("MEMORY_COMPACT_INTERVAL должен быть >= 0")
+	ErrStorage      = errors.New("неверный STORAGE (memory, postgres, sqlite)")
+	ErrNoSQLitePath = errors.New("SQLITE_PATH не установлен")
+	ErrDBPool       = errors.New("DB_MAX_OPEN_CONNS и DB_MAX_IDLE_CONNS должны быть >= 0")
+	ErrDBIdleConns  = errors.New("DB_MAX_IDLE_CONNS не может быть больше DB_MAX_OPEN_CONNS")
+	ErrDBTimeouts   = errors.New("таймауты БД должны быть >= 0")
+	ErrAddress      = errors.New("неверный ADDR, ожидался хост:порт с портом 1-65535")
+	ErrMetricsAddr  = errors.New("неверный METRICS_ADDR, ожидался хост:порт, отличный от ADDR")
+	ErrReplica      = errors.New("DB_REPLICA_DSN работает только с STORAGE=postgres")
+	ErrLimits       = errors.New("LIMIT_* должны быть >= 0")
+	ErrLogLevel     = errors.New("неверный LOG_LEVEL (debug, info, warn, error)")
+	ErrLogFormat    = errors.New("неверный LOG_FORMAT (console, json)")
+	ErrRoleToken    = errors.New("AUTH_ROLE_TOKEN должен быть не короче 16 символов")
+	ErrTracing      = errors.New("неверный TRACING_EXPORTER (none, stdout, otlp)")
+	ErrSampleRatio  = errors.New("TRACING_SAMPLE_RATIO должен быть от 0 до 1")
+	ErrTracingURL   = errors.New("TRACING_ENDPOINT должен быть URL http:// или https://")
+	ErrTimeouts     = errors.New("SHUTDOWN_*, SUBSCRIPTIONS_KEEP_ALIVE, METRICS_SLOW_FIELD, HTTP_CORS_MAX_AGE и HTTP_HSTS должны быть >= 0")
+	ErrOrigin       = errors.New("неверный источник в HTTP_CORS_ORIGINS или HTTP_WEBSOCKET_ORIGINS, ожидалось * или https://хост[:порт]")
+	ErrCORSWildcard = errors.New("HTTP_CORS_CREDENTIALS несовместим с HTTP_CORS_ORIGINS=*")
+	ErrTrustedProxy = errors.New("неверный адрес в HTTP_TRUSTED_PROXIES, ожидался IP или подсеть CIDR")
+	ErrTrustedMode  = errors.New("неверный TRUSTED_DOCUMENTS_MODE (off, report, enforce)")
+	ErrNoManifest   = errors.New("TRUSTED_DOCUMENTS_MANIFEST не установлен")
 )
 
-// Validate проверяет на параметры кофига.
+// Validate проверяет параметры конфига, пустые и нулевые значения - по умолчанию.
 func (c Config) Validate() error {
 	var errs []error
 
-	if c.DB.DSN == "" {
-		errs = append(errs, ErrNoDSN)
-	}
 	if c.Server.Addr == "" {
 		errs = append(errs, ErrNoAddress)
+	} else if !validAddr(c.Server.Addr) {
+		errs = append(errs, ErrAddress)
+	}
+	if c.Metrics.Addr != "" && (!validAddr(c.Metrics.Addr) || c.Metrics.Addr == c.Server.Addr) {
+		errs = append(errs, ErrMetricsAddr)
+	}
+	if c.Server.ShutdownTimeout < 0 || c.Server.DrainDelay < 0 || c.Server.SubscriptionsTimeout < 0 || c.Server.JobsTimeout < 0 || c.Subscriptions.KeepAlive < 0 || c.Metrics.SlowField < 0 ||
+		c.HTTP.CORSMaxAge < 0 || c.HTTP.HSTS < 0 {
+		errs = append(errs, ErrTimeouts)
+	}
+	switch c.Storage {
+	case "", StorageMemory:
+	case StoragePostgres:
+		if c.DB.DSN == "" {
+			errs = append(errs, ErrNoDSN)
+		}
+	case StorageSQLite:
+		if c.SQLite.Path == "" {
+			errs = append(errs, ErrNoSQLitePath)
+		}
+	default:
+		errs = append(errs, ErrStorage)
+	}
+	switch c.Filters.Mode {
+	case "", FilterOff, FilterReject, FilterScore:
+	default:
+		errs = append(errs, ErrFilterMode)
+	}
+	if c.Filters.Threshold < 0 || c.Filters.MaxLinks < 0 || c.Filters.DuplicateWindow < 0 {
+		errs = append(errs, ErrFilterLimits)
+	}
+	switch c.Memory.Fsync {
+	case "", FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		errs = append(errs, ErrMemoryFsync)
+	}
+	if c.Memory.CompactInterval < 0 {
+		errs = append(errs, ErrMemoryLimits)
+	}
+	if c.DB.ReplicaDSN != "" && c.Storage != StoragePostgres {
+		errs = append(errs, ErrReplica)
+	}
+	errs = append(errs, validatePool(c.DB.Pool)...)
+	if c.DB.QueryTimeout < 0 {
+		errs = append(errs, ErrDBTimeouts)
+	}
+	if c.Limits.QueryComplexity < 0 || c.Limits.QueryCacheSize < 0 || c.Limits.APQCacheSize < 0 ||
+		c.Limits.MaxBodySize < 0 || c.Limits.MaxBatchSize < 0 {
+		errs = append(errs, ErrLimits)
+	}
+	switch c.Log.Level {
+	case "", "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, ErrLogLevel)
+	}
+	switch c.Log.Format {
+	case "", "console", "json":
+	default:
+		errs = append(errs, ErrLogFormat)
+	}
+	if c.Auth.RoleToken != "" && len(c.Auth.RoleToken) < minRoleTokenLength {
+		errs = append(errs, ErrRoleToken)
 	}
+	switch c.Tracing.Exporter {
+	case "", TracingNone, TracingStdout, TracingOTLP:
+	default:
+		errs = append(errs, ErrTracing)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, ErrSampleRatio)
+	}
+	if c.Tracing.Endpoint != "" {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, ErrTracingURL)
+		}
+	}
+	for _, origin := range append(append([]string(nil), c.HTTP.CORSOrigins...), c.HTTP.WebsocketOrigins...) {
+		if !validOrigin(origin) {
+			errs = append(errs, ErrOrigin)
+			break
+		}
+	}
+	if c.HTTP.CORSCredentials && slices.Contains(c.HTTP.CORSOrigins, "*") {
+		errs = append(errs, ErrCORSWildcard)
+	}
+	for _, proxy := range c.HTTP.TrustedProxies {
+		if !validProxy(proxy) {
+			errs = append(errs, ErrTrustedProxy)
+			break
+		}
+	}
+	switch c.TrustedDocuments.Mode {
+	case "", TrustedOff:
+	case TrustedReport, TrustedEnforce:
+		if c.TrustedDocuments.Manifest == "" {
+			errs = append(errs, ErrNoManifest)
+		}
+	default:
+		errs = append(errs, ErrTrustedMode)
+	}
+	errs = append(errs, c.envErrs...)
 
 	return errors.Join(errs...)
 }
 
-// TODO: Добавить валидацию параметров.
+// validAddr адрес вида хост:порт, хост можно опустить.
+func validAddr(addr string) bool {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// validOrigin * или источник браузера: схема и хост без пути.
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.User == nil
+}
+
+// validProxy IP или подсеть CIDR.
+func validProxy(proxy string) bool {
+	if _, _, err := net.ParseCIDR(proxy); err == nil {
+		return true
+	}
+	return net.ParseIP(proxy) != nil
+}
@@ -22,7 +22,7 @@ func TestConfigValidate(t *testing.T) {
 		},
 		{
 			name:  "Нету DSN",
-			cfg:   Config{Server: ServerConfig{Addr: "0.0.0.0:8080"}},
+			cfg:   Config{Server: ServerConfig{Addr: "0.0.0.0:8080"}, Storage: StoragePostgres},
 			NoDSN: true,
 		},
 		{
@@ -32,7 +32,7 @@ func TestConfigValidate(t *testing.T) {
 		},
 		{
 			name:  "Нету DSN и ADDR",
-			cfg:   Config{},
+			cfg:   Config{Storage: StoragePostgres},
 			NoDSN: true,
 			NoAdr: true,
 		},
@@ -54,3 +54,261 @@ func TestConfigValidate(t *testing.T) {
 		})
 	}
 }
+
+// Тест на валидацию настроек сохранения хранилища в памяти.
+func TestConfigValidate_Memory(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr error
+		fails   bool
+	}{
+		{name: "По умолчанию", env: map[string]string{}},
+		{name: "Все настройки", env: map[string]string{
+			"MEMORY_DIR":              "/var/lib/gqlgo",
+			"MEMORY_FSYNC":            "interval",
+			"MEMORY_COMPACT_INTERVAL": "1m",
+		}},
+		{name: "Неверный fsync", env: map[string]string{"MEMORY_FSYNC": "sometimes"}, wantErr: ErrMemoryFsync, fails: true},
+		{name: "Отрицательный интервал", env: map[string]string{"MEMORY_COMPACT_INTERVAL": "-1m"}, wantErr: ErrMemoryLimits, fails: true},
+		{name: "Неверный интервал", env: map[string]string{"MEMORY_COMPACT_INTERVAL": "час"}, fails: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("DSN", "dsn")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			err := LoadFromEnv().Validate()
+			if !tc.fails && err != nil {
+				t.Fatalf("ошибка не ожидалась: %v", err)
+			}
+			if tc.fails && err == nil {
+				t.Fatalf("ожидалась ошибка")
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// Тест на выбор хранилища из env.
+func TestConfigValidate_Storage(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantStorage string
+		wantErr     error
+	}{
+		{name: "По умолчанию память", env: map[string]string{}, wantStorage: StorageMemory},
+		{name: "Старый USE_POSTGRES", env: map[string]string{"USE_POSTGRES": "true"}, wantStorage: StoragePostgres},
+		{name: "STORAGE главнее USE_POSTGRES", env: map[string]string{"USE_POSTGRES": "true", "STORAGE": "sqlite"}, wantStorage: StorageSQLite},
+		{name: "SQLite с путем", env: map[string]string{"STORAGE": "SQLite", "SQLITE_PATH": "/tmp/app.db"}, wantStorage: StorageSQLite},
+		{name: "Неизвестное хранилище", env: map[string]string{"STORAGE": "redis"}, wantStorage: "redis", wantErr: ErrStorage},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("DSN", "dsn")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			cfg := LoadFromEnv()
+			if cfg.Storage != tc.wantStorage {
+				t.Fatalf("ожидалось хранилище %q, а получили %q", tc.wantStorage, cfg.Storage)
+			}
+			if cfg.UsePostgres != (tc.wantStorage == StoragePostgres) {
+				t.Fatalf("UsePostgres не совпадает с хранилищем: %v", cfg.UsePostgres)
+			}
+			err := cfg.Validate()
+			if tc.wantErr == nil && err != nil {
+				t.Fatalf("ошибка не ожидалась: %v", err)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// Тест на валидацию настроек фильтров из env.
+func TestConfigValidate_Filters(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr error
+		fails   bool
+	}{
+		{name: "По умолчанию", env: map[string]string{}},
+		{name: "Все настройки", env: map[string]string{
+			"FILTER_MODE":             "reject",
+			"FILTER_THRESHOLD":        "2.5",
+			"FILTER_MAX_LINKS":        "3",
+			"FILTER_DUPLICATE_WINDOW": "30s",
+			"FILTER_BLOCKLIST":        "ru:казино;en:casino",
+		}},
+		{name: "Неверный режим", env: map[string]string{"FILTER_MODE": "drop"}, wantErr: ErrFilterMode, fails: true},
+		{name: "Отрицательный лимит", env: map[string]string{"FILTER_MAX_LINKS": "-1"}, wantErr: ErrFilterLimits, fails: true},
+		{name: "Неверное окно", env: map[string]string{"FILTER_DUPLICATE_WINDOW": "минута"}, fails: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("DSN", "dsn")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			err := LoadFromEnv().Validate()
+			if !tc.fails && err != nil {
+				t.Fatalf("ошибка не ожидалась: %v", err)
+			}
+			if tc.fails && err == nil {
+				t.Fatalf("ожидалась ошибка")
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// Тест на разбор списков запрещенных слов.
+func TestParseBlockList(t *testing.T) {
+	lists, err := ParseBlockList(" RU: казино, ставки ; en:casino;*:spam; ")
+	if err != nil {
+		t.Fatalf("ошибка не ожидалась: %v", err)
+	}
+	if len(lists["ru"]) != 2 || lists["en"][0] != "casino" || lists["*"][0] != "spam" {
+		t.Fatalf("неверный разбор: %v", lists)
+	}
+	if _, err := ParseBlockList("казино"); err == nil {
+		t.Fatalf("ожидалась ошибка без языка")
+	}
+}
+
+// Тест на валидацию пула и таймаутов БД.
+func TestConfigValidate_DB(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr error
+		fails   bool
+	}{
+		{name: "По умолчанию", env: map[string]string{}},
+		{name: "Все настройки", env: map[string]string{
+			"STORAGE":               "postgres",
+			"DB_REPLICA_DSN":        "postgres://replica/app",
+			"DB_MAX_OPEN_CONNS":     "50",
+			"DB_MAX_IDLE_CONNS":     "50",
+			"DB_CONN_MAX_LIFETIME":  "30m",
+			"DB_CONN_MAX_IDLE_TIME": "1m",
+			"DB_CONNECT_TIMEOUT":    "10s",
+			"DB_STATEMENT_TIMEOUT":  "3s",
+			"DB_QUERY_TIMEOUT":      "5s",
+		}},
+		{name: "Без ограничения открытых", env: map[string]string{"DB_MAX_OPEN_CONNS": "0", "DB_MAX_IDLE_CONNS": "100"}},
+		{name: "Отрицательный пул", env: map[string]string{"DB_MAX_OPEN_CONNS": "-1"}, wantErr: ErrDBPool, fails: true},
+		{name: "Простаивающих больше открытых", env: map[string]string{"DB_MAX_OPEN_CONNS": "5", "DB_MAX_IDLE_CONNS": "10"}, wantErr: ErrDBIdleConns, fails: true},
+		{name: "Отрицательный таймаут", env: map[string]string{"DB_STATEMENT_TIMEOUT": "-1s"}, wantErr: ErrDBTimeouts, fails: true},
+		{name: "Неверное число", env: map[string]string{"DB_MAX_IDLE_CONNS": "много"}, fails: true},
+		{name: "Неверный таймаут", env: map[string]string{"DB_QUERY_TIMEOUT": "5"}, fails: true},
+		{name: "Реплика без Postgres", env: map[string]string{"DB_REPLICA_DSN": "postgres://replica/app"}, wantErr: ErrReplica, fails: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("DSN", "dsn")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			err := LoadFromEnv().Validate()
+			if !tc.fails && err != nil {
+				t.Fatalf("ошибка не ожидалась: %v", err)
+			}
+			if tc.fails && err == nil {
+				t.Fatalf("ожидалась ошибка")
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// Тест на валидацию сервера, лимитов, логов, авторизации и подписок.
+func TestConfigValidate_Params(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr error
+		fails   bool
+	}{
+		{name: "По умолчанию память без DSN", env: map[string]string{}},
+		{name: "Все настройки", env: map[string]string{
+			"ADDR":                     ":8080",
+			"SHUTDOWN_TIMEOUT":         "30s",
+			"LIMIT_QUERY_COMPLEXITY":   "200",
+			"LIMIT_QUERY_CACHE_SIZE":   "0",
+			"LIMIT_APQ_CACHE_SIZE":     "500",
+			"LOG_LEVEL":                "DEBUG",
+			"LOG_FORMAT":               "json",
+			"SUBSCRIPTIONS_ENABLED":    "false",
+			"SUBSCRIPTIONS_KEEP_ALIVE": "30s",
+		}},
+		{name: "Postgres без DSN", env: map[string]string{"STORAGE": "postgres"}, wantErr: ErrNoDSN, fails: true},
+		{name: "Адрес без порта", env: map[string]string{"ADDR": "localhost"}, wantErr: ErrAddress, fails: true},
+		{name: "Порт вне диапазона", env: map[string]string{"ADDR": "localhost:70000"}, wantErr: ErrAddress, fails: true},
+		{name: "Отрицательный лимит", env: map[string]string{"LIMIT_QUERY_COMPLEXITY": "-1"}, wantErr: ErrLimits, fails: true},
+		{name: "Неверный уровень логов", env: map[string]string{"LOG_LEVEL": "verbose"}, wantErr: ErrLogLevel, fails: true},
+		{name: "Неверный формат логов", env: map[string]string{"LOG_FORMAT": "xml"}, wantErr: ErrLogFormat, fails: true},
+		{name: "Короткий токен ролей", env: map[string]string{"AUTH_ROLE_TOKEN": "secret"}, wantErr: ErrRoleToken, fails: true},
+		{name: "Отрицательный keep-alive", env: map[string]string{"SUBSCRIPTIONS_KEEP_ALIVE": "-1s"}, wantErr: ErrTimeouts, fails: true},
+		{name: "Неверный bool", env: map[string]string{"SUBSCRIPTIONS_ENABLED": "наверное"}, fails: true},
+		{name: "Трассировка OTLP", env: map[string]string{"TRACING_EXPORTER": "OTLP", "TRACING_ENDPOINT": "http://collector:4318", "TRACING_SAMPLE_RATIO": "0.1"}},
+		{name: "Неверный экспорт", env: map[string]string{"TRACING_EXPORTER": "jaeger"}, wantErr: ErrTracing, fails: true},
+		{name: "Доля трасс больше 1", env: map[string]string{"TRACING_SAMPLE_RATIO": "2"}, wantErr: ErrSampleRatio, fails: true},
+		{name: "Адрес коллектора без схемы", env: map[string]string{"TRACING_ENDPOINT": "collector:4318"}, wantErr: ErrTracingURL, fails: true},
+		{name: "CORS и websocket", env: map[string]string{"HTTP_CORS_ORIGINS": "https://app.example.com, http://localhost:3000", "HTTP_WEBSOCKET_ORIGINS": "*", "HTTP_CORS_CREDENTIALS": "true", "LIMIT_MAX_BATCH_SIZE": "10"}},
+		{name: "Источник с путем", env: map[string]string{"HTTP_CORS_ORIGINS": "https://app.example.com/app"}, wantErr: ErrOrigin, fails: true},
+		{name: "Источник без схемы", env: map[string]string{"HTTP_WEBSOCKET_ORIGINS": "app.example.com"}, wantErr: ErrOrigin, fails: true},
+		{name: "Метрики на отдельном адресе", env: map[string]string{"METRICS_ENABLED": "true", "METRICS_ADDR": "127.0.0.1:9090", "METRICS_OPERATIONS": "Feed,GetPost"}},
+		{name: "Метрики на основном адресе", env: map[string]string{"ADDR": ":8080", "METRICS_ADDR": ":8080"}, wantErr: ErrMetricsAddr, fails: true},
+		{name: "Доверенные прокси", env: map[string]string{"HTTP_TRUSTED_PROXIES": "10.0.0.0/8, 127.0.0.1"}},
+		{name: "Неверный прокси", env: map[string]string{"HTTP_TRUSTED_PROXIES": "proxy.local"}, wantErr: ErrTrustedProxy, fails: true},
+		{name: "Credentials с любым источником", env: map[string]string{"HTTP_CORS_ORIGINS": "*", "HTTP_CORS_CREDENTIALS": "true"}, wantErr: ErrCORSWildcard, fails: true},
+		{name: "Доверенные документы", env: map[string]string{"TRUSTED_DOCUMENTS_MODE": "Enforce", "TRUSTED_DOCUMENTS_MANIFEST": "persisted-documents.json"}},
+		{name: "Неверный режим документов", env: map[string]string{"TRUSTED_DOCUMENTS_MODE": "strict"}, wantErr: ErrTrustedMode, fails: true},
+		{name: "Режим документов без манифеста", env: map[string]string{"TRUSTED_DOCUMENTS_MODE": "report"}, wantErr: ErrNoManifest, fails: true},
+		{name: "Отрицательный предел тела", env: map[string]string{"LIMIT_MAX_BODY_SIZE": "-1"}, wantErr: ErrLimits, fails: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			err := LoadFromEnv().Validate()
+			if !tc.fails && err != nil {
+				t.Fatalf("ошибка не ожидалась: %v", err)
+			}
+			if tc.fails && err == nil {
+				t.Fatalf("ожидалась ошибка")
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// AnyLanguage список слов, который применяется к тексту на любом языке.
+const AnyLanguage = "*"
+
+// BlockList запрещенные слова по языкам.
+type BlockList struct {
+	words  map[string]map[string]struct{}
+	weight float64
+}
+
+// NewBlockList слова по коду языка (ru, en или * для любого).
+func NewBlockList(lists map[string][]string, weight float64) *BlockList {
+	words := make(map[string]map[string]struct{}, len(lists))
+	for lang, list := range lists {
+		set := make(map[string]struct{}, len(list))
+		for _, w := range list {
+			if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+				set[w] = struct{}{}
+			}
+		}
+		words[strings.ToLower(lang)] = set
+	}
+	return &BlockList{words: words, weight: weight}
+}
+
+func (b *BlockList) Name() string { return "blocklist" }
+
+// Check ищет слова из списка языка текста и общего списка.
+func (b *BlockList) Check(_ context.Context, c Content) (Result, error) {
+	if len(b.words) == 0 {
+		return Result{}, nil
+	}
+	lists := []map[string]struct{}{b.words[AnyLanguage]}
+	if lang := DetectLanguage(c.Text); lang != "" {
+		lists = append(lists, b.words[lang])
+	}
+
+	for _, word := range strings.FieldsFunc(strings.ToLower(c.Text), notWordRune) {
+		for _, list := range lists {
+			if _, ok := list[word]; ok {
+				return Result{Score: b.weight, Reason: "запрещенное слово " + word}, nil
+			}
+		}
+	}
+	return Result{}, nil
+}
+
+// DetectLanguage грубо определяет язык по алфавиту: ru для кириллицы, en для латиницы.
+func DetectLanguage(text string) string {
+	var cyrillic, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	switch {
+	case cyrillic == 0 && latin == 0:
+		return ""
+	case cyrillic >= latin:
+		return "ru"
+	default:
+		return "en"
+	}
+}
+
+func notWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"context"
+	"crypto/sha256"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Duplicate повтор одного и того же текста автором за окно времени.
+// Хранит отпечатки в памяти процесса, 0 - проверка выключена.
+type Duplicate struct {
+	window time.Duration
+	weight float64
+	now    func() time.Time
+
+	mu        sync.Mutex
+	seen      map[string]map[[sha256.Size]byte]time.Time
+	lastPrune time.Time
+}
+
+func NewDuplicate(window time.Duration, weight float64) *Duplicate {
+	return &Duplicate{
+		window: window,
+		weight: weight,
+		now:    time.Now,
+		seen:   map[string]map[[sha256.Size]byte]time.Time{},
+	}
+}
+
+func (d *Duplicate) Name() string { return "duplicate" }
+
+// Check запоминает текст и срабатывает, если автор уже писал его в окне.
+func (d *Duplicate) Check(_ context.Context, c Content) (Result, error) {
+	if d.window <= 0 || c.AuthorID == "" {
+		return Result{}, nil
+	}
+	key := sha256.Sum256([]byte(strings.Join(strings.Fields(strings.ToLower(c.Text)), " ")))
+	now := d.now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pruneLocked(now)
+
+	byAuthor := d.seen[c.AuthorID]
+	if byAuthor == nil {
+		byAuthor = map[[sha256.Size]byte]time.Time{}
+		d.seen[c.AuthorID] = byAuthor
+	}
+	prev, ok := byAuthor[key]
+	byAuthor[key] = now
+	if ok && now.Sub(prev) <= d.window {
+		return Result{Score: d.weight, Reason: "повтор недавнего текста"}, nil
+	}
+	return Result{}, nil
+}
+
+// pruneLocked удаляет отпечатки старше окна, не чаще раза в окно.
+func (d *Duplicate) pruneLocked(now time.Time) {
+	if now.Sub(d.lastPrune) < d.window {
+		return
+	}
+	d.lastPrune = now
+	for author, byAuthor := range d.seen {
+		for key, at := range byAuthor {
+			if now.Sub(at) > d.window {
+				delete(byAuthor, key)
+			}
+		}
+		if len(byAuthor) == 0 {
+			delete(d.seen, author)
+		}
+	}
+}
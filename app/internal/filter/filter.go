@@ -0,0 +1,126 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/RoGogDBD/GQLGo/internal/models"
+)
+
+// Kind тип проверяемого контента.
+type Kind string
+
+const (
+	KindPost    Kind = "post"
+	KindComment Kind = "comment"
+)
+
+// Mode что делать с подозрительным контентом.
+type Mode string
+
+const (
+	// ModeOff фильтры не запускаются.
+	ModeOff Mode = "off"
+	// ModeReject подозрительный контент отклоняется с ошибкой.
+	ModeReject Mode = "reject"
+	// ModeScore подозрительный контент сохраняется в состоянии PENDING до решения модератора.
+	ModeScore Mode = "score"
+)
+
+// DefaultThreshold сумма очков, с которой контент считается подозрительным.
+const DefaultThreshold = 1.0
+
+// IsValid проверяет режим, пустой режим - выключено.
+func (m Mode) IsValid() bool {
+	switch m {
+	case "", ModeOff, ModeReject, ModeScore:
+		return true
+	}
+	return false
+}
+
+// Content то, что проверяют фильтры.
+type Content struct {
+	Kind     Kind
+	AuthorID string
+	Text     string
+}
+
+// Result срабатывание фильтра, нулевой Score - контент чистый.
+type Result struct {
+	Score  float64
+	Reason string
+}
+
+// ContentFilter проверка контента перед сохранением.
+type ContentFilter interface {
+	Name() string
+	Check(ctx context.Context, c Content) (Result, error)
+}
+
+// Verdict итог проверки всеми фильтрами.
+type Verdict struct {
+	State   models.ModerationState
+	Score   float64
+	Reasons []string
+}
+
+// RejectedError контент отклонен фильтрами.
+type RejectedError struct {
+	Reasons []string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("контент отклонен: %s", strings.Join(e.Reasons, "; "))
+}
+
+// Pipeline запускает фильтры по порядку и суммирует очки.
+type Pipeline struct {
+	mode      Mode
+	threshold float64
+	filters   []ContentFilter
+}
+
+func NewPipeline(mode Mode, threshold float64, filters ...ContentFilter) *Pipeline {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	active := make([]ContentFilter, 0, len(filters))
+	for _, f := range filters {
+		if f != nil {
+			active = append(active, f)
+		}
+	}
+	return &Pipeline{mode: mode, threshold: threshold, filters: active}
+}
+
+// Run проверяет контент. В режиме reject возвращает *RejectedError,
+// в режиме score переводит контент в PENDING.
+func (p *Pipeline) Run(ctx context.Context, c Content) (Verdict, error) {
+	verdict := Verdict{State: models.ModerationStateVisible}
+	if p == nil || p.mode == "" || p.mode == ModeOff {
+		return verdict, nil
+	}
+
+	for _, f := range p.filters {
+		res, err := f.Check(ctx, c)
+		if err != nil {
+			return verdict, fmt.Errorf("фильтр %s: %w", f.Name(), err)
+		}
+		if res.Score <= 0 {
+			continue
+		}
+		verdict.Score += res.Score
+		verdict.Reasons = append(verdict.Reasons, fmt.Sprintf("%s: %s", f.Name(), res.Reason))
+	}
+
+	if verdict.Score < p.threshold {
+		return verdict, nil
+	}
+	if p.mode == ModeReject {
+		return verdict, &RejectedError{Reasons: verdict.Reasons}
+	}
+	verdict.State = models.ModerationStatePending
+	return verdict, nil
+}
@@ -0,0 +1,144 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/models"
+)
+
+// Тест на список запрещенных слов по языку текста.
+func TestBlockList(t *testing.T) {
+	list := NewBlockList(map[string][]string{
+		"ru":        {"Казино"},
+		"en":        {"casino"},
+		AnyLanguage: {"viagra"},
+	}, 1)
+
+	tests := []struct {
+		name string
+		text string
+		hit  bool
+	}{
+		{name: "Русское слово", text: "Лучшее казино в городе", hit: true},
+		{name: "Английское слово", text: "Best CASINO online", hit: true},
+		{name: "Общий список", text: "Купите viagra", hit: true},
+		{name: "Слово чужого языка", text: "Здесь пишут casino латиницей", hit: false},
+		{name: "Часть слова", text: "casinos are fun", hit: false},
+		{name: "Чистый текст", text: "Обычный пост", hit: false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := list.Check(context.Background(), Content{Text: tc.text})
+			if err != nil {
+				t.Fatalf("ошибка не ожидалась: %v", err)
+			}
+			if (res.Score > 0) != tc.hit {
+				t.Fatalf("ожидалось срабатывание %v, а получили %+v", tc.hit, res)
+			}
+		})
+	}
+}
+
+// Тест на лимит ссылок.
+func TestLinkLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		max  int
+		text string
+		hit  bool
+	}{
+		{name: "В пределах", max: 2, text: "https://a.ru и http://b.ru", hit: false},
+		{name: "Больше лимита", max: 2, text: "https://a.ru http://b.ru www.c.ru", hit: true},
+		{name: "Без лимита", max: 0, text: "https://a.ru http://b.ru www.c.ru", hit: false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			res, _ := NewLinkLimit(tc.max, 1).Check(context.Background(), Content{Text: tc.text})
+			if (res.Score > 0) != tc.hit {
+				t.Fatalf("ожидалось срабатывание %v, а получили %+v", tc.hit, res)
+			}
+		})
+	}
+}
+
+// Тест на повтор текста в окне времени.
+func TestDuplicate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDuplicate(time.Minute, 1)
+	d.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		author  string
+		text    string
+		hit     bool
+	}{
+		{name: "Первый текст", author: "alice", text: "Привет всем", hit: false},
+		{name: "Повтор с другим регистром", advance: 10 * time.Second, author: "alice", text: "привет   ВСЕМ", hit: true},
+		{name: "Тот же текст другого автора", author: "bob", text: "Привет всем", hit: false},
+		{name: "Повтор после окна", advance: 2 * time.Minute, author: "alice", text: "Привет всем", hit: false},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		res, err := d.Check(ctx, Content{AuthorID: step.author, Text: step.text})
+		if err != nil {
+			t.Fatalf("%s: ошибка не ожидалась: %v", step.name, err)
+		}
+		if (res.Score > 0) != step.hit {
+			t.Fatalf("%s: ожидалось срабатывание %v, а получили %+v", step.name, step.hit, res)
+		}
+	}
+}
+
+// Тест на режимы пайплайна.
+func TestPipeline_Run(t *testing.T) {
+	spam := NewBlockList(map[string][]string{AnyLanguage: {"spam"}}, 1)
+	links := NewLinkLimit(1, 0.5)
+
+	tests := []struct {
+		name      string
+		mode      Mode
+		threshold float64
+		text      string
+		rejected  bool
+		state     models.ModerationState
+	}{
+		{name: "Выключен", mode: ModeOff, text: "spam", state: models.ModerationStateVisible},
+		{name: "Чистый текст", mode: ModeReject, text: "hello", state: models.ModerationStateVisible},
+		{name: "Отклонение", mode: ModeReject, text: "spam", rejected: true},
+		{name: "На модерацию", mode: ModeScore, text: "spam", state: models.ModerationStatePending},
+		{name: "Ниже порога", mode: ModeScore, text: "https://a https://b", state: models.ModerationStateVisible},
+		{name: "Сумма очков", mode: ModeScore, threshold: 1.5, text: "spam https://a https://b", state: models.ModerationStatePending},
+		{name: "Сумма ниже порога", mode: ModeScore, threshold: 2, text: "spam https://a https://b", state: models.ModerationStateVisible},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			verdict, err := NewPipeline(tc.mode, tc.threshold, spam, links, nil).Run(context.Background(), Content{Text: tc.text})
+			var rejected *RejectedError
+			if tc.rejected {
+				if !errors.As(err, &rejected) || len(rejected.Reasons) == 0 {
+					t.Fatalf("ожидалось отклонение, а получили %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ошибка не ожидалась: %v", err)
+			}
+			if verdict.State != tc.state {
+				t.Fatalf("ожидалось состояние %s, а получили %s", tc.state, verdict.State)
+			}
+		})
+	}
+}
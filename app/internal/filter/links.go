@@ -0,0 +1,31 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)`)
+
+// LinkLimit ограничение количества ссылок в тексте, 0 - без ограничения.
+type LinkLimit struct {
+	max    int
+	weight float64
+}
+
+func NewLinkLimit(max int, weight float64) *LinkLimit {
+	return &LinkLimit{max: max, weight: weight}
+}
+
+func (l *LinkLimit) Name() string { return "links" }
+
+func (l *LinkLimit) Check(_ context.Context, c Content) (Result, error) {
+	if l.max <= 0 {
+		return Result{}, nil
+	}
+	if n := len(linkPattern.FindAllStringIndex(c.Text, -1)); n > l.max {
+		return Result{Score: l.weight, Reason: fmt.Sprintf("ссылок %d (<= %d)", n, l.max)}, nil
+	}
+	return Result{}, nil
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errBatchDisabled = errors.New("пакетные запросы выключены")
+
+// batchHandler POST /query: объект передается gqlgen как есть, массив операций
+// выполняется по одной и ответ собирается в массив в том же порядке.
+// Пакеты больше maxBatch отклоняются целиком, maxBatch 0 - пакеты выключены.
+func batchHandler(srv http.Handler, maxBatch int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, errorBody(errBodyTooLarge, "BODY_TOO_LARGE"))
+				return
+			}
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		trimmed := bytes.TrimLeft(body, " \t\r\n")
+		if len(trimmed) == 0 || trimmed[0] != '[' {
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			srv.ServeHTTP(c.Writer, c.Request)
+			return
+		}
+
+		var ops []json.RawMessage
+		if err := json.Unmarshal(trimmed, &ops); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, errorBody(fmt.Errorf("пакет: %w", err), "BAD_REQUEST"))
+			return
+		}
+		if maxBatch <= 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, errorBody(errBatchDisabled, "BATCH_DISABLED"))
+			return
+		}
+		if len(ops) > maxBatch {
+			err := fmt.Errorf("в пакете %d операций, допустимо не больше %d", len(ops), maxBatch)
+			c.AbortWithStatusJSON(http.StatusBadRequest, errorBody(err, "BATCH_TOO_LARGE"))
+			return
+		}
+
+		out := make([]json.RawMessage, 0, len(ops))
+		for _, op := range ops {
+			req := c.Request.Clone(c.Request.Context())
+			req.Body = io.NopCloser(bytes.NewReader(op))
+			req.ContentLength = int64(len(op))
+			w := &bufferedWriter{header: http.Header{}}
+			srv.ServeHTTP(w, req)
+			resp := bytes.TrimSpace(w.body.Bytes())
+			if len(resp) == 0 {
+				resp = []byte("null")
+			}
+			out = append(out, resp)
+		}
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// bufferedWriter ответ одной операции пакета.
+type bufferedWriter struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header         { return w.header }
+func (w *bufferedWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *bufferedWriter) WriteHeader(int)             {}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/RoGogDBD/GQLGo/internal/lifecycle"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+	"github.com/RoGogDBD/GQLGo/internal/service"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// errorPresenter добавляет в extensions код ошибки и детали блокировки.
+// Ошибки репозитория получают коды NOT_FOUND, CONFLICT и INVALID_CURSOR, дедлайн запроса - TIMEOUT,
+// остановка сервера - SHUTTING_DOWN.
+func errorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	var banErr *service.BanError
+	if errors.As(err, &banErr) {
+		setCode(gqlErr, "BANNED")
+		gqlErr.Extensions["reason"] = banErr.Ban.Reason
+		if banErr.Ban.Until != nil {
+			gqlErr.Extensions["until"] = banErr.Ban.Until.UTC().Format(time.RFC3339)
+		}
+	}
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		setCode(gqlErr, "NOT_FOUND")
+	case errors.Is(err, repository.ErrConflict):
+		setCode(gqlErr, "CONFLICT")
+	case errors.Is(err, repository.ErrInvalidCursor):
+		setCode(gqlErr, "INVALID_CURSOR")
+	case errors.Is(err, context.DeadlineExceeded):
+		setCode(gqlErr, "TIMEOUT")
+	case errors.Is(err, lifecycle.ErrShuttingDown):
+		setCode(gqlErr, "SHUTTING_DOWN")
+	}
+	return gqlErr
+}
+
+// setCode записывает код ошибки в extensions.
+func setCode(gqlErr *gqlerror.Error, code string) {
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = map[string]any{}
+	}
+	gqlErr.Extensions["code"] = code
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/version"
+	"github.com/gin-gonic/gin"
+)
+
+// checkTimeout предел одной проверки готовности.
+const checkTimeout = 2 * time.Second
+
+// Check проверка готовности, ошибка - сервис не готов принимать запросы.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health состояние процесса для оркестратора: /healthz, /readyz и /version.
+type Health struct {
+	mu       sync.RWMutex
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+func NewHealth() *Health {
+	return &Health{}
+}
+
+// AddCheck добавляет проверку готовности, результат в /readyz под именем name.
+func (h *Health) AddCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// Drain переводит /readyz в 503, чтобы балансировщик убрал инстанс до остановки сервера.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// healthz процесс жив и обслуживает HTTP.
+func (h *Health) healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyz выполняет проверки по очереди, ответ 503 если остановка или хоть одна проверка не прошла.
+func (h *Health) readyz(c *gin.Context) {
+	h.mu.RLock()
+	checks := append([]namedCheck(nil), h.checks...)
+	h.mu.RUnlock()
+
+	status, code := "ok", http.StatusOK
+	results := make(map[string]string, len(checks))
+	for _, nc := range checks {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), checkTimeout)
+		err := nc.check(ctx)
+		cancel()
+		if err != nil {
+			results[nc.name] = err.Error()
+			status, code = "fail", http.StatusServiceUnavailable
+			continue
+		}
+		results[nc.name] = "ok"
+	}
+	if h.draining.Load() {
+		status, code = "draining", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": results})
+}
+
+// versionInfo сведения о сборке.
+func (h *Health) versionInfo(c *gin.Context) {
+	c.JSON(http.StatusOK, version.Get())
+}
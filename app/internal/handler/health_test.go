@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Тест на ответы /healthz и /readyz по проверкам и остановке.
+func TestHealth_Readyz(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	errDown := errors.New("нет подключения")
+
+	tests := []struct {
+		name       string
+		check      Check
+		drain      bool
+		wantCode   int
+		wantStatus string
+		wantCheck  string
+	}{
+		{name: "Готов", check: func(context.Context) error { return nil }, wantCode: http.StatusOK, wantStatus: "ok", wantCheck: "ok"},
+		{name: "Проверка не прошла", check: func(context.Context) error { return errDown }, wantCode: http.StatusServiceUnavailable, wantStatus: "fail", wantCheck: errDown.Error()},
+		{name: "Остановка", check: func(context.Context) error { return nil }, drain: true, wantCode: http.StatusServiceUnavailable, wantStatus: "draining", wantCheck: "ok"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHealth()
+			h.AddCheck("db", tc.check)
+			if tc.drain {
+				h.Drain()
+			}
+			r := gin.New()
+			r.GET("/healthz", h.healthz)
+			r.GET("/readyz", h.readyz)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("healthz: ожидался код 200, а получили %d", rec.Code)
+			}
+
+			rec = httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tc.wantCode {
+				t.Fatalf("ожидался код %d, а получили %d", tc.wantCode, rec.Code)
+			}
+			var body struct {
+				Status string            `json:"status"`
+				Checks map[string]string `json:"checks"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("ответ: %v", err)
+			}
+			if body.Status != tc.wantStatus || body.Checks["db"] != tc.wantCheck {
+				t.Fatalf("ожидалось %s/%s, а получили %+v", tc.wantStatus, tc.wantCheck, body)
+			}
+		})
+	}
+}
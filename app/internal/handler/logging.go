@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+	"github.com/RoGogDBD/GQLGo/internal/reqctx"
+	"github.com/gin-gonic/gin"
+)
+
+// errInternal ответ клиенту на панику резолвера, подробности только в логе.
+var errInternal = errors.New("внутренняя ошибка сервера")
+
+// accessLog строка журнала доступа на каждый запрос, кроме probePaths.
+// Уровень по статусу: 5xx - error, 4xx - warn, остальное - info.
+func accessLog(log logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(probePaths, c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		ctx := reqctx.WithOperations(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		// Контекст после Next: в нем уже id запроса и трассировка.
+		status := c.Writer.Status()
+		l := log.Ctx(c.Request.Context()).With(
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"duration", time.Since(start),
+			"ip", c.ClientIP(),
+			"size", c.Writer.Size(),
+		)
+		if ops := reqctx.Operations(ctx); len(ops) > 0 {
+			l = l.With("operations", ops)
+		}
+		switch {
+		case status >= http.StatusInternalServerError:
+			l.Errorf("%s %s %d", c.Request.Method, c.Request.URL.Path, status)
+		case status >= http.StatusBadRequest:
+			l.Warnf("%s %s %d", c.Request.Method, c.Request.URL.Path, status)
+		default:
+			l.Infof("%s %s %d", c.Request.Method, c.Request.URL.Path, status)
+		}
+	}
+}
+
+// recovery паника обработчика в лог с полями запроса и ответ 500.
+func recovery(log logger.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		log.Ctx(c.Request.Context()).With("stack", string(debug.Stack())).Errorf("panic: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// recoverResolver паника резолвера в лог, клиенту - ошибка без подробностей.
+func recoverResolver(log logger.Logger) graphql.RecoverFunc {
+	return func(ctx context.Context, err any) error {
+		log.Ctx(ctx).With("stack", string(debug.Stack())).Errorf("panic в резолвере: %v", err)
+		return errInternal
+	}
+}
+
+// operationName имя операции вида "query Feed" в контекст и в журнал доступа.
+func operationName(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+	if oc == nil || oc.Operation == nil {
+		return next(ctx)
+	}
+	name := oc.OperationName
+	if name == "" {
+		name = oc.Operation.Name
+	}
+	if name == "" {
+		name = "anonymous"
+	}
+	op := fmt.Sprintf("%s %s", oc.Operation.Operation, name)
+	reqctx.AddOperation(ctx, op)
+
+	info := reqctx.From(ctx)
+	info.Operation = op
+	return next(reqctx.With(ctx, info))
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+	"github.com/RoGogDBD/GQLGo/internal/qraphql/graph"
+	"github.com/RoGogDBD/GQLGo/internal/reqctx"
+	"github.com/gin-gonic/gin"
+)
+
+// logLine одна запись recordLogger.
+type logLine struct {
+	level  string
+	msg    string
+	fields map[string]any
+}
+
+// recordLogger запоминает записи вместе с полями.
+type recordLogger struct {
+	mu     *sync.Mutex
+	lines  *[]logLine
+	fields []any
+}
+
+func newRecordLogger() recordLogger {
+	return recordLogger{mu: &sync.Mutex{}, lines: &[]logLine{}}
+}
+
+func (l recordLogger) log(level, format string, args ...any) {
+	fields := map[string]any{}
+	for i := 0; i+1 < len(l.fields); i += 2 {
+		fields[fmt.Sprint(l.fields[i])] = l.fields[i+1]
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.lines = append(*l.lines, logLine{level: level, msg: fmt.Sprintf(format, args...), fields: fields})
+}
+
+func (l recordLogger) Debugf(format string, args ...any) { l.log("debug", format, args...) }
+func (l recordLogger) Infof(format string, args ...any)  { l.log("info", format, args...) }
+func (l recordLogger) Warnf(format string, args ...any)  { l.log("warn", format, args...) }
+func (l recordLogger) Errorf(format string, args ...any) { l.log("error", format, args...) }
+
+func (l recordLogger) With(kv ...any) logger.Logger {
+	l.fields = append(append([]any(nil), l.fields...), kv...)
+	return l
+}
+
+func (l recordLogger) Ctx(ctx context.Context) logger.Logger {
+	return l.With(logger.ContextFields(ctx)...)
+}
+
+func (l recordLogger) all() []logLine {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]logLine(nil), *l.lines...)
+}
+
+// Тест на журнал доступа: id запроса, операции GraphQL, уровень по статусу и пропуск проб.
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		wantLines int
+		wantLevel string
+		wantOps   []string
+	}{
+		{name: "Именованная операция", method: http.MethodPost, path: "/query", body: `{"query":"query Ping { __typename }"}`, wantLines: 1, wantLevel: "info", wantOps: []string{"query Ping"}},
+		{name: "Анонимная операция", method: http.MethodPost, path: "/query", body: `{"query":"{ __typename }"}`, wantLines: 1, wantLevel: "info", wantOps: []string{"query anonymous"}},
+		{name: "Неизвестный путь", method: http.MethodGet, path: "/nope", wantLines: 1, wantLevel: "warn"},
+		{name: "Проба не пишется", method: http.MethodGet, path: "/healthz"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			log := newRecordLogger()
+			r := NewRouter(&graph.Resolver{Logger: log}, RouterConfig{})
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(reqctx.HeaderRequestID, "req-1")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			lines := log.all()
+			if len(lines) != tc.wantLines {
+				t.Fatalf("ожидалось %d записей, а получили %+v", tc.wantLines, lines)
+			}
+			if tc.wantLines == 0 {
+				return
+			}
+			line := lines[0]
+			if line.level != tc.wantLevel {
+				t.Fatalf("ожидался уровень %s, а получили %s", tc.wantLevel, line.level)
+			}
+			if line.fields["request_id"] != "req-1" || line.fields["status"] != rec.Code {
+				t.Fatalf("поля запроса: %+v", line.fields)
+			}
+			ops, _ := line.fields["operations"].([]string)
+			if fmt.Sprint(ops) != fmt.Sprint(tc.wantOps) {
+				t.Fatalf("ожидались операции %v, а получили %v", tc.wantOps, ops)
+			}
+		})
+	}
+}
+
+// Тест на панику резолвера: в лог с полями запроса, клиенту без подробностей.
+func TestRecoverResolver(t *testing.T) {
+	log := newRecordLogger()
+	ctx := reqctx.With(context.Background(), reqctx.Info{RequestID: "req-1", Operation: "mutation AddPost"})
+
+	err := recoverResolver(log)(ctx, "boom")
+	if err != errInternal {
+		t.Fatalf("ожидалась ошибка %v, а получили %v", errInternal, err)
+	}
+	lines := log.all()
+	if len(lines) != 1 || lines[0].level != "error" {
+		t.Fatalf("ожидалась одна запись error, а получили %+v", lines)
+	}
+	if lines[0].fields["request_id"] != "req-1" || lines[0].fields["operation"] != "mutation AddPost" {
+		t.Fatalf("поля запроса: %+v", lines[0].fields)
+	}
+}
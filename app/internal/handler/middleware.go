@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/lifecycle"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+	"github.com/RoGogDBD/GQLGo/internal/reqctx"
+	"github.com/RoGogDBD/GQLGo/internal/service"
+	"github.com/RoGogDBD/GQLGo/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxRequestIDLength длиннее входящий id запроса заменяется своим.
+const maxRequestIDLength = 128
+
+// requestMiddleware id запроса из заголовка (или новый) и IP клиента в контекст.
+func requestMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(reqctx.HeaderRequestID)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.NewString()
+		}
+		c.Header(reqctx.HeaderRequestID, id)
+		ctx := reqctx.With(c.Request.Context(), reqctx.Info{RequestID: id, IP: c.ClientIP()})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// traceMiddleware спан HTTP запроса, родитель - traceparent из заголовков.
+func traceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := tracing.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ctx, span := tracing.Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+			))
+		defer span.End()
+		if id := reqctx.From(ctx).RequestID; id != "" {
+			span.SetAttributes(attribute.String("http.request.id", id))
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, strconv.Itoa(status))
+		}
+	}
+}
+
+// viewerMiddleware берет пользователя из заголовка X-User-ID, роль - только с токеном ролей.
+func viewerMiddleware(users repository.UserRepo, roleToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if id := c.GetHeader(auth.HeaderUserID); id != "" {
+			elevated := auth.CheckRoleToken(roleToken, c.GetHeader(auth.HeaderRoleToken))
+			ctx := auth.WithViewer(c.Request.Context(), loadViewer(c.Request.Context(), users, id, elevated))
+			c.Request = c.Request.WithContext(ctx)
+		}
+		c.Next()
+	}
+}
+
+// websocketInit берет пользователя и контекст трассировки из init payload подписки.
+// Соединение регистрируется в lc, чтобы при остановке закрыть его с close frame.
+func websocketInit(users repository.UserRepo, lc *lifecycle.Manager, roleToken string) transport.WebsocketInitFunc {
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		if payload.GetString("traceparent") != "" {
+			ctx = tracing.Extract(ctx, tracing.PayloadCarrier(payload))
+		}
+		if id := payload.GetString(auth.InitPayloadUserID); id != "" {
+			elevated := auth.CheckRoleToken(roleToken, payload.GetString(auth.InitPayloadRoleToken))
+			ctx = auth.WithViewer(ctx, loadViewer(ctx, users, id, elevated))
+		}
+		if lc != nil {
+			var err error
+			if ctx, err = lc.Connect(ctx); err != nil {
+				return ctx, nil, err
+			}
+		}
+		return ctx, nil, nil
+	}
+}
+
+// websocketClose снимает соединение с учета lc.
+func websocketClose(lc *lifecycle.Manager) transport.WebsocketCloseFunc {
+	return func(ctx context.Context, _ int) {
+		if lc != nil {
+			lc.Disconnect(ctx)
+		}
+	}
+}
+
+// loadViewer дополняет пользователя ролью, неизвестный пользователь остается без роли.
+// id присылает клиент, поэтому роль модератора или администратора из базы
+// действует только при elevated - верном токене ролей.
+func loadViewer(ctx context.Context, users repository.UserRepo, id string, elevated bool) auth.Viewer {
+	viewer := auth.Viewer{UserID: id}
+	if users == nil {
+		return viewer
+	}
+	if u, err := users.GetByID(ctx, id); err == nil && u != nil && (elevated || !auth.Elevated(u.Role)) {
+		viewer.Role = u.Role
+	}
+	return viewer
+}
+
+// allowedWhileBanned мутации, доступные заблокированному пользователю: они ничего не публикуют.
+var allowedWhileBanned = map[string]struct{}{
+	"markNotificationsRead": {},
+	"markPostRead":          {},
+	"removeBookmark":        {},
+	"unfollowUser":          {},
+}
+
+// banGuard не пускает заблокированных пользователей в мутации и подписки.
+func banGuard(moderation *service.ModerationService) graphql.FieldMiddleware {
+	return func(ctx context.Context, next graphql.Resolver) (any, error) {
+		fc := graphql.GetFieldContext(ctx)
+		if fc == nil || (fc.Object != "Mutation" && fc.Object != "Subscription") {
+			return next(ctx)
+		}
+		if _, ok := allowedWhileBanned[fc.Field.Name]; ok {
+			return next(ctx)
+		}
+		if viewer, ok := auth.ViewerFrom(ctx); ok {
+			if err := moderation.CheckAuthor(ctx, viewer.UserID); err != nil {
+				return nil, err
+			}
+		}
+		return next(ctx)
+	}
+}
+
+// auditTargetArgs аргументы мутаций с id цели, по приоритету.
+var auditTargetArgs = []string{"postId", "userId", "targetId", "id"}
+
+// auditMutations пишет успешные мутации в журнал в одной транзакции с ними.
+func auditMutations(audit *service.AuditService) graphql.FieldMiddleware {
+	return func(ctx context.Context, next graphql.Resolver) (any, error) {
+		fc := graphql.GetFieldContext(ctx)
+		if fc == nil || fc.Object != "Mutation" {
+			return next(ctx)
+		}
+		var targetID string
+		for _, name := range auditTargetArgs {
+			if id, ok := fc.Args[name].(string); ok && id != "" {
+				targetID = id
+				break
+			}
+		}
+		return audit.Mutation(ctx, fc.Field.Name, fc.Field.Definition.Type.Name(), targetID, next)
+	}
+}
+
+// operationDeadline один дедлайн на операцию Query или Mutation вместе со всеми ее полями.
+// Ответ собирается целиком внутри next, поэтому таймер освобождается сразу после него.
+// Подписки живут долго и дедлайна не получают.
+func operationDeadline(timeout time.Duration) graphql.ResponseMiddleware {
+	return func(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+		oc := graphql.GetOperationContext(ctx)
+		if timeout <= 0 || oc == nil || oc.Operation == nil || oc.Operation.Operation == ast.Subscription {
+			return next(ctx)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return next(ctx)
+	}
+}
+
+// shutdownGuard после начала остановки отклоняет новые мутации и подписки,
+// начатые мутации учитываются, чтобы остановка их дождалась.
+func shutdownGuard(lc *lifecycle.Manager) graphql.OperationMiddleware {
+	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		oc := graphql.GetOperationContext(ctx)
+		if oc == nil || oc.Operation == nil {
+			return next(ctx)
+		}
+		switch oc.Operation.Operation {
+		case ast.Subscription:
+			if err := lc.Subscribe(); err != nil {
+				return graphql.OneShot(&graphql.Response{Errors: gqlerror.List{errorPresenter(ctx, err)}})
+			}
+		case ast.Mutation:
+			done, err := lc.BeginMutation()
+			if err != nil {
+				return graphql.OneShot(&graphql.Response{Errors: gqlerror.List{errorPresenter(ctx, err)}})
+			}
+			responses := next(ctx)
+			return func(ctx context.Context) *graphql.Response {
+				defer done()
+				return responses(ctx)
+			}
+		}
+		return next(ctx)
+	}
+}
+
+// primaryForMutations мутации читают из основной базы, чтобы ответ видел свою запись.
+func primaryForMutations(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	if oc := graphql.GetOperationContext(ctx); oc != nil && oc.Operation != nil && oc.Operation.Operation == ast.Mutation {
+		ctx = repository.WithPrimary(ctx)
+	}
+	return next(ctx)
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Тест на дедлайн операции: один на запрос и мутацию, таймер освобождается после ответа.
+func TestOperationDeadline(t *testing.T) {
+	tests := []struct {
+		name         string
+		operation    ast.Operation
+		timeout      time.Duration
+		wantDeadline bool
+	}{
+		{name: "Запрос", operation: ast.Query, timeout: time.Minute, wantDeadline: true},
+		{name: "Мутация", operation: ast.Mutation, timeout: time.Minute, wantDeadline: true},
+		{name: "Подписка", operation: ast.Subscription, timeout: time.Minute},
+		{name: "Без дедлайна", operation: ast.Query},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := graphql.WithOperationContext(context.Background(), &graphql.OperationContext{
+				Operation: &ast.OperationDefinition{Operation: tc.operation},
+			})
+			var opCtx context.Context
+			operationDeadline(tc.timeout)(ctx, func(ctx context.Context) *graphql.Response {
+				opCtx = ctx
+				return &graphql.Response{}
+			})
+
+			if _, ok := opCtx.Deadline(); ok != tc.wantDeadline {
+				t.Fatalf("ожидался дедлайн %v, а получили %v", tc.wantDeadline, ok)
+			}
+			if tc.wantDeadline && opCtx.Err() == nil {
+				t.Fatal("контекст операции должен отменяться после ответа")
+			}
+		})
+	}
+}
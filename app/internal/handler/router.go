@@ -8,27 +8,156 @@ import (
 	"github.com/99designs/gqlgen/graphql/handler/lru"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/RoGogDBD/GQLGo/internal/lifecycle"
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+	"github.com/RoGogDBD/GQLGo/internal/metrics"
 	"github.com/RoGogDBD/GQLGo/internal/qraphql/graph"
+	"github.com/RoGogDBD/GQLGo/internal/tracing"
+	"github.com/RoGogDBD/GQLGo/internal/trusted"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/vektah/gqlparser/v2/ast"
 )
 
-func NewRouter(resolver *graph.Resolver) *gin.Engine {
+// Значения RouterConfig по умолчанию.
+const (
+	defaultCacheSize = 1000
+	defaultKeepAlive = 10 * time.Second
+)
+
+// RouterConfig настройки обработки запросов, нулевые значения - по умолчанию.
+type RouterConfig struct {
+	// QueryTimeout дедлайн каждой операции Query и Mutation, 0 - без дедлайна.
+	QueryTimeout time.Duration
+	// ComplexityLimit предел сложности запроса, 0 - без предела.
+	ComplexityLimit int
+	QueryCacheSize  int
+	APQCacheSize    int
+	// RoleToken секрет для X-Role-Token: без него роли модератора и администратора
+	// из базы не действуют, пусто - не действуют никогда.
+	RoleToken string
+	// DisableSubscriptions не принимать websocket подписки.
+	DisableSubscriptions bool
+	// KeepAlive интервал ping websocket.
+	KeepAlive time.Duration
+	// Health проверки /readyz и остановка, nil - без проверок.
+	Health *Health
+	// Metrics метрики операций и /metrics, nil - выключены.
+	Metrics *metrics.Metrics
+	// MetricsElsewhere /metrics отдается на отдельном адресе, а не этим роутером.
+	MetricsElsewhere bool
+	// TraceResolvers спан на каждый резолвер, а не только на операцию.
+	TraceResolvers bool
+	// Lifecycle учет мутаций и websocket соединений для остановки, nil - без учета.
+	Lifecycle *lifecycle.Manager
+	// CORS источники браузерных приложений, без Origins CORS выключен.
+	CORS CORSConfig
+	// WebsocketOrigins источники подписок помимо того же хоста, пусто - как CORS.Origins.
+	WebsocketOrigins []string
+	// MaxBodySize предел тела запроса в байтах.
+	MaxBodySize int64
+	// MaxBatchSize операций в одном POST массивом, 0 - пакеты выключены.
+	MaxBatchSize int
+	// TrustedProxies прокси, которым верим X-Forwarded-For при определении IP клиента,
+	// пусто - IP клиента берется из соединения.
+	TrustedProxies []string
+	// HSTS max-age Strict-Transport-Security, 0 - без заголовка.
+	HSTS time.Duration
+	// DisableIntrospection не отвечать на __schema и __type.
+	DisableIntrospection bool
+	// DisablePlayground не отдавать playground на /.
+	DisablePlayground bool
+	// TrustedDocuments манифест одобренных операций, nil - любые операции.
+	TrustedDocuments *trusted.Manifest
+	// TrustedMode report - чужие операции только в лог, enforce - отклоняются.
+	TrustedMode trusted.Mode
+}
+
+// probePaths запросы оркестратора, не пишутся в лог доступа.
+var probePaths = []string{"/healthz", "/readyz", "/metrics"}
+
+func NewRouter(resolver *graph.Resolver, cfg RouterConfig) *gin.Engine {
+	health := cfg.Health
+	if health == nil {
+		health = NewHealth()
+	}
+
+	log := resolver.Logger
+	if log == nil {
+		log = logger.Nop()
+	}
+
+	wsOrigins := cfg.WebsocketOrigins
+	if len(wsOrigins) == 0 {
+		wsOrigins = cfg.CORS.Origins
+	}
+
 	r := gin.New()
-	r.Use(gin.Logger(), gin.Recovery())
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Errorf("доверенные прокси: %v", err)
+		_ = r.SetTrustedProxies(nil)
+	}
+	r.Use(recovery(log), accessLog(log), securityHeaders(cfg.HSTS), corsMiddleware(cfg.CORS), bodyLimit(orDefault(cfg.MaxBodySize, defaultMaxBodySize)),
+		requestMiddleware(), traceMiddleware(), viewerMiddleware(resolver.UserRepo, cfg.RoleToken))
 	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
-	srv.AddTransport(transport.Websocket{
-		KeepAlivePingInterval: 10 * time.Second,
-	})
+	if !cfg.DisableSubscriptions {
+		srv.AddTransport(transport.Websocket{
+			KeepAlivePingInterval: orDefault(cfg.KeepAlive, defaultKeepAlive),
+			InitFunc:              websocketInit(resolver.UserRepo, cfg.Lifecycle, cfg.RoleToken),
+			CloseFunc:             websocketClose(cfg.Lifecycle),
+			Upgrader:              websocket.Upgrader{CheckOrigin: checkOrigin(wsOrigins)},
+		})
+	}
 	srv.AddTransport(transport.GET{})
 	srv.AddTransport(transport.POST{})
-	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
-	srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New[string](1000)})
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](orDefault(cfg.QueryCacheSize, defaultCacheSize)))
+	srv.SetErrorPresenter(errorPresenter)
+	if !cfg.DisableIntrospection {
+		srv.Use(extension.Introspection{})
+	}
+	srv.SetRecoverFunc(recoverResolver(log))
+	srv.Use(tracing.Extension{Resolvers: cfg.TraceResolvers})
+	if cfg.Metrics != nil {
+		srv.Use(cfg.Metrics)
+	}
+	srv.AroundOperations(operationName)
+	if cfg.Lifecycle != nil {
+		srv.AroundOperations(shutdownGuard(cfg.Lifecycle))
+	}
+	srv.AroundOperations(primaryForMutations)
+	srv.AroundResponses(operationDeadline(cfg.QueryTimeout))
+	srv.AroundFields(banGuard(resolver.ModerationService))
+	srv.AroundFields(auditMutations(resolver.AuditService))
+	if cfg.TrustedDocuments != nil && (cfg.TrustedMode == trusted.ModeReport || cfg.TrustedMode == trusted.ModeEnforce) {
+		// Раньше APQ: иначе APQ закэширует операцию, которой нет в манифесте.
+		srv.Use(trusted.Extension{Manifest: cfg.TrustedDocuments, Mode: cfg.TrustedMode, Logger: log})
+	}
+	srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New[string](orDefault(cfg.APQCacheSize, defaultCacheSize))})
+	if cfg.ComplexityLimit > 0 {
+		srv.Use(extension.FixedComplexityLimit(cfg.ComplexityLimit))
+	}
 
-	r.GET("/", gin.WrapH(playground.Handler("GraphQL playground", "/query")))
-	r.POST("/query", gin.WrapH(srv))
+	r.GET("/healthz", health.healthz)
+	r.GET("/readyz", health.readyz)
+	r.GET("/version", health.versionInfo)
+	if cfg.Metrics != nil && !cfg.MetricsElsewhere {
+		r.GET("/metrics", gin.WrapH(cfg.Metrics.Handler()))
+	}
+	if !cfg.DisablePlayground {
+		r.GET("/", gin.WrapH(playground.Handler("GraphQL playground", "/query")))
+	}
+	r.POST("/query", batchHandler(srv, cfg.MaxBatchSize))
 	r.GET("/query", gin.WrapH(srv))
 	return r
 }
 
+// orDefault def вместо нулевого значения.
+func orDefault[T comparable](v, def T) T {
+	var zero T
+	if v == zero {
+		return def
+	}
+	return v
+}
+
 // TODO: НОрмально вынести middleware и кучки
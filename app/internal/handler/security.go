@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/reqctx"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMaxBodySize предел тела запроса по умолчанию.
+const defaultMaxBodySize = 1 << 20
+
+// CORSConfig доступ браузерных приложений с других источников.
+type CORSConfig struct {
+	// Origins разрешенные источники, * - любой, пусто - CORS выключен.
+	Origins []string
+	// Headers заголовки запроса сверх стандартных.
+	Headers []string
+	// Credentials разрешить cookie и Authorization.
+	Credentials bool
+	// MaxAge сколько браузер кэширует preflight, 0 - не кэширует.
+	MaxAge time.Duration
+}
+
+// allowOrigin разрешен ли источник списком: * или точное совпадение без учета регистра.
+func allowOrigin(origins []string, origin string) bool {
+	for _, o := range origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// corsMiddleware заголовки CORS для разрешенных источников и ответ на preflight.
+// Запросы без Origin и с чужим Origin проходят без заголовков, их отклонит браузер;
+// preflight с чужого источника получает 403.
+func corsMiddleware(cfg CORSConfig) gin.HandlerFunc {
+	headers := strings.Join(append([]string{"Content-Type", reqctx.HeaderRequestID, auth.HeaderUserID, auth.HeaderRoleToken, "traceparent", "tracestate"}, cfg.Headers...), ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	wildcard := slices.Contains(cfg.Origins, "*") && !cfg.Credentials
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || len(cfg.Origins) == 0 {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !allowOrigin(cfg.Origins, origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		if wildcard {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.Credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			h.Set("Access-Control-Expose-Headers", reqctx.HeaderRequestID)
+			c.Next()
+			return
+		}
+		h.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		h.Set("Access-Control-Allow-Headers", headers)
+		if cfg.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// checkOrigin проверка Origin websocket: без Origin (не браузер), тот же хост или из списка.
+func checkOrigin(origins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		return strings.EqualFold(u.Host, r.Host) || allowOrigin(origins, origin)
+	}
+}
+
+// securityHeaders заголовки безопасности. Playground на / грузит скрипты с CDN,
+// поэтому строгий Content-Security-Policy ставится на все, кроме него.
+func securityHeaders(hsts time.Duration) gin.HandlerFunc {
+	hstsValue := "max-age=" + strconv.Itoa(int(hsts.Seconds()))
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		if c.Request.URL.Path != "/" {
+			h.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+		}
+		if hsts > 0 {
+			h.Set("Strict-Transport-Security", hstsValue)
+		}
+		c.Next()
+	}
+}
+
+// bodyLimit ограничивает тело запроса: заявленное больше limit - сразу 413,
+// иначе чтение сверх limit вернет *http.MaxBytesError.
+func bodyLimit(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, errorBody(errBodyTooLarge, "BODY_TOO_LARGE"))
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+var errBodyTooLarge = errors.New("тело запроса слишком большое")
+
+// errorBody ответ в формате GraphQL с одной ошибкой и кодом, для отказов до gqlgen.
+func errorBody(err error, code string) gin.H {
+	return gin.H{"errors": []gin.H{{"message": err.Error(), "extensions": gin.H{"code": code}}}}
+}
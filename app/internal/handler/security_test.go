@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/qraphql/graph"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+	"github.com/RoGogDBD/GQLGo/internal/trusted"
+	"github.com/gin-gonic/gin"
+)
+
+// Тест на CORS: разрешенный источник, чужой источник и preflight.
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := NewRouter(&graph.Resolver{}, RouterConfig{CORS: CORSConfig{Origins: []string{"https://app.example.com"}, MaxAge: time.Minute}})
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		preflight   bool
+		wantCode    int
+		wantAllowed string
+	}{
+		{name: "Preflight разрешен", method: http.MethodOptions, origin: "https://app.example.com", preflight: true, wantCode: http.StatusNoContent, wantAllowed: "https://app.example.com"},
+		{name: "Preflight чужой", method: http.MethodOptions, origin: "https://evil.example.com", preflight: true, wantCode: http.StatusForbidden},
+		{name: "Запрос разрешен", method: http.MethodPost, origin: "https://app.example.com", wantCode: http.StatusOK, wantAllowed: "https://app.example.com"},
+		{name: "Запрос чужой", method: http.MethodPost, origin: "https://evil.example.com", wantCode: http.StatusOK},
+		{name: "Без Origin", method: http.MethodPost, wantCode: http.StatusOK},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/query", strings.NewReader(`{"query":"{ __typename }"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if tc.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantCode {
+				t.Fatalf("ожидался код %d, а получили %d", tc.wantCode, rec.Code)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tc.wantAllowed {
+				t.Fatalf("ожидался Access-Control-Allow-Origin %q, а получили %q", tc.wantAllowed, got)
+			}
+			if tc.preflight && tc.wantAllowed != "" && rec.Header().Get("Access-Control-Max-Age") != "60" {
+				t.Fatalf("ожидался Access-Control-Max-Age 60, а получили %q", rec.Header().Get("Access-Control-Max-Age"))
+			}
+		})
+	}
+}
+
+// Тест на проверку Origin websocket.
+func TestCheckOrigin(t *testing.T) {
+	check := checkOrigin([]string{"https://app.example.com"})
+
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{name: "Без Origin", want: true},
+		{name: "Тот же хост", origin: "http://api.example.com", want: true},
+		{name: "Из списка", origin: "https://app.example.com", want: true},
+		{name: "Чужой", origin: "https://evil.example.com", want: false},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://api.example.com/query", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if got := check(req); got != tc.want {
+				t.Fatalf("ожидалось %v, а получили %v", tc.want, got)
+			}
+		})
+	}
+}
+
+// Тест на предел тела, пакеты, заголовки безопасности, интроспекцию и playground.
+func TestRouter_Limits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		cfg      RouterConfig
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{name: "Тело больше предела", cfg: RouterConfig{MaxBodySize: 16}, method: http.MethodPost, path: "/query", body: `{"query":"{ __typename }"}`, wantCode: http.StatusRequestEntityTooLarge, wantBody: "BODY_TOO_LARGE"},
+		{name: "Пакеты выключены", method: http.MethodPost, path: "/query", body: `[{"query":"{ __typename }"}]`, wantCode: http.StatusBadRequest, wantBody: "BATCH_DISABLED"},
+		{name: "Пакет больше предела", cfg: RouterConfig{MaxBatchSize: 1}, method: http.MethodPost, path: "/query", body: `[{"query":"{ __typename }"},{"query":"{ __typename }"}]`, wantCode: http.StatusBadRequest, wantBody: "BATCH_TOO_LARGE"},
+		{name: "Пакет", cfg: RouterConfig{MaxBatchSize: 2}, method: http.MethodPost, path: "/query", body: `[{"query":"{ a: __typename }"},{"query":"{ b: __typename }"}]`, wantCode: http.StatusOK, wantBody: `[{"data":{"a":"Query"}},{"data":{"b":"Query"}}]`},
+		{name: "Интроспекция", method: http.MethodPost, path: "/query", body: `{"query":"{ __schema { queryType { name } } }"}`, wantCode: http.StatusOK, wantBody: `"name":"Query"`},
+		{name: "Интроспекция выключена", cfg: RouterConfig{DisableIntrospection: true}, method: http.MethodPost, path: "/query", body: `{"query":"{ __schema { queryType { name } } }"}`, wantCode: http.StatusOK, wantBody: "introspection disabled"},
+		{name: "Playground", method: http.MethodGet, path: "/", wantCode: http.StatusOK},
+		{name: "Playground выключен", cfg: RouterConfig{DisablePlayground: true}, method: http.MethodGet, path: "/", wantCode: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRouter(&graph.Resolver{}, tc.cfg)
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantCode {
+				t.Fatalf("ожидался код %d, а получили %d: %s", tc.wantCode, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tc.wantBody) {
+				t.Fatalf("ожидалось %s в ответе, а получили %s", tc.wantBody, rec.Body.String())
+			}
+			if rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+				t.Fatal("нет заголовков безопасности")
+			}
+		})
+	}
+}
+
+// Тест на роль из базы: модератор и администратор только с токеном ролей.
+func TestViewerMiddleware_RoleToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	st, err := repository.NewMemoryStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.SeedUsers(&models.User{ID: "admin", Username: "admin", Role: models.RoleAdmin})
+	const secret = "0123456789abcdef"
+
+	tests := []struct {
+		name     string
+		secret   string
+		token    string
+		wantRole models.Role
+	}{
+		{name: "Без токена", secret: secret},
+		{name: "Чужой токен", secret: secret, token: "fedcba9876543210"},
+		{name: "Верный токен", secret: secret, token: secret, wantRole: models.RoleAdmin},
+		{name: "Секрет не задан", token: secret},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(viewerMiddleware(repository.NewMemoryUserRepo(st), tc.secret))
+			r.GET("/", func(c *gin.Context) {
+				viewer, _ := auth.ViewerFrom(c.Request.Context())
+				c.String(http.StatusOK, string(viewer.Role))
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(auth.HeaderUserID, "admin")
+			if tc.token != "" {
+				req.Header.Set(auth.HeaderRoleToken, tc.token)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if got := models.Role(rec.Body.String()); got != tc.wantRole {
+				t.Fatalf("ожидалась роль %q, а получили %q", tc.wantRole, got)
+			}
+		})
+	}
+}
+
+// Тест на HSTS и Content-Security-Policy.
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := NewRouter(&graph.Resolver{}, RouterConfig{HSTS: time.Hour})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["status"] != "ok" {
+		t.Fatalf("healthz: %s", rec.Body.String())
+	}
+	if got := rec.Header().Get("Strict-Transport-Security"); got != "max-age=3600" {
+		t.Fatalf("ожидался HSTS max-age=3600, а получили %q", got)
+	}
+	if rec.Header().Get("Content-Security-Policy") == "" {
+		t.Fatal("ожидался Content-Security-Policy")
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get("Content-Security-Policy") != "" {
+		t.Fatal("playground не должен получать строгий Content-Security-Policy")
+	}
+}
+
+// Тест на доверенные документы: в enforce чужая операция отклоняется до выполнения.
+func TestRouter_TrustedDocuments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manifest, err := trusted.Parse([]byte(`{"ping": "query Ping { __typename }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRouter(&graph.Resolver{}, RouterConfig{TrustedDocuments: manifest, TrustedMode: trusted.ModeEnforce})
+
+	tests := []struct {
+		name     string
+		body     string
+		wantBody string
+	}{
+		{name: "По хэшу", body: `{"extensions":{"persistedQuery":{"version":1,"sha256Hash":"ping"}}}`, wantBody: `{"data":{"__typename":"Query"}}`},
+		{name: "Чужая операция", body: `{"query":"{ __schema { types { name } } }"}`, wantBody: trusted.CodeNotTrusted},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if !strings.Contains(rec.Body.String(), tc.wantBody) {
+				t.Fatalf("ожидалось %s в ответе, а получили %s", tc.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+// Тест на IP клиента: X-Forwarded-For учитывается только от доверенного прокси.
+func TestRouter_TrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		proxies []string
+		want    string
+	}{
+		{name: "Без доверенных прокси", want: "192.0.2.1"},
+		{name: "Доверенный прокси", proxies: []string{"192.0.2.0/24"}, want: "203.0.113.7"},
+		{name: "Чужой прокси", proxies: []string{"10.0.0.0/8"}, want: "192.0.2.1"},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRouter(&graph.Resolver{}, RouterConfig{TrustedProxies: tc.proxies})
+			r.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if got := rec.Body.String(); got != tc.want {
+				t.Fatalf("ожидался IP %s, а получили %s", tc.want, got)
+			}
+		})
+	}
+}
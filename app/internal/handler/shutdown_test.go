@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RoGogDBD/GQLGo/internal/lifecycle"
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+	"github.com/RoGogDBD/GQLGo/internal/qraphql/graph"
+	"github.com/gin-gonic/gin"
+)
+
+// Тест на отказ в мутациях после начала остановки, запросы на чтение проходят.
+func TestShutdownGuard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	lc := lifecycle.New(lifecycle.Config{}, logger.Nop())
+	lc.Drain()
+	r := NewRouter(&graph.Resolver{}, RouterConfig{Lifecycle: lc})
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode string
+	}{
+		{name: "Мутация", query: `mutation { markNotificationsRead }`, wantCode: "SHUTTING_DOWN"},
+		{name: "Запрос", query: `{ __typename }`},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{"query": tc.query})
+			req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			var resp struct {
+				Errors []struct {
+					Extensions map[string]any `json:"extensions"`
+				} `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("ответ: %v", err)
+			}
+			var code any
+			if len(resp.Errors) > 0 {
+				code = resp.Errors[0].Extensions["code"]
+			}
+			if tc.wantCode == "" && code != nil || tc.wantCode != "" && code != tc.wantCode {
+				t.Fatalf("ожидался код %q, а получили %s", tc.wantCode, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+)
+
+// ErrShuttingDown новые подписки и мутации после начала остановки.
+var ErrShuttingDown = errors.New("сервер останавливается")
+
+// Значения Config по умолчанию.
+const (
+	DefaultMutationTimeout     = 5 * time.Second
+	DefaultSubscriptionTimeout = 2 * time.Second
+	DefaultJobTimeout          = 10 * time.Second
+)
+
+// Config сроки этапов остановки, нулевые значения - по умолчанию.
+type Config struct {
+	// MutationTimeout ожидание мутаций, начатых до остановки.
+	MutationTimeout time.Duration
+	// SubscriptionTimeout ожидание закрытия websocket соединений после отправки close.
+	SubscriptionTimeout time.Duration
+	// JobTimeout ожидание фоновых задач после отмены их контекста.
+	JobTimeout time.Duration
+}
+
+type closer struct {
+	name string
+	fn   func() error
+}
+
+type connKey struct{}
+
+// Manager порядок остановки процесса: новые подписки и мутации отклоняются,
+// начатые мутации дожидаются, websocket соединения закрываются с close frame,
+// фоновые задачи отменяются и дожидаются, затем ресурсы закрываются
+// в обратном порядке регистрации (рассылка раньше хранилища).
+type Manager struct {
+	cfg    Config
+	logger logger.Logger
+
+	mu        sync.Mutex
+	draining  bool
+	mutations sync.WaitGroup
+	conns     map[uint64]context.CancelFunc
+	nextConn  uint64
+	connsDone chan struct{}
+	closers   []closer
+
+	jobs       sync.WaitGroup
+	jobsCtx    context.Context
+	cancelJobs context.CancelFunc
+
+	once sync.Once
+	err  error
+}
+
+func New(cfg Config, logger logger.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		cfg:        cfg,
+		logger:     logger,
+		conns:      make(map[uint64]context.CancelFunc),
+		jobsCtx:    ctx,
+		cancelJobs: cancel,
+	}
+}
+
+// Go запускает фоновую задачу, ctx отменяется при остановке.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	m.jobs.Add(1)
+	go func() {
+		defer m.jobs.Done()
+		fn(m.jobsCtx)
+		m.logger.Debugf("фоновая задача %s завершена", name)
+	}()
+}
+
+// OnClose ресурс, закрываемый в конце остановки. Закрываются в обратном порядке.
+func (m *Manager) OnClose(name string, fn func() error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closers = append(m.closers, closer{name: name, fn: fn})
+}
+
+// Connect регистрирует websocket соединение: при остановке его контекст
+// отменяется, и gqlgen закрывает соединение. Парный вызов - Disconnect.
+func (m *Manager) Connect(ctx context.Context) (context.Context, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.draining {
+		return ctx, ErrShuttingDown
+	}
+	m.nextConn++
+	id := m.nextConn
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, connKey{}, id))
+	m.conns[id] = cancel
+	return ctx, nil
+}
+
+// Disconnect соединение закрыто, ctx - из Connect.
+func (m *Manager) Disconnect(ctx context.Context) {
+	id, ok := ctx.Value(connKey{}).(uint64)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cancel, ok := m.conns[id]
+	if !ok {
+		return
+	}
+	cancel()
+	delete(m.conns, id)
+	if len(m.conns) == 0 && m.connsDone != nil {
+		close(m.connsDone)
+		m.connsDone = nil
+	}
+}
+
+// Subscribe ErrShuttingDown, если новые подписки уже не принимаются.
+func (m *Manager) Subscribe() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.draining {
+		return ErrShuttingDown
+	}
+	return nil
+}
+
+// BeginMutation учитывает мутацию до вызова done, после начала остановки - ErrShuttingDown.
+func (m *Manager) BeginMutation() (done func(), err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.draining {
+		return nil, ErrShuttingDown
+	}
+	m.mutations.Add(1)
+	var once sync.Once
+	return func() { once.Do(m.mutations.Done) }, nil
+}
+
+// Drain перестает принимать подписки, мутации и websocket соединения.
+// Shutdown вызывает его сам, отдельно нужен, чтобы отклонять новые операции,
+// пока HTTP сервер дожидается запросов.
+func (m *Manager) Drain() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.draining = true
+}
+
+// Draining началась ли остановка.
+func (m *Manager) Draining() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.draining
+}
+
+// Shutdown останавливает все по этапам, повторный вызов возвращает тот же результат.
+// Просроченный этап не прерывает остановку: ошибка возвращается, следующие этапы выполняются.
+func (m *Manager) Shutdown() error {
+	m.once.Do(func() {
+		m.Drain()
+		var errs []error
+		if !wait(m.mutations.Wait, orDefault(m.cfg.MutationTimeout, DefaultMutationTimeout)) {
+			errs = append(errs, errors.New("мутации не завершились за отведенное время"))
+		}
+		if !m.closeConns(orDefault(m.cfg.SubscriptionTimeout, DefaultSubscriptionTimeout)) {
+			errs = append(errs, errors.New("websocket соединения не закрылись за отведенное время"))
+		}
+		m.cancelJobs()
+		if !wait(m.jobs.Wait, orDefault(m.cfg.JobTimeout, DefaultJobTimeout)) {
+			errs = append(errs, errors.New("фоновые задачи не завершились за отведенное время"))
+		}
+
+		m.mu.Lock()
+		closers := m.closers
+		m.closers = nil
+		m.mu.Unlock()
+		for i := len(closers) - 1; i >= 0; i-- {
+			if err := closers[i].fn(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", closers[i].name, err))
+			}
+		}
+		m.err = errors.Join(errs...)
+	})
+	return m.err
+}
+
+// closeConns отменяет контексты соединений и ждет Disconnect от каждого.
+func (m *Manager) closeConns(timeout time.Duration) bool {
+	m.mu.Lock()
+	if len(m.conns) == 0 {
+		m.mu.Unlock()
+		return true
+	}
+	done := make(chan struct{})
+	m.connsDone = done
+	for _, cancel := range m.conns {
+		cancel()
+	}
+	n := len(m.conns)
+	m.mu.Unlock()
+
+	m.logger.Infof("закрываем websocket соединения: %d", n)
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// wait ждет fn не дольше timeout.
+func wait(fn func(), timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func orDefault(v, def time.Duration) time.Duration {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,108 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+)
+
+// Тест на порядок остановки: мутации, соединения, фоновые задачи, ресурсы в обратном порядке.
+func TestManager_Shutdown(t *testing.T) {
+	m := New(Config{}, logger.Nop())
+
+	var (
+		mu    sync.Mutex
+		steps []string
+	)
+	step := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, s)
+	}
+
+	m.OnClose("storage", func() error { step("storage"); return nil })
+	m.OnClose("notifier", func() error { step("notifier"); return nil })
+	m.Go("job", func(ctx context.Context) {
+		<-ctx.Done()
+		step("job")
+	})
+
+	connCtx, err := m.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("соединение: %v", err)
+	}
+	// Соединение закрывается по отмене контекста, как websocket в gqlgen.
+	go func() {
+		<-connCtx.Done()
+		step("conn")
+		m.Disconnect(connCtx)
+	}()
+
+	done, err := m.BeginMutation()
+	if err != nil {
+		t.Fatalf("мутация: %v", err)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		step("mutation")
+		done()
+	}()
+
+	if err := m.Shutdown(); err != nil {
+		t.Fatalf("остановка: %v", err)
+	}
+	want := "mutation conn job notifier storage"
+	if got := strings.Join(steps, " "); got != want {
+		t.Fatalf("ожидался порядок %q, а получили %q", want, got)
+	}
+}
+
+// Тест на отказ в новых операциях после начала остановки.
+func TestManager_Drain(t *testing.T) {
+	m := New(Config{}, logger.Nop())
+	m.Drain()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "Соединение", call: func() error { _, err := m.Connect(context.Background()); return err }},
+		{name: "Подписка", call: m.Subscribe},
+		{name: "Мутация", call: func() error { _, err := m.BeginMutation(); return err }},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.call(); !errors.Is(err, ErrShuttingDown) {
+				t.Fatalf("ожидалась ошибка %v, а получили %v", ErrShuttingDown, err)
+			}
+		})
+	}
+}
+
+// Тест на сроки: зависшая мутация не блокирует остановку, ресурсы все равно закрываются.
+func TestManager_ShutdownTimeout(t *testing.T) {
+	m := New(Config{MutationTimeout: 10 * time.Millisecond, JobTimeout: 10 * time.Millisecond}, logger.Nop())
+	closed := false
+	m.OnClose("storage", func() error { closed = true; return nil })
+	if _, err := m.BeginMutation(); err != nil {
+		t.Fatalf("мутация: %v", err)
+	}
+	m.Go("stuck", func(context.Context) { select {} })
+
+	err := m.Shutdown()
+	if err == nil || !strings.Contains(err.Error(), "мутации") || !strings.Contains(err.Error(), "фоновые задачи") {
+		t.Fatalf("ожидались ошибки сроков, а получили %v", err)
+	}
+	if !closed {
+		t.Fatal("хранилище должно закрыться и после просроченных этапов")
+	}
+	if again := m.Shutdown(); again != err {
+		t.Fatalf("повторная остановка: ожидалось %v, а получили %v", err, again)
+	}
+}
@@ -1,7 +1,51 @@
 package logger
 
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/RoGogDBD/GQLGo/internal/reqctx"
+)
+
+// Logger логгер с уровнями и полями ключ-значение.
 type Logger interface {
+	Debugf(format string, args ...any)
 	Infof(format string, args ...any)
-	//	Warnf(format string, args ...any)
+	Warnf(format string, args ...any)
 	Errorf(format string, args ...any)
+	// With логгер с полями: With("post_id", id).Errorf(...).
+	With(kv ...any) Logger
+	// Ctx логгер с полями запроса из ctx: request_id, operation, trace_id.
+	Ctx(ctx context.Context) Logger
+}
+
+// ContextFields поля запроса из ctx для Ctx, пусто вне запроса.
+func ContextFields(ctx context.Context) []any {
+	var kv []any
+	info := reqctx.From(ctx)
+	if info.RequestID != "" {
+		kv = append(kv, "request_id", info.RequestID)
+	}
+	if info.Operation != "" {
+		kv = append(kv, "operation", info.Operation)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		kv = append(kv, "trace_id", sc.TraceID().String())
+	}
+	return kv
 }
+
+// Nop логгер, который ничего не пишет.
+func Nop() Logger {
+	return nop{}
+}
+
+type nop struct{}
+
+func (nop) Debugf(string, ...any)        {}
+func (nop) Infof(string, ...any)         {}
+func (nop) Warnf(string, ...any)         {}
+func (nop) Errorf(string, ...any)        {}
+func (n nop) With(...any) Logger         { return n }
+func (n nop) Ctx(context.Context) Logger { return n }
@@ -0,0 +1,59 @@
+package markup
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+var (
+	md = goldmark.New(goldmark.WithExtensions(extension.Strikethrough))
+
+	// policy строгий allow-list: ссылки, выделение, код, цитаты и списки.
+	policy = newPolicy()
+	strict = bluemonday.StrictPolicy()
+
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "em", "strong", "del", "code", "pre", "blockquote", "ul", "ol", "li")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w-]+$`)).OnElements("code")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	return p
+}
+
+// Render markdown в безопасный HTML.
+func Render(src string) string {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(src), &buf); err != nil {
+		return Escape(src)
+	}
+	return strings.TrimSpace(policy.Sanitize(buf.String()))
+}
+
+// Sanitize чистит готовый HTML по allow-list.
+func Sanitize(s string) string {
+	return policy.Sanitize(s)
+}
+
+// Plain текст без разметки из отрендеренного HTML.
+func Plain(renderedHTML string) string {
+	s := strings.NewReplacer("</p>", "\n\n", "<br>", "\n", "</li>", "\n", "</blockquote>", "\n\n", "</pre>", "\n\n").Replace(renderedHTML)
+	s = html.UnescapeString(strict.Sanitize(s))
+	return strings.TrimSpace(blankLines.ReplaceAllString(s, "\n\n"))
+}
+
+// Escape экранирует текст для вставки в HTML.
+func Escape(src string) string {
+	return "<p>" + html.EscapeString(src) + "</p>"
+}
@@ -0,0 +1,84 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+)
+
+// Тест на рендер разрешенного подмножества markdown.
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		contains []string
+	}{
+		{name: "Выделение", src: "*a* **b** ~~c~~", contains: []string{"<em>a</em>", "<strong>b</strong>", "<del>c</del>"}},
+		{name: "Код", src: "`x` и\n\n```go\nfmt.Println()\n```", contains: []string{"<code>x</code>", `<code class="language-go">`}},
+		{name: "Цитата", src: "> цитата", contains: []string{"<blockquote>", "цитата"}},
+		{name: "Списки", src: "- a\n- b\n\n1. c", contains: []string{"<ul>", "<li>a</li>", "<ol>"}},
+		{name: "Ссылка", src: "[site](https://example.com)", contains: []string{`<a href="https://example.com" rel="nofollow">site</a>`}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			out := Render(tc.src)
+			for _, want := range tc.contains {
+				if !strings.Contains(out, want) {
+					t.Fatalf("ожидалось %q в %q", want, out)
+				}
+			}
+		})
+	}
+}
+
+// Тест на нейтрализацию XSS.
+func TestRender_XSS(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		forbidden []string
+	}{
+		{name: "script", src: "<script>alert(1)</script>", forbidden: []string{"<script", "alert(1)</script>"}},
+		{name: "onerror", src: `<img src=x onerror="alert(1)">`, forbidden: []string{"<img", "onerror"}},
+		{name: "javascript ссылка", src: "[click](javascript:alert(1))", forbidden: []string{"javascript:"}},
+		{name: "data ссылка", src: "[x](data:text/html;base64,PHNjcmlwdD4=)", forbidden: []string{"data:"}},
+		{name: "iframe", src: `<iframe src="https://evil"></iframe>`, forbidden: []string{"<iframe"}},
+		{name: "style", src: `<p style="background:url(javascript:alert(1))">x</p>`, forbidden: []string{"style=", "javascript:"}},
+		{name: "Атрибут в ссылке", src: `[x](https://a.b "t\" onmouseover=\"alert(1))`, forbidden: []string{`onmouseover="`}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			out := Render(tc.src)
+			for _, bad := range tc.forbidden {
+				if strings.Contains(strings.ToLower(out), strings.ToLower(bad)) {
+					t.Fatalf("не ожидалось %q в %q", bad, out)
+				}
+			}
+		})
+	}
+}
+
+// Тест на текст без разметки.
+func TestPlain(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "Выделение", src: "**a** & *b*", want: "a & b"},
+		{name: "Абзацы", src: "a\n\nb", want: "a\n\nb"},
+		{name: "HTML как текст", src: "<b>x</b>", want: "x"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Plain(Render(tc.src)); got != tc.want {
+				t.Fatalf("ожидалось %q, а получили %q", tc.want, got)
+			}
+		})
+	}
+}
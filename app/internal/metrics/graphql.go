@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = (*Metrics)(nil)
+
+const (
+	// codeUnknown ошибки без extensions.code.
+	codeUnknown = "UNKNOWN"
+	// operationOther операции с именем не из списка известных.
+	operationOther = "other"
+)
+
+func (m *Metrics) ExtensionName() string {
+	return "Metrics"
+}
+
+func (m *Metrics) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse длительность операции и коды ошибок ответа.
+// У подписки ответ на каждое событие, поэтому для нее считаются только ошибки.
+func (m *Metrics) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if resp == nil {
+		return nil
+	}
+	for _, err := range resp.Errors {
+		code, _ := err.Extensions["code"].(string)
+		if code == "" {
+			code = codeUnknown
+		}
+		m.errors.WithLabelValues(code).Inc()
+	}
+
+	if !graphql.HasOperationContext(ctx) {
+		return resp
+	}
+	opCtx := graphql.GetOperationContext(ctx)
+	if opCtx.Operation == nil || opCtx.Operation.Operation == ast.Subscription {
+		return resp
+	}
+	m.operations.WithLabelValues(m.operationName(opCtx.Operation), string(opCtx.Operation.Operation)).
+		Observe(time.Since(opCtx.Stats.OperationStart).Seconds())
+	return resp
+}
+
+// InterceptField длительность резолверов, медленные считаются отдельно.
+func (m *Metrics) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+	start := time.Now()
+	res, err := next(ctx)
+	d := time.Since(start)
+
+	m.resolvers.WithLabelValues(fc.Object, fc.Field.Name).Observe(d.Seconds())
+	if d >= m.slowField {
+		m.slowFields.WithLabelValues(fc.Object, fc.Field.Name).Inc()
+	}
+	return res, err
+}
+
+// operationName метка операции: имя из разобранного документа, если оно известно.
+func (m *Metrics) operationName(op *ast.OperationDefinition) string {
+	if op.Name == "" {
+		return "anonymous"
+	}
+	if _, ok := m.known[op.Name]; ok {
+		return op.Name
+	}
+	return operationOther
+}
@@ -0,0 +1,132 @@
+// Package metrics метрики Prometheus: операции GraphQL, резолверы, ошибки, пул БД и подписки.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gqlgo"
+
+// DefaultSlowField с какой длительности резолвер считается медленным.
+const DefaultSlowField = 100 * time.Millisecond
+
+// Metrics реестр метрик сервиса и расширение gqlgen, которое их пишет.
+type Metrics struct {
+	reg       *prometheus.Registry
+	slowField time.Duration
+	// known имена операций, которые попадают в метку operation как есть.
+	known map[string]struct{}
+
+	operations *prometheus.HistogramVec
+	resolvers  *prometheus.HistogramVec
+	slowFields *prometheus.CounterVec
+	errors     *prometheus.CounterVec
+}
+
+// New метрики в отдельном реестре вместе с метриками Go и процесса.
+// slowField 0 - по умолчанию.
+func New(slowField time.Duration) *Metrics {
+	if slowField <= 0 {
+		slowField = DefaultSlowField
+	}
+	m := &Metrics{
+		reg:       prometheus.NewRegistry(),
+		slowField: slowField,
+		known:     map[string]struct{}{},
+		operations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "operation_duration_seconds",
+			Help:      "Длительность операций query и mutation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "type"}),
+		resolvers: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "resolver_duration_seconds",
+			Help:      "Длительность резолверов полей, простые поля структур не учитываются.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"object", "field"}),
+		slowFields: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "slow_resolvers_total",
+			Help:      "Резолверы дольше порога медленного поля.",
+		}, []string{"object", "field"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "graphql",
+			Name:      "errors_total",
+			Help:      "Ошибки в ответах по extensions.code.",
+		}, []string{"code"}),
+	}
+	m.reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.operations, m.resolvers, m.slowFields, m.errors,
+	)
+	return m
+}
+
+// Handler ответ /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.reg, promhttp.HandlerOpts{Registry: m.reg})
+}
+
+// AddOperations известные имена операций. Остальные имена пишутся как other:
+// имя приходит от клиента, и без списка число серий не ограничено.
+// Вызывается до начала обработки запросов.
+func (m *Metrics) AddOperations(names ...string) {
+	for _, name := range names {
+		if name != "" {
+			m.known[name] = struct{}{}
+		}
+	}
+}
+
+// AddDB статистика пула sql.DB, name различает основную базу и реплику.
+func (m *Metrics) AddDB(name string, db *sql.DB) error {
+	return m.reg.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// SubscriptionSource подписки рассылки: подписчики по топику и потерянные события.
+type SubscriptionSource interface {
+	Subscribers() map[string]int
+	Dropped() uint64
+}
+
+// AddSubscriptions активные подписки по постам и потерянные события рассылки комментариев.
+func (m *Metrics) AddSubscriptions(src SubscriptionSource) error {
+	return m.reg.Register(&subscriptionCollector{
+		src: src,
+		active: prometheus.NewDesc(prometheus.BuildFQName(namespace, "subscriptions", "active"),
+			"Активные подписки на комментарии по постам.", []string{"post_id"}, nil),
+		dropped: prometheus.NewDesc(prometheus.BuildFQName(namespace, "subscriptions", "dropped_events_total"),
+			"События, не доставленные подписчику: буфер полон или подписка закрыта.", nil, nil),
+	})
+}
+
+// subscriptionCollector снимает состояние рассылки в момент сбора.
+type subscriptionCollector struct {
+	src     SubscriptionSource
+	active  *prometheus.Desc
+	dropped *prometheus.Desc
+}
+
+func (c *subscriptionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.active
+	ch <- c.dropped
+}
+
+func (c *subscriptionCollector) Collect(ch chan<- prometheus.Metric) {
+	for postID, n := range c.src.Subscribers() {
+		ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, float64(n), postID)
+	}
+	ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(c.src.Dropped()))
+}
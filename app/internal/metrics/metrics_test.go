@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Тест на метрики ответа: длительность операции по имени и типу, ошибки по коду.
+func TestMetrics_InterceptResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation *ast.OperationDefinition
+		// clientName operationName из запроса клиента.
+		clientName string
+		errs       gqlerror.List
+		wantOps    int
+		wantLabel  string
+		wantCodes  map[string]float64
+	}{
+		{
+			name:      "Известная операция",
+			operation: &ast.OperationDefinition{Operation: ast.Query, Name: "Feed"},
+			wantOps:   1,
+			wantLabel: "Feed",
+		},
+		{
+			name:       "Неизвестное имя",
+			operation:  &ast.OperationDefinition{Operation: ast.Query, Name: "Random123"},
+			clientName: "Random123",
+			wantOps:    1,
+			wantLabel:  operationOther,
+		},
+		{
+			name:       "Имя клиента не из документа",
+			operation:  &ast.OperationDefinition{Operation: ast.Query},
+			clientName: "Feed",
+			wantOps:    1,
+			wantLabel:  "anonymous",
+		},
+		{
+			name:      "Mutation с ошибками",
+			operation: &ast.OperationDefinition{Operation: ast.Mutation},
+			errs: gqlerror.List{
+				{Message: "нет", Extensions: map[string]any{"code": "NOT_FOUND"}},
+				{Message: "без кода"},
+			},
+			wantOps:   1,
+			wantCodes: map[string]float64{"NOT_FOUND": 1, codeUnknown: 1},
+		},
+		{
+			name:      "Подписка без длительности",
+			operation: &ast.OperationDefinition{Operation: ast.Subscription, Name: "Comments"},
+			errs:      gqlerror.List{{Message: "нет", Extensions: map[string]any{"code": "BANNED"}}},
+			wantCodes: map[string]float64{"BANNED": 1},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m := New(0)
+			m.AddOperations("Feed")
+			ctx := graphql.WithOperationContext(context.Background(), &graphql.OperationContext{
+				Operation:     tc.operation,
+				OperationName: tc.clientName,
+				Stats:         graphql.Stats{OperationStart: time.Now()},
+			})
+			m.InterceptResponse(ctx, func(context.Context) *graphql.Response {
+				return &graphql.Response{Errors: tc.errs}
+			})
+
+			if n := testutil.CollectAndCount(m.operations); n != tc.wantOps {
+				t.Fatalf("ожидалось %d серий длительности, а получили %d", tc.wantOps, n)
+			}
+			if tc.wantLabel != "" {
+				// Серия с другой меткой добавится второй.
+				_, err := m.operations.GetMetricWithLabelValues(tc.wantLabel, string(tc.operation.Operation))
+				if err != nil || testutil.CollectAndCount(m.operations) != 1 {
+					t.Fatalf("ожидалась метка operation=%s: %v", tc.wantLabel, err)
+				}
+			}
+			for code, want := range tc.wantCodes {
+				if got := testutil.ToFloat64(m.errors.WithLabelValues(code)); got != want {
+					t.Fatalf("ошибки %s: ожидалось %v, а получили %v", code, want, got)
+				}
+			}
+		})
+	}
+}
+
+type subscriptionsStub struct{}
+
+func (subscriptionsStub) Subscribers() map[string]int { return map[string]int{"p1": 2} }
+func (subscriptionsStub) Dropped() uint64             { return 3 }
+
+// Тест на метрики подписок: активные по постам и потерянные события.
+func TestMetrics_Subscriptions(t *testing.T) {
+	m := New(0)
+	if err := m.AddSubscriptions(subscriptionsStub{}); err != nil {
+		t.Fatalf("регистрация: %v", err)
+	}
+	want := `
+# HELP gqlgo_subscriptions_active Активные подписки на комментарии по постам.
+# TYPE gqlgo_subscriptions_active gauge
+gqlgo_subscriptions_active{post_id="p1"} 2
+# HELP gqlgo_subscriptions_dropped_events_total События, не доставленные подписчику: буфер полон или подписка закрыта.
+# TYPE gqlgo_subscriptions_dropped_events_total counter
+gqlgo_subscriptions_dropped_events_total 3
+`
+	err := testutil.GatherAndCompare(m.reg, strings.NewReader(want), "gqlgo_subscriptions_active", "gqlgo_subscriptions_dropped_events_total")
+	if err != nil {
+		t.Fatalf("метрики: %v", err)
+	}
+}
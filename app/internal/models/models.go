@@ -12,16 +12,18 @@ type (
 	}
 
 	Comment struct {
-		ID            string             `json:"id"`
-		PostID        string             `json:"postId"`
-		Post          *Post              `json:"post"`
-		Author        *User              `json:"author"`
-		Body          string             `json:"body"`
-		ParentID      *string            `json:"parentId,omitempty"`
-		Depth         int32              `json:"depth"`
-		ChildrenCount int32              `json:"childrenCount"`
-		Children      *CommentConnection `json:"children"`
-		CreatedAt     time.Time          `json:"-"`
+		ID              string             `json:"id"`
+		PostID          string             `json:"postId"`
+		Post            *Post              `json:"post"`
+		Author          *User              `json:"author"`
+		Body            string             `json:"body"`
+		BodyHTML        string             `json:"bodyHtml"`
+		ParentID        *string            `json:"parentId,omitempty"`
+		Depth           int32              `json:"depth"`
+		ChildrenCount   int32              `json:"childrenCount"`
+		ModerationState ModerationState    `json:"moderationState"`
+		Children        *CommentConnection `json:"children"`
+		CreatedAt       time.Time          `json:"-"`
 	}
 
 	CommentConnection struct {
@@ -48,16 +50,25 @@ type (
 		ID              string             `json:"id"`
 		Title           string             `json:"title"`
 		Body            string             `json:"body"`
+		BodyHTML        string             `json:"bodyHtml"`
 		Author          *User              `json:"author"`
 		CommentsEnabled bool               `json:"commentsEnabled"`
+		Status          PostStatus         `json:"status"`
+		ModerationState ModerationState    `json:"moderationState"`
+		PublishAt       *time.Time         `json:"publishAt,omitempty"`
+		PublishedAt     *time.Time         `json:"publishedAt,omitempty"`
 		Comments        *CommentConnection `json:"comments"`
+		CreatedAt       time.Time          `json:"-"`
 	}
 
 	CreatePostInput struct {
-		AuthorID        string `json:"authorId"`
-		Title           string `json:"title"`
-		Body            string `json:"body"`
-		CommentsEnabled *bool  `json:"commentsEnabled,omitempty"`
+		AuthorID        string      `json:"authorId"`
+		Title           string      `json:"title"`
+		Body            string      `json:"body"`
+		CommentsEnabled *bool       `json:"commentsEnabled,omitempty"`
+		Tags            []string    `json:"tags,omitempty"`
+		Status          *PostStatus `json:"status,omitempty"`
+		PublishAt       *time.Time  `json:"publishAt,omitempty"`
 	}
 
 	PostConnection struct {
@@ -72,8 +83,112 @@ type (
 	}
 )
 
-// ============================== USERS ==============================
-type User struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
+// ============================== TAGS ==============================
+type Tag struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Slug       string `json:"slug"`
+	PostsCount int32  `json:"postsCount"`
+}
+
+// ============================== NOTIFICATIONS ==============================
+type (
+	Notification struct {
+		ID        string           `json:"id"`
+		Kind      NotificationKind `json:"kind"`
+		UserID    string           `json:"-"`
+		Actor     *User            `json:"actor"`
+		PostID    string           `json:"postId"`
+		CommentID *string          `json:"commentId,omitempty"`
+		ReadAt    *time.Time       `json:"readAt,omitempty"`
+		CreatedAt time.Time        `json:"createdAt"`
+	}
+
+	// Mention упоминание пользователя в посте или комментарии.
+	Mention struct {
+		ID        string
+		UserID    string
+		AuthorID  string
+		PostID    string
+		CommentID *string
+		CreatedAt time.Time
+	}
+)
+
+// Read прочитано ли уведомление.
+func (n *Notification) Read() bool {
+	return n.ReadAt != nil
+}
+
+// ============================== REPORTS ==============================
+type Report struct {
+	ID         string            `json:"id"`
+	TargetType ReportTargetType  `json:"targetType"`
+	TargetID   string            `json:"targetId"`
+	Reporter   *User             `json:"reporter"`
+	Reason     ReportReason      `json:"reason"`
+	Note       *string           `json:"note,omitempty"`
+	Status     ReportStatus      `json:"status"`
+	Action     *ModerationAction `json:"action,omitempty"`
+	ResolvedBy *User             `json:"resolvedBy,omitempty"`
+	ResolvedAt *time.Time        `json:"resolvedAt,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+}
+
+// ============================== BANS ==============================
+
+// Ban блокировка пользователя, при Until == nil бессрочная.
+type Ban struct {
+	ID        string     `json:"id"`
+	User      *User      `json:"user"`
+	Reason    string     `json:"reason"`
+	Until     *time.Time `json:"until,omitempty"`
+	BannedBy  *User      `json:"bannedBy,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	LiftedAt  *time.Time `json:"liftedAt,omitempty"`
+	LiftedBy  *User      `json:"liftedBy,omitempty"`
+}
+
+// ActiveAt действует ли блокировка в момент at.
+func (b *Ban) ActiveAt(at time.Time) bool {
+	return b.LiftedAt == nil && (b.Until == nil || b.Until.After(at))
 }
+
+// ============================== AUDIT ==============================
+type (
+	// AuditEntry запись журнала мутаций, Diff - JSON изменившихся полей цели.
+	AuditEntry struct {
+		ID         string    `json:"id"`
+		Actor      *User     `json:"actor,omitempty"`
+		Action     string    `json:"action"`
+		TargetType *string   `json:"targetType,omitempty"`
+		TargetID   *string   `json:"targetId,omitempty"`
+		Diff       *string   `json:"diff,omitempty"`
+		RequestID  *string   `json:"requestId,omitempty"`
+		IP         *string   `json:"ip,omitempty"`
+		CreatedAt  time.Time `json:"createdAt"`
+	}
+
+	AuditFilter struct {
+		ActorID    *string    `json:"actorId,omitempty"`
+		Action     *string    `json:"action,omitempty"`
+		TargetType *string    `json:"targetType,omitempty"`
+		TargetID   *string    `json:"targetId,omitempty"`
+		Since      *time.Time `json:"since,omitempty"`
+		Until      *time.Time `json:"until,omitempty"`
+	}
+)
+
+// ============================== USERS ==============================
+type (
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Role     Role   `json:"role"`
+	}
+
+	// Viewer текущий пользователь запроса.
+	Viewer struct {
+		ID string `json:"id"`
+	}
+)
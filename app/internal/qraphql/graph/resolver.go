@@ -3,6 +3,8 @@
 package graph
 
 import (
+	"errors"
+
 	"github.com/RoGogDBD/GQLGo/internal/logger"
 	"github.com/RoGogDBD/GQLGo/internal/repository"
 	"github.com/RoGogDBD/GQLGo/internal/service"
@@ -23,7 +25,23 @@ type Resolver struct {
 	UserRepo        repository.UserRepo
 	PostRepo        repository.PostRepo
 	CommentRepo     repository.CommentRepo
+	TagRepo         repository.TagRepo
 	CommentNotifier *service.CommentNotifier
 	Logger          logger.Logger
 	PostService     *service.PostService
+	CommentService  *service.CommentService
+
+	NotificationService *service.NotificationService
+	FollowService       *service.FollowService
+	ReaderService       *service.ReaderService
+	ModerationService   *service.ModerationService
+	AuditService        *service.AuditService
+}
+
+// optional для nullable полей: отсутствующая сущность отдается как null.
+func optional[T any](v *T, err error) (*T, error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return v, err
 }
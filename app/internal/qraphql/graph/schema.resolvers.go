@@ -8,12 +8,25 @@ package graph
 import (
 	"context"
 	"fmt"
-	"strings"
+	"time"
 
+	"github.com/RoGogDBD/GQLGo/internal/auth"
 	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+	"github.com/RoGogDBD/GQLGo/internal/service"
 	"github.com/RoGogDBD/GQLGo/internal/utils/graph"
 )
 
+// Body is the resolver for the body field.
+func (r *commentResolver) Body(ctx context.Context, obj *models.Comment, format *models.BodyFormat) (string, error) {
+	return graph.FormatBody(obj.Body, obj.BodyHTML, format), nil
+}
+
+// IsNew is the resolver for the isNew field.
+func (r *commentResolver) IsNew(ctx context.Context, obj *models.Comment) (bool, error) {
+	return r.ReaderService.IsNew(ctx, obj)
+}
+
 // Children is the resolver for the children field.
 func (r *commentResolver) Children(ctx context.Context, obj *models.Comment, first *int32, after *string, order *models.CommentOrder) (*models.CommentConnection, error) {
 	parentID := obj.ID
@@ -27,52 +40,114 @@ func (r *mutationResolver) CreatePost(ctx context.Context, input models.CreatePo
 
 // SetCommentsEnabled is the resolver for the setCommentsEnabled field.
 func (r *mutationResolver) SetCommentsEnabled(ctx context.Context, postID string, enabled bool) (*models.Post, error) {
-	return r.PostRepo.SetCommentsEnabled(ctx, postID, enabled)
+	post, err := r.PostRepo.SetCommentsEnabled(ctx, postID, enabled)
+	return post, repository.NotFoundAs(err, service.ErrPostNotFound)
+}
+
+// SetPostTags is the resolver for the setPostTags field.
+func (r *mutationResolver) SetPostTags(ctx context.Context, postID string, tags []string) (*models.Post, error) {
+	return r.PostService.SetTags(ctx, postID, tags)
+}
+
+// PublishPost is the resolver for the publishPost field.
+func (r *mutationResolver) PublishPost(ctx context.Context, postID string) (*models.Post, error) {
+	return r.PostService.Publish(ctx, postID)
+}
+
+// ArchivePost is the resolver for the archivePost field.
+func (r *mutationResolver) ArchivePost(ctx context.Context, postID string) (*models.Post, error) {
+	return r.PostService.Archive(ctx, postID)
+}
+
+// MarkNotificationsRead is the resolver for the markNotificationsRead field.
+func (r *mutationResolver) MarkNotificationsRead(ctx context.Context, ids []string) (int32, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return 0, service.ErrUnauthorized
+	}
+	n, err := r.NotificationService.MarkRead(ctx, viewer.UserID, ids)
+	return int32(n), err
+}
+
+// FollowUser is the resolver for the followUser field.
+func (r *mutationResolver) FollowUser(ctx context.Context, userID string) (*models.User, error) {
+	return r.FollowService.Follow(ctx, userID)
+}
+
+// UnfollowUser is the resolver for the unfollowUser field.
+func (r *mutationResolver) UnfollowUser(ctx context.Context, userID string) (*models.User, error) {
+	return r.FollowService.Unfollow(ctx, userID)
+}
+
+// BookmarkPost is the resolver for the bookmarkPost field.
+func (r *mutationResolver) BookmarkPost(ctx context.Context, postID string) (*models.Post, error) {
+	return r.ReaderService.Bookmark(ctx, postID)
+}
+
+// RemoveBookmark is the resolver for the removeBookmark field.
+func (r *mutationResolver) RemoveBookmark(ctx context.Context, postID string) (*models.Post, error) {
+	return r.ReaderService.RemoveBookmark(ctx, postID)
+}
+
+// MarkPostRead is the resolver for the markPostRead field.
+func (r *mutationResolver) MarkPostRead(ctx context.Context, postID string) (*models.Post, error) {
+	return r.ReaderService.MarkPostRead(ctx, postID)
+}
+
+// ReportContent is the resolver for the reportContent field.
+func (r *mutationResolver) ReportContent(ctx context.Context, targetID string, reason models.ReportReason, note *string) (*models.Report, error) {
+	return r.ModerationService.Report(ctx, targetID, reason, note)
+}
+
+// ResolveReport is the resolver for the resolveReport field.
+func (r *mutationResolver) ResolveReport(ctx context.Context, id string, action models.ModerationAction) (*models.Report, error) {
+	return r.ModerationService.Resolve(ctx, id, action)
+}
+
+// BanUser is the resolver for the banUser field.
+func (r *mutationResolver) BanUser(ctx context.Context, userID string, until *time.Time, reason string, hideContent *bool) (*models.Ban, error) {
+	return r.ModerationService.Ban(ctx, userID, until, reason, hideContent != nil && *hideContent)
+}
+
+// UnbanUser is the resolver for the unbanUser field.
+func (r *mutationResolver) UnbanUser(ctx context.Context, userID string) (*models.User, error) {
+	return r.ModerationService.Unban(ctx, userID)
 }
 
 // AddComment is the resolver for the addComment field.
 func (r *mutationResolver) AddComment(ctx context.Context, input models.AddCommentInput) (*models.Comment, error) {
-	if input.PostID == "" {
-		return nil, fmt.Errorf("требуется id поста")
-	}
-	if input.AuthorID == "" {
-		return nil, fmt.Errorf("требуется id автора")
-	}
+	return r.CommentService.Add(ctx, input)
+}
 
-	body := strings.TrimSpace(input.Body)
-	if body == "" {
-		return nil, fmt.Errorf("требуется тело коммента")
-	}
-	if len(body) > 2000 {
-		return nil, fmt.Errorf("тело комментария длинное (<= 2000)")
-	}
+// Post is the resolver for the post field.
+func (r *notificationResolver) Post(ctx context.Context, obj *models.Notification) (*models.Post, error) {
+	return optional(r.PostService.Get(ctx, obj.PostID))
+}
 
-	post, err := r.PostRepo.GetByID(ctx, input.PostID)
-	if err != nil {
-		return nil, err
-	}
-	if post == nil {
-		return nil, fmt.Errorf("пост не найден")
-	}
-	if !post.CommentsEnabled {
-		return nil, fmt.Errorf("комментарии отключены")
-	}
+// Body is the resolver for the body field.
+func (r *postResolver) Body(ctx context.Context, obj *models.Post, format *models.BodyFormat) (string, error) {
+	return graph.FormatBody(obj.Body, obj.BodyHTML, format), nil
+}
 
-	depth, err := graph.ResolveCommentDepth(ctx, r.CommentRepo, input.PostID, input.ParentID)
-	if err != nil {
-		return nil, err
-	}
+// Tags is the resolver for the tags field.
+func (r *postResolver) Tags(ctx context.Context, obj *models.Post) ([]*models.Tag, error) {
+	return r.TagRepo.ListByPost(ctx, obj.ID)
+}
 
-	comment, err := r.CommentRepo.Create(ctx, input.PostID, input.AuthorID, input.ParentID, body, depth)
-	if err != nil {
-		return nil, err
-	}
-	if r.CommentNotifier != nil {
-		if err := r.CommentNotifier.Publish(input.PostID, comment); err != nil {
-			r.Logger.Errorf("comment notifier publish: %v", err)
-		}
-	}
-	return comment, nil
+// Bookmarked is the resolver for the bookmarked field.
+func (r *postResolver) Bookmarked(ctx context.Context, obj *models.Post) (bool, error) {
+	return r.ReaderService.Bookmarked(ctx, obj.ID)
+}
+
+// LastReadAt is the resolver for the lastReadAt field.
+func (r *postResolver) LastReadAt(ctx context.Context, obj *models.Post) (*time.Time, error) {
+	return r.ReaderService.LastReadAt(ctx, obj.ID)
+}
+
+// UnreadCommentCount is the resolver for the unreadCommentCount field.
+func (r *postResolver) UnreadCommentCount(ctx context.Context, obj *models.Post) (int32, error) {
+	n, err := r.ReaderService.UnreadCommentCount(ctx, obj.ID)
+	return int32(n), err
 }
 
 // Comments is the resolver for the comments field.
@@ -80,6 +155,33 @@ func (r *postResolver) Comments(ctx context.Context, obj *models.Post, first *in
 	return graph.ResolveCommentConnection(ctx, r.CommentRepo, obj.ID, nil, first, after, order, models.CommentOrderNewest)
 }
 
+// Viewer is the resolver for the viewer field.
+func (r *queryResolver) Viewer(ctx context.Context) (*models.Viewer, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return nil, nil
+	}
+	return &models.Viewer{ID: viewer.UserID}, nil
+}
+
+// Feed is the resolver for the feed field.
+func (r *queryResolver) Feed(ctx context.Context, first *int32, after *string) (*models.PostConnection, error) {
+	f := int32(20)
+	if first != nil {
+		f = *first
+	}
+	// проверка кол элементов.
+	list, _, err := r.FollowService.Feed(ctx, f+1, after)
+	if err != nil {
+		return nil, err
+	}
+	hasNext := int32(len(list)) > f
+	if hasNext {
+		list = list[:f]
+	}
+	return graph.NewPostConnection(list, hasNext), nil
+}
+
 // GetPosts is the resolver for the GetPosts field.
 func (r *queryResolver) GetPosts(ctx context.Context, first *int32, after *string) (*models.PostConnection, error) {
 	f := int32(20)
@@ -87,7 +189,8 @@ func (r *queryResolver) GetPosts(ctx context.Context, first *int32, after *strin
 		f = *first
 	}
 	// проверка кол элементов.
-	list, _, err := r.PostRepo.List(ctx, f+1, after)
+	viewer, _ := auth.ViewerFrom(ctx)
+	list, _, err := r.PostRepo.List(ctx, f+1, after, viewer)
 	if err != nil {
 		return nil, err
 	}
@@ -100,7 +203,7 @@ func (r *queryResolver) GetPosts(ctx context.Context, first *int32, after *strin
 
 // GetPost is the resolver for the GetPost field.
 func (r *queryResolver) GetPost(ctx context.Context, id string) (*models.Post, error) {
-	return r.PostRepo.GetByID(ctx, id)
+	return r.PostService.Get(ctx, id)
 }
 
 // GetUsers is the resolver for the GetUsers field.
@@ -123,7 +226,100 @@ func (r *queryResolver) GetUsers(ctx context.Context, first *int32, after *strin
 
 // GetUser is the resolver for the GetUser field.
 func (r *queryResolver) GetUser(ctx context.Context, id string) (*models.User, error) {
-	return r.UserRepo.GetByID(ctx, id)
+	return optional(r.UserRepo.GetByID(ctx, id))
+}
+
+// Tags is the resolver for the tags field.
+func (r *queryResolver) Tags(ctx context.Context, first *int32, after *string) (*models.TagConnection, error) {
+	f := int32(20)
+	if first != nil {
+		f = *first
+	}
+	// проверка кол элементов.
+	list, _, err := r.TagRepo.List(ctx, f+1, after)
+	if err != nil {
+		return nil, err
+	}
+	hasNext := int32(len(list)) > f
+	if hasNext {
+		list = list[:f]
+	}
+	return graph.NewTagConnection(list, hasNext), nil
+}
+
+// PostsByTag is the resolver for the postsByTag field.
+func (r *queryResolver) PostsByTag(ctx context.Context, tag string, first *int32, after *string) (*models.PostConnection, error) {
+	f := int32(20)
+	if first != nil {
+		f = *first
+	}
+	// проверка кол элементов.
+	viewer, _ := auth.ViewerFrom(ctx)
+	list, _, err := r.TagRepo.ListPosts(ctx, service.TagSlug(tag), f+1, after, viewer)
+	if err != nil {
+		return nil, err
+	}
+	hasNext := int32(len(list)) > f
+	if hasNext {
+		list = list[:f]
+	}
+	return graph.NewPostConnection(list, hasNext), nil
+}
+
+// Reports is the resolver for the reports field.
+func (r *queryResolver) Reports(ctx context.Context, status *models.ReportStatus, first *int32, after *string) (*models.ReportConnection, error) {
+	f := int32(20)
+	if first != nil {
+		f = *first
+	}
+	// проверка кол элементов.
+	list, _, err := r.ModerationService.Reports(ctx, status, f+1, after)
+	if err != nil {
+		return nil, err
+	}
+	hasNext := int32(len(list)) > f
+	if hasNext {
+		list = list[:f]
+	}
+	return graph.NewReportConnection(list, hasNext), nil
+}
+
+// AuditLog is the resolver for the auditLog field.
+func (r *queryResolver) AuditLog(ctx context.Context, filter *models.AuditFilter, first *int32, after *string) (*models.AuditConnection, error) {
+	f := int32(20)
+	if first != nil {
+		f = *first
+	}
+	// проверка кол элементов.
+	list, _, err := r.AuditService.List(ctx, filter, f+1, after)
+	if err != nil {
+		return nil, err
+	}
+	hasNext := int32(len(list)) > f
+	if hasNext {
+		list = list[:f]
+	}
+	return graph.NewAuditConnection(list, hasNext), nil
+}
+
+// Post is the resolver for the post field.
+func (r *reportResolver) Post(ctx context.Context, obj *models.Report) (*models.Post, error) {
+	if obj.TargetType == models.ReportTargetTypePost {
+		return optional(r.PostService.Get(ctx, obj.TargetID))
+	}
+	c, err := optional(r.CommentRepo.GetByID(ctx, obj.TargetID))
+	if err != nil || c == nil {
+		return nil, err
+	}
+	return optional(r.PostService.Get(ctx, c.PostID))
+}
+
+// Comment is the resolver for the comment field.
+func (r *reportResolver) Comment(ctx context.Context, obj *models.Report) (*models.Comment, error) {
+	if obj.TargetType != models.ReportTargetTypeComment {
+		return nil, nil
+	}
+	return optional(r.CommentRepo.GetByID(ctx, obj.TargetID))
 }
 
 // CommentAdded is the resolver for the commentAdded field.
@@ -134,6 +330,10 @@ func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string)
 	if r.CommentNotifier == nil {
 		return nil, fmt.Errorf("subscriptions отключены")
 	}
+	// Черновики и скрытые посты: подписка только для тех, кто видит пост.
+	if _, err := r.PostService.Get(ctx, postID); err != nil {
+		return nil, err
+	}
 
 	ch, unsubscribe, err := r.CommentNotifier.Subscribe(postID)
 	if err != nil {
@@ -148,23 +348,179 @@ func (r *subscriptionResolver) CommentAdded(ctx context.Context, postID string)
 	return ch, nil
 }
 
+// PostPublished is the resolver for the postPublished field.
+func (r *subscriptionResolver) PostPublished(ctx context.Context) (<-chan *models.Post, error) {
+	ch, unsubscribe, err := r.PostService.SubscribePublished()
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		unsubscribe()
+	}()
+
+	return ch, nil
+}
+
+// NotificationAdded is the resolver for the notificationAdded field.
+func (r *subscriptionResolver) NotificationAdded(ctx context.Context) (<-chan *models.Notification, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return nil, service.ErrUnauthorized
+	}
+
+	ch, unsubscribe := r.NotificationService.Subscribe(viewer.UserID)
+	go func() {
+		<-ctx.Done()
+		unsubscribe()
+	}()
+
+	return ch, nil
+}
+
+// Role is the resolver for the role field.
+func (r *userResolver) Role(ctx context.Context, obj *models.User) (models.Role, error) {
+	if obj.Role == "" {
+		return models.RoleUser, nil
+	}
+	return obj.Role, nil
+}
+
+// Ban is the resolver for the ban field.
+func (r *userResolver) Ban(ctx context.Context, obj *models.User) (*models.Ban, error) {
+	return r.ModerationService.ViewBan(ctx, obj.ID)
+}
+
+// FollowersCount is the resolver for the followersCount field.
+func (r *userResolver) FollowersCount(ctx context.Context, obj *models.User) (int32, error) {
+	n, err := r.FollowService.CountFollowers(ctx, obj.ID)
+	return int32(n), err
+}
+
+// FollowingCount is the resolver for the followingCount field.
+func (r *userResolver) FollowingCount(ctx context.Context, obj *models.User) (int32, error) {
+	n, err := r.FollowService.CountFollowing(ctx, obj.ID)
+	return int32(n), err
+}
+
+// Followers is the resolver for the followers field.
+func (r *userResolver) Followers(ctx context.Context, obj *models.User, first *int32, after *string) (*models.UserConnection, error) {
+	f := int32(20)
+	if first != nil {
+		f = *first
+	}
+	// проверка кол элементов.
+	list, _, err := r.FollowService.Followers(ctx, obj.ID, f+1, after)
+	if err != nil {
+		return nil, err
+	}
+	hasNext := int32(len(list)) > f
+	if hasNext {
+		list = list[:f]
+	}
+	return graph.NewUserConnection(list, hasNext), nil
+}
+
+// Following is the resolver for the following field.
+func (r *userResolver) Following(ctx context.Context, obj *models.User, first *int32, after *string) (*models.UserConnection, error) {
+	f := int32(20)
+	if first != nil {
+		f = *first
+	}
+	// проверка кол элементов.
+	list, _, err := r.FollowService.Following(ctx, obj.ID, f+1, after)
+	if err != nil {
+		return nil, err
+	}
+	hasNext := int32(len(list)) > f
+	if hasNext {
+		list = list[:f]
+	}
+	return graph.NewUserConnection(list, hasNext), nil
+}
+
+// User is the resolver for the user field.
+func (r *viewerResolver) User(ctx context.Context, obj *models.Viewer) (*models.User, error) {
+	return optional(r.UserRepo.GetByID(ctx, obj.ID))
+}
+
+// Notifications is the resolver for the notifications field.
+func (r *viewerResolver) Notifications(ctx context.Context, obj *models.Viewer, first *int32, after *string, unreadOnly *bool) (*models.NotificationConnection, error) {
+	f := int32(20)
+	if first != nil {
+		f = *first
+	}
+	unread := unreadOnly != nil && *unreadOnly
+	// проверка кол элементов.
+	list, _, err := r.NotificationService.List(ctx, obj.ID, f+1, after, unread)
+	if err != nil {
+		return nil, err
+	}
+	hasNext := int32(len(list)) > f
+	if hasNext {
+		list = list[:f]
+	}
+	return graph.NewNotificationConnection(list, hasNext), nil
+}
+
+// UnreadNotificationsCount is the resolver for the unreadNotificationsCount field.
+func (r *viewerResolver) UnreadNotificationsCount(ctx context.Context, obj *models.Viewer) (int32, error) {
+	n, err := r.NotificationService.CountUnread(ctx, obj.ID)
+	return int32(n), err
+}
+
+// Bookmarks is the resolver for the bookmarks field.
+func (r *viewerResolver) Bookmarks(ctx context.Context, obj *models.Viewer, first *int32, after *string) (*models.PostConnection, error) {
+	f := int32(20)
+	if first != nil {
+		f = *first
+	}
+	// проверка кол элементов.
+	list, _, err := r.ReaderService.Bookmarks(ctx, obj.ID, f+1, after)
+	if err != nil {
+		return nil, err
+	}
+	hasNext := int32(len(list)) > f
+	if hasNext {
+		list = list[:f]
+	}
+	return graph.NewPostConnection(list, hasNext), nil
+}
+
 // Comment returns CommentResolver implementation.
 func (r *Resolver) Comment() CommentResolver { return &commentResolver{r} }
 
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Notification returns NotificationResolver implementation.
+func (r *Resolver) Notification() NotificationResolver { return &notificationResolver{r} }
+
 // Post returns PostResolver implementation.
 func (r *Resolver) Post() PostResolver { return &postResolver{r} }
 
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Report returns ReportResolver implementation.
+func (r *Resolver) Report() ReportResolver { return &reportResolver{r} }
+
 // Subscription returns SubscriptionResolver implementation.
 func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
 
+// User returns UserResolver implementation.
+func (r *Resolver) User() UserResolver { return &userResolver{r} }
+
+// Viewer returns ViewerResolver implementation.
+func (r *Resolver) Viewer() ViewerResolver { return &viewerResolver{r} }
+
 type commentResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type notificationResolver struct{ *Resolver }
 type postResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type reportResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
+type viewerResolver struct{ *Resolver }
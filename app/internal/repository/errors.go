@@ -0,0 +1,46 @@
+package repository
+
+import "errors"
+
+// Общие ошибки всех хранилищ. Проверять через errors.Is: конкретные
+// ошибки конфликта оборачивают ErrConflict.
+var (
+	// ErrNotFound сущности с таким id нет.
+	ErrNotFound = errors.New("не найдено")
+	// ErrConflict операция противоречит текущему состоянию сущности.
+	ErrConflict = errors.New("конфликт")
+	// ErrInvalidCursor курсор after не указывает на элемент списка.
+	ErrInvalidCursor = errors.New("неверный курсор")
+
+	ErrEmptyID   = errors.New("неверный id")
+	ErrNilEntity = errors.New("пустая сущность")
+
+	ErrAlreadyExist = Conflict("уже существует")
+	// ErrStatusConflict пост уже в другом статусе.
+	ErrStatusConflict = Conflict("статус поста уже изменен")
+	// ErrReportClosed жалоба уже рассмотрена.
+	ErrReportClosed = Conflict("жалоба уже рассмотрена")
+)
+
+// kindError ошибка со своим текстом, относящаяся к общей ошибке kind.
+type kindError struct {
+	msg  string
+	kind error
+}
+
+func (e *kindError) Error() string { return e.msg }
+func (e *kindError) Unwrap() error { return e.kind }
+
+// NotFound ошибка ErrNotFound с текстом msg.
+func NotFound(msg string) error { return &kindError{msg: msg, kind: ErrNotFound} }
+
+// Conflict ошибка ErrConflict с текстом msg.
+func Conflict(msg string) error { return &kindError{msg: msg, kind: ErrConflict} }
+
+// NotFoundAs заменяет ErrNotFound на target с понятным текстом, остальные ошибки не меняет.
+func NotFoundAs(err, target error) error {
+	if errors.Is(err, ErrNotFound) {
+		return target
+	}
+	return err
+}
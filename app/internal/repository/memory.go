@@ -2,24 +2,18 @@ package repository
 
 import (
 	"context"
-	"database/sql"
-	"errors"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/RoGogDBD/GQLGo/internal/auth"
 	"github.com/RoGogDBD/GQLGo/internal/models"
 	"github.com/RoGogDBD/GQLGo/internal/utils/repository"
 	"github.com/google/uuid"
 )
 
-var (
-	ErrEmptyID = errors.New("неверный id")
-	// ErrNotFound     = errors.New("не найдено")
-	ErrAlreadyExist = errors.New("уже существует")
-	ErrNilEntity    = errors.New("пустая сущность")
-	// ErrBadCursor    = errors.New("неверный курсор")
-)
-
 type MemoryStorage struct {
 	// mu sync.Mutex
 	mu             sync.RWMutex
@@ -32,21 +26,81 @@ type MemoryStorage struct {
 	commentCreated map[string]time.Time
 	byPost         map[string][]string
 	byParent       map[string][]string
+	tags           map[string]*models.Tag
+	postTags       map[string][]string
+	tagPosts       map[string][]string
+	notifications  map[string]*models.Notification
+	byRecipient    map[string][]string
+	mentions       map[string]*models.Mention
+	following      map[string][]string
+	followers      map[string][]string
+	bookmarks      map[string][]string
+	postReads      map[string]map[string]time.Time
+	reports        map[string]*models.Report
+	reportOrder    []string
+	bans           map[string][]*models.Ban
 
 	ttl           time.Duration
 	lastPrune     time.Time
 	pruneInterval time.Duration
+
+	// tx транзакция MemoryTransactor, которая держит блокировку.
+	tx *memoryTx
+
+	// persist сохранение на диск, nil - только память.
+	persist  *persister
+	dirty    map[tableKey]struct{}
+	dirtyAll bool
+}
+
+// MemoryOption настройка хранилища в памяти.
+type MemoryOption func(*memoryOptions)
+
+type memoryOptions struct {
+	ttl     time.Duration
+	persist PersistConfig
+}
+
+// WithTTL время жизни данных, 0 - бессрочно.
+func WithTTL(ttl time.Duration) MemoryOption {
+	return func(o *memoryOptions) { o.ttl = ttl }
+}
+
+// WithPersistence сохранение в каталог cfg.Dir: снимок и журнал изменений.
+func WithPersistence(cfg PersistConfig) MemoryOption {
+	return func(o *memoryOptions) { o.persist = cfg }
 }
 
 type (
 	MemoryUserRepo    struct{ st *MemoryStorage }
 	MemoryPostRepo    struct{ st *MemoryStorage }
 	MemoryCommentRepo struct{ st *MemoryStorage }
+	MemoryTagRepo     struct{ st *MemoryStorage }
+
+	MemoryNotificationRepo struct{ st *MemoryStorage }
+	MemoryFollowRepo       struct{ st *MemoryStorage }
+	MemoryBookmarkRepo     struct{ st *MemoryStorage }
+	MemoryReadStateRepo    struct{ st *MemoryStorage }
+	MemoryReportRepo       struct{ st *MemoryStorage }
+	MemoryBanRepo          struct{ st *MemoryStorage }
 )
 
 // ==================== Конструктор ====================
-func NewMemoryStorage() *MemoryStorage {
-	return NewMemoryStorageWithTTL(24 * time.Hour)
+
+// NewMemoryStorage хранилище в памяти, с WithPersistence состояние восстанавливается с диска.
+func NewMemoryStorage(opts ...MemoryOption) (*MemoryStorage, error) {
+	o := memoryOptions{ttl: 24 * time.Hour}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	st := NewMemoryStorageWithTTL(o.ttl)
+	if o.persist.Dir != "" {
+		if err := st.openPersistence(o.persist); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
 }
 
 func NewMemoryStorageWithTTL(ttl time.Duration) *MemoryStorage {
@@ -60,6 +114,19 @@ func NewMemoryStorageWithTTL(ttl time.Duration) *MemoryStorage {
 		commentCreated: map[string]time.Time{},
 		byPost:         map[string][]string{},
 		byParent:       map[string][]string{},
+		tags:           map[string]*models.Tag{},
+		postTags:       map[string][]string{},
+		tagPosts:       map[string][]string{},
+		notifications:  map[string]*models.Notification{},
+		byRecipient:    map[string][]string{},
+		mentions:       map[string]*models.Mention{},
+		following:      map[string][]string{},
+		followers:      map[string][]string{},
+		bookmarks:      map[string][]string{},
+		postReads:      map[string]map[string]time.Time{},
+		reports:        map[string]*models.Report{},
+		reportOrder:    make([]string, 0),
+		bans:           map[string][]*models.Ban{},
 		ttl:            ttl,
 		pruneInterval:  time.Minute,
 	}
@@ -69,6 +136,50 @@ func NewMemoryStorageWithTTL(ttl time.Duration) *MemoryStorage {
 func NewMemoryPostRepo(st *MemoryStorage) *MemoryPostRepo       { return &MemoryPostRepo{st: st} }
 func NewMemoryUserRepo(st *MemoryStorage) *MemoryUserRepo       { return &MemoryUserRepo{st: st} }
 func NewMemoryCommentRepo(st *MemoryStorage) *MemoryCommentRepo { return &MemoryCommentRepo{st: st} }
+func NewMemoryTagRepo(st *MemoryStorage) *MemoryTagRepo         { return &MemoryTagRepo{st: st} }
+func NewMemoryNotificationRepo(st *MemoryStorage) *MemoryNotificationRepo {
+	return &MemoryNotificationRepo{st: st}
+}
+func NewMemoryFollowRepo(st *MemoryStorage) *MemoryFollowRepo     { return &MemoryFollowRepo{st: st} }
+func NewMemoryBookmarkRepo(st *MemoryStorage) *MemoryBookmarkRepo { return &MemoryBookmarkRepo{st: st} }
+func NewMemoryReadStateRepo(st *MemoryStorage) *MemoryReadStateRepo {
+	return &MemoryReadStateRepo{st: st}
+}
+func NewMemoryReportRepo(st *MemoryStorage) *MemoryReportRepo { return &MemoryReportRepo{st: st} }
+func NewMemoryBanRepo(st *MemoryStorage) *MemoryBanRepo       { return &MemoryBanRepo{st: st} }
+
+// SeedUsers добавляет постоянных пользователей, TTL на них не действует.
+func (st *MemoryStorage) SeedUsers(users ...*models.User) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, u := range users {
+		if u != nil && u.ID != "" {
+			st.users[u.ID] = repository.CloneUser(u)
+		}
+	}
+}
+
+// ==================== Блокировки ====================
+
+// lock блокировка записи. Внутри MemoryTransactor ее уже держит транзакция.
+func (st *MemoryStorage) lock(ctx context.Context) {
+	if !st.inTx(ctx) {
+		st.mu.Lock()
+	}
+}
+
+// rlock блокировка чтения, внутри транзакции не нужна.
+func (st *MemoryStorage) rlock(ctx context.Context) {
+	if !st.inTx(ctx) {
+		st.mu.RLock()
+	}
+}
+
+func (st *MemoryStorage) runlock(ctx context.Context) {
+	if !st.inTx(ctx) {
+		st.mu.RUnlock()
+	}
+}
 
 // ======================== POST REPO ========================
 func (r *MemoryPostRepo) GetByID(ctx context.Context, id string) (*models.Post, error) {
@@ -80,54 +191,74 @@ func (r *MemoryPostRepo) GetByID(ctx context.Context, id string) (*models.Post,
 	}
 
 	now := time.Now().UTC()
-	r.st.mu.Lock()
+	r.st.lock(ctx)
 	r.st.maybePrune(now)
-	r.st.mu.Unlock()
+	r.st.unlock(ctx, nil)
 
-	r.st.mu.RLock()
-	defer r.st.mu.RUnlock()
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
 
 	p := r.st.posts[id]
 	if p == nil {
-		return nil, nil
+		return nil, ErrNotFound
 	}
 
 	return repository.ClonePost(p), nil
 }
 
-func (r *MemoryPostRepo) Create(ctx context.Context, in models.CreatePostInput) (*models.Post, error) {
+// GetForUpdate пост по id под блокировкой записи. MemoryTransactor держит ее
+// до конца транзакции, поэтому пост никто не изменит.
+func (r *MemoryPostRepo) GetForUpdate(ctx context.Context, id string) (*models.Post, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	commentsEnabled := true
-	if in.CommentsEnabled != nil {
-		commentsEnabled = *in.CommentsEnabled
+	if id == "" {
+		return nil, ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, nil)
+
+	p := r.st.posts[id]
+	if p == nil {
+		return nil, ErrNotFound
 	}
+	return repository.ClonePost(p), nil
+}
 
-	p := &models.Post{
-		ID:              uuid.NewString(),
-		Title:           in.Title,
-		Body:            in.Body,
-		CommentsEnabled: commentsEnabled,
-		Author:          &models.User{ID: in.AuthorID},
+func (r *MemoryPostRepo) Create(ctx context.Context, p *models.Post) (_ *models.Post, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 	if p == nil {
 		return nil, ErrNilEntity
 	}
-	if p.ID == "" {
+	if p.Author == nil || p.Author.ID == "" {
 		return nil, ErrEmptyID
 	}
 
 	now := time.Now().UTC()
-	r.st.mu.Lock()
-	defer r.st.mu.Unlock()
+	cp := repository.ClonePost(p)
+	cp.ID = uuid.NewString()
+	cp.Author = &models.User{ID: p.Author.ID}
+	cp.CreatedAt = now
+	if cp.Status == "" {
+		cp.Status = models.PostStatusPublished
+	}
+	if cp.ModerationState == "" {
+		cp.ModerationState = models.ModerationStateVisible
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
 	r.st.maybePrune(now)
 
-	if r.st.posts[p.ID] != nil {
+	if r.st.posts[cp.ID] != nil {
 		return nil, ErrAlreadyExist
 	}
 
-	cp := repository.ClonePost(p)
+	r.st.touch(tablePosts, cp.ID)
+	r.st.touch(tablePostCreated, cp.ID)
 	r.st.posts[cp.ID] = cp
 	r.st.postCreated[cp.ID] = now
 	r.st.postOrder = append(r.st.postOrder, cp.ID)
@@ -135,20 +266,23 @@ func (r *MemoryPostRepo) Create(ctx context.Context, in models.CreatePostInput)
 	return repository.ClonePost(cp), nil
 }
 
-func (r *MemoryPostRepo) List(ctx context.Context, first int32, after *string) ([]*models.Post, *string, error) {
+func (r *MemoryPostRepo) List(ctx context.Context, first int32, after *string, viewer auth.Viewer) ([]*models.Post, *string, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, nil, err
 	}
 
 	now := time.Now().UTC()
-	r.st.mu.Lock()
+	r.st.lock(ctx)
 	r.st.maybePrune(now)
-	r.st.mu.Unlock()
+	r.st.unlock(ctx, nil)
 
-	r.st.mu.RLock()
-	defer r.st.mu.RUnlock()
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
 
-	ids := repository.PaginateIDs(r.st.postOrder, after, first)
+	ids, ok := repository.PaginateIDs(r.st.postOrder, after, first, r.st.postVisibleLocked(viewer))
+	if !ok {
+		return nil, nil, ErrInvalidCursor
+	}
 	posts := make([]*models.Post, 0, len(ids))
 	for _, id := range ids {
 		if p := r.st.posts[id]; p != nil {
@@ -158,7 +292,7 @@ func (r *MemoryPostRepo) List(ctx context.Context, first int32, after *string) (
 	return posts, repository.LastID(posts, func(p *models.Post) string { return p.ID }), nil
 }
 
-func (r *MemoryPostRepo) SetCommentsEnabled(ctx context.Context, postID string, enabled bool) (*models.Post, error) {
+func (r *MemoryPostRepo) SetCommentsEnabled(ctx context.Context, postID string, enabled bool) (_ *models.Post, err error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
@@ -167,18 +301,126 @@ func (r *MemoryPostRepo) SetCommentsEnabled(ctx context.Context, postID string,
 	}
 
 	now := time.Now().UTC()
-	r.st.mu.Lock()
-	defer r.st.mu.Unlock()
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
 	r.st.maybePrune(now)
 
 	p := r.st.posts[postID]
 	if p == nil {
-		return nil, nil
+		return nil, ErrNotFound
 	}
+	r.st.touch(tablePosts, postID)
 	p.CommentsEnabled = enabled
 	return repository.ClonePost(p), nil
 }
 
+func (r *MemoryPostRepo) UpdateStatus(ctx context.Context, postID string, from []models.PostStatus, to models.PostStatus, at time.Time) (_ *models.Post, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if postID == "" {
+		return nil, ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+	r.st.maybePrune(time.Now().UTC())
+
+	p := r.st.posts[postID]
+	if p == nil {
+		return nil, ErrNotFound
+	}
+	if !slices.Contains(from, p.Status) {
+		return nil, ErrStatusConflict
+	}
+	r.st.touch(tablePosts, postID)
+	setPostStatus(p, to, at)
+	return repository.ClonePost(p), nil
+}
+
+func (r *MemoryPostRepo) PublishDue(ctx context.Context, now time.Time, limit int) (_ []*models.Post, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+	r.st.maybePrune(time.Now().UTC())
+
+	due := make([]*models.Post, 0)
+	for _, id := range r.st.postOrder {
+		p := r.st.posts[id]
+		if p != nil && p.Status == models.PostStatusScheduled && p.PublishAt != nil && !p.PublishAt.After(now) {
+			due = append(due, p)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].PublishAt.Before(*due[j].PublishAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	out := make([]*models.Post, 0, len(due))
+	for _, p := range due {
+		r.st.touch(tablePosts, p.ID)
+		setPostStatus(p, models.PostStatusPublished, now)
+		out = append(out, repository.ClonePost(p))
+	}
+	return out, nil
+}
+
+func (r *MemoryPostRepo) SetModerationState(ctx context.Context, postID string, state models.ModerationState) (_ *models.Post, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if postID == "" {
+		return nil, ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+
+	p := r.st.posts[postID]
+	if p == nil {
+		return nil, ErrNotFound
+	}
+	r.st.touch(tablePosts, postID)
+	p.ModerationState = state
+	return repository.ClonePost(p), nil
+}
+
+func (r *MemoryPostRepo) HideByAuthor(ctx context.Context, authorID string) (_ int, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if authorID == "" {
+		return 0, ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+
+	n := 0
+	for _, p := range r.st.posts {
+		if p.Author != nil && p.Author.ID == authorID && p.ModerationState != models.ModerationStateHidden {
+			r.st.touch(tablePosts, p.ID)
+			p.ModerationState = models.ModerationStateHidden
+			n++
+		}
+	}
+	return n, nil
+}
+
+func setPostStatus(p *models.Post, status models.PostStatus, at time.Time) {
+	p.Status = status
+	if status == models.PostStatusPublished {
+		at := at.UTC()
+		p.PublishedAt = &at
+	}
+}
+
 // ======================== USER REPO ========================
 func (r *MemoryUserRepo) GetByID(ctx context.Context, id string) (*models.User, error) {
 	if err := ctx.Err(); err != nil {
@@ -189,16 +431,16 @@ func (r *MemoryUserRepo) GetByID(ctx context.Context, id string) (*models.User,
 	}
 
 	now := time.Now().UTC()
-	r.st.mu.Lock()
+	r.st.lock(ctx)
 	r.st.maybePrune(now)
-	r.st.mu.Unlock()
+	r.st.unlock(ctx, nil)
 
-	r.st.mu.RLock()
-	defer r.st.mu.RUnlock()
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
 
 	u := r.st.users[id]
 	if u == nil {
-		return nil, nil
+		return nil, ErrNotFound
 	}
 	return repository.CloneUser(u), nil
 }
@@ -209,14 +451,17 @@ func (r *MemoryUserRepo) List(ctx context.Context, first int32, after *string) (
 	}
 
 	now := time.Now().UTC()
-	r.st.mu.Lock()
+	r.st.lock(ctx)
 	r.st.maybePrune(now)
-	r.st.mu.Unlock()
+	r.st.unlock(ctx, nil)
 
-	r.st.mu.RLock()
-	defer r.st.mu.RUnlock()
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
 
-	ids := repository.PaginateIDs(repository.SortedKeys(r.st.users), after, first)
+	ids, ok := repository.PaginateIDs(repository.SortedKeys(r.st.users), after, first, nil)
+	if !ok {
+		return nil, nil, ErrInvalidCursor
+	}
 	users := make([]*models.User, 0, len(ids))
 	for _, id := range ids {
 		if u := r.st.users[id]; u != nil {
@@ -226,7 +471,49 @@ func (r *MemoryUserRepo) List(ctx context.Context, first int32, after *string) (
 	return users, repository.LastID(users, func(u *models.User) string { return u.ID }), nil
 }
 
+func (r *MemoryUserRepo) GetByUsernames(ctx context.Context, usernames []string) ([]*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	want := make(map[string]struct{}, len(usernames))
+	for _, name := range usernames {
+		want[strings.ToLower(name)] = struct{}{}
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	users := make([]*models.User, 0, len(want))
+	for _, id := range repository.SortedKeys(r.st.users) {
+		u := r.st.users[id]
+		if _, ok := want[strings.ToLower(u.Username)]; ok {
+			users = append(users, repository.CloneUser(u))
+		}
+	}
+	return users, nil
+}
+
 // ======================== COMMENT REPO ========================
+func (r *MemoryCommentRepo) GetByID(ctx context.Context, id string) (*models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, ErrEmptyID
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	c := r.st.comments[id]
+	if c == nil {
+		return nil, ErrNotFound
+	}
+	cp := *c
+	return &cp, nil
+}
+
 func (r *MemoryCommentRepo) GetMeta(ctx context.Context, id string) (string, int, error) {
 	if err := ctx.Err(); err != nil {
 		return "", 0, err
@@ -236,39 +523,49 @@ func (r *MemoryCommentRepo) GetMeta(ctx context.Context, id string) (string, int
 	}
 
 	now := time.Now().UTC()
-	r.st.mu.Lock()
+	r.st.lock(ctx)
 	r.st.maybePrune(now)
-	r.st.mu.Unlock()
+	r.st.unlock(ctx, nil)
 
-	r.st.mu.RLock()
-	defer r.st.mu.RUnlock()
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
 
 	c := r.st.comments[id]
 	if c == nil {
-		return "", 0, sql.ErrNoRows
+		return "", 0, ErrNotFound
 	}
 	return c.PostID, int(c.Depth), nil
 }
 
-func (r *MemoryCommentRepo) Create(ctx context.Context, postID, authorID string, parentID *string, body string, depth int) (*models.Comment, error) {
+func (r *MemoryCommentRepo) Create(ctx context.Context, c *models.Comment) (_ *models.Comment, err error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	if postID == "" || authorID == "" {
+	if c == nil {
+		return nil, ErrNilEntity
+	}
+	if c.PostID == "" || c.Author == nil || c.Author.ID == "" {
 		return nil, ErrEmptyID
 	}
 
 	timeNow := time.Now().UTC()
 	id := uuid.NewString()
+	postID, parentID := c.PostID, c.ParentID
+	state := c.ModerationState
+	if state == "" {
+		state = models.ModerationStateVisible
+	}
 
 	comment := &models.Comment{
-		ID:            id,
-		PostID:        postID,
-		Author:        &models.User{ID: authorID},
-		Body:          body,
-		ParentID:      parentID,
-		Depth:         int32(depth),
-		ChildrenCount: 0,
+		ID:              id,
+		PostID:          postID,
+		Author:          &models.User{ID: c.Author.ID},
+		Body:            c.Body,
+		BodyHTML:        c.BodyHTML,
+		ParentID:        parentID,
+		Depth:           c.Depth,
+		ChildrenCount:   0,
+		ModerationState: state,
 		Children: &models.CommentConnection{
 			Edges:      []*models.CommentEdge{},
 			PageInfo:   &models.PageInfo{HasNextPage: false, EndCursor: nil},
@@ -277,29 +574,31 @@ func (r *MemoryCommentRepo) Create(ctx context.Context, postID, authorID string,
 		CreatedAt: timeNow,
 	}
 
-	r.st.mu.Lock()
-	defer r.st.mu.Unlock()
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
 	r.st.maybePrune(timeNow)
 
-	r.st.comments[id] = comment
-	r.st.commentCreated[id] = timeNow
-	r.st.byPost[postID] = append(r.st.byPost[postID], id)
 	parentKey := ""
 	if parentID != nil {
 		parentKey = *parentID
 	}
+	r.st.touch(tableComments, id)
+	r.st.touch(tableCommentCreated, id)
+	r.st.touch(tableByPost, postID)
+	r.st.touch(tableByParent, parentKey)
+	r.st.comments[id] = comment
+	r.st.commentCreated[id] = timeNow
+	r.st.byPost[postID] = append(r.st.byPost[postID], id)
 	r.st.byParent[parentKey] = append(r.st.byParent[parentKey], id)
 
-	if parentID != nil && *parentID != "" {
-		if parent := r.st.comments[*parentID]; parent != nil {
-			parent.ChildrenCount++
-		}
+	if state == models.ModerationStateVisible {
+		r.st.addChildLocked(parentID, 1)
 	}
 
 	return comment, nil
 }
 
-func (r *MemoryCommentRepo) ListByParent(ctx context.Context, postID string, parentID *string, first int32, after *string, order models.CommentOrder) ([]*models.Comment, *string, error) {
+func (r *MemoryCommentRepo) ListByParent(ctx context.Context, postID string, parentID *string, first int32, after *string, order models.CommentOrder, viewer auth.Viewer) ([]*models.Comment, *string, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, nil, err
 	}
@@ -319,82 +618,1102 @@ func (r *MemoryCommentRepo) ListByParent(ctx context.Context, postID string, par
 	}
 
 	now := time.Now().UTC()
-	r.st.mu.Lock()
+	r.st.lock(ctx)
 	r.st.maybePrune(now)
-	r.st.mu.Unlock()
+	r.st.unlock(ctx, nil)
 
-	r.st.mu.RLock()
-	defer r.st.mu.RUnlock()
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
 
 	ids := append([]string(nil), r.st.byParent[parentKey]...)
 	if len(ids) == 0 {
 		return []*models.Comment{}, nil, nil
 	}
 
-	filtered := make([]string, 0, len(ids))
+	siblings := make([]string, 0, len(ids))
 	for _, id := range ids {
 		if c := r.st.comments[id]; c != nil && c.PostID == postID {
-			filtered = append(filtered, id)
+			siblings = append(siblings, id)
 		}
 	}
 
-	repository.SortCommentIDs(filtered, r.st.comments, order)
+	repository.SortCommentIDs(siblings, r.st.comments, order)
 	//func sortCommentIDs(ids []string, comments map[string]*models.Comment, order models.CommentOrder) {
 	//	sort.Slice(ids, func(i, j int) bool {
 	//		return CommentLess(comments[ids[i]], comments[ids[j]], order)
 	//	})
 	//}
 
-	filtered = repository.PaginateIDs(filtered, after, first)
+	filtered, ok := repository.PaginateIDs(siblings, after, first, func(id string) bool {
+		return CommentVisible(r.st.comments[id], viewer)
+	})
+	if !ok {
+		return nil, nil, ErrInvalidCursor
+	}
 
 	out := make([]*models.Comment, 0, len(filtered))
 	for _, id := range filtered {
 		if c := r.st.comments[id]; c != nil {
-			out = append(out, c)
+			cp := *c
+			out = append(out, &cp)
 		}
 	}
 
 	return out, repository.LastID(out, func(c *models.Comment) string { return c.ID }), nil
 }
 
-// TRASH...
+func (r *MemoryCommentRepo) SetModerationState(ctx context.Context, id string, state models.ModerationState) (_ *models.Comment, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, ErrEmptyID
+	}
 
-func (st *MemoryStorage) maybePrune(now time.Time) {
-	if st.ttl <= 0 {
-		return
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+
+	c := r.st.comments[id]
+	if c == nil {
+		return nil, ErrNotFound
 	}
-	if !st.lastPrune.IsZero() && now.Sub(st.lastPrune) < st.pruneInterval {
-		return
+	r.st.touch(tableComments, id)
+	switch was, now := c.ModerationState == models.ModerationStateVisible, state == models.ModerationStateVisible; {
+	case was && !now:
+		r.st.addChildLocked(c.ParentID, -1)
+	case !was && now:
+		r.st.addChildLocked(c.ParentID, 1)
 	}
-	st.lastPrune = now
-	st.pruneExpired(now)
+	c.ModerationState = state
+	cp := *c
+	return &cp, nil
 }
 
-func (st *MemoryStorage) pruneExpired(now time.Time) {
-	if st.ttl > 0 {
-		for id, ts := range st.postCreated {
-			if now.Sub(ts) > st.ttl {
-				delete(st.posts, id)
-				delete(st.postCreated, id)
-				st.postOrder = repository.RemoveID(st.postOrder, id)
-				for _, cid := range st.byPost[id] {
-					st.deleteCommentLocked(cid)
-				}
-				delete(st.byPost, id)
+func (r *MemoryCommentRepo) HideByAuthor(ctx context.Context, authorID string) (_ int, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if authorID == "" {
+		return 0, ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+
+	n := 0
+	for _, c := range r.st.comments {
+		if c.Author != nil && c.Author.ID == authorID && c.ModerationState != models.ModerationStateHidden {
+			r.st.touch(tableComments, c.ID)
+			if c.ModerationState == models.ModerationStateVisible {
+				r.st.addChildLocked(c.ParentID, -1)
 			}
+			c.ModerationState = models.ModerationStateHidden
+			n++
 		}
-		for id, ts := range st.userCreated {
-			if now.Sub(ts) > st.ttl {
-				delete(st.users, id)
-				delete(st.userCreated, id)
-			}
+	}
+	return n, nil
+}
+
+// ======================== TAG REPO ========================
+func (r *MemoryTagRepo) SetPostTags(ctx context.Context, postID string, tags []*models.Tag) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if postID == "" {
+		return ErrEmptyID
+	}
+
+	now := time.Now().UTC()
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+	r.st.maybePrune(now)
+
+	r.st.unlinkPostTagsLocked(postID)
+
+	slugs := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t == nil || t.Slug == "" {
+			continue
 		}
-		for id, ts := range st.commentCreated {
-			if now.Sub(ts) > st.ttl {
-				st.deleteCommentLocked(id)
-			}
+		if r.st.tags[t.Slug] == nil {
+			cp := repository.CloneTag(t)
+			cp.ID = uuid.NewString()
+			cp.PostsCount = 0
+			r.st.touch(tableTags, cp.Slug)
+			r.st.tags[cp.Slug] = cp
+		}
+		r.st.touch(tableTagPosts, t.Slug)
+		r.st.tagPosts[t.Slug] = append(r.st.tagPosts[t.Slug], postID)
+		slugs = append(slugs, t.Slug)
+	}
+	if len(slugs) > 0 {
+		r.st.touch(tablePostTags, postID)
+		r.st.postTags[postID] = slugs
+	}
+	return nil
+}
+
+func (r *MemoryTagRepo) ListByPost(ctx context.Context, postID string) ([]*models.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if postID == "" {
+		return nil, ErrEmptyID
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	slugs := append([]string(nil), r.st.postTags[postID]...)
+	sort.Strings(slugs)
+
+	out := make([]*models.Tag, 0, len(slugs))
+	for _, slug := range slugs {
+		if t := r.st.tagLocked(slug); t != nil {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (r *MemoryTagRepo) List(ctx context.Context, first int32, after *string) ([]*models.Tag, *string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	r.st.lock(ctx)
+	r.st.maybePrune(now)
+	r.st.unlock(ctx, nil)
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	// Теги без видимых всем постов не показываются, как в Postgres.
+	slugs, ok := repository.PaginateIDs(repository.SortedKeys(r.st.tags), after, first, func(slug string) bool {
+		return r.st.publicTagPostsLocked(slug) > 0
+	})
+	if !ok {
+		return nil, nil, ErrInvalidCursor
+	}
+	out := make([]*models.Tag, 0, len(slugs))
+	for _, slug := range slugs {
+		if t := r.st.tagLocked(slug); t != nil {
+			out = append(out, t)
+		}
+	}
+	return out, repository.LastID(out, func(t *models.Tag) string { return t.Slug }), nil
+}
+
+func (r *MemoryTagRepo) ListPosts(ctx context.Context, slug string, first int32, after *string, viewer auth.Viewer) ([]*models.Post, *string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	r.st.lock(ctx)
+	r.st.maybePrune(now)
+	r.st.unlock(ctx, nil)
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	tagged := make(map[string]struct{}, len(r.st.tagPosts[slug]))
+	for _, id := range r.st.tagPosts[slug] {
+		tagged[id] = struct{}{}
+	}
+	ids := make([]string, 0, len(tagged))
+	for _, id := range r.st.postOrder {
+		if _, ok := tagged[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+
+	ids, ok := repository.PaginateIDs(ids, after, first, r.st.postVisibleLocked(viewer))
+	if !ok {
+		return nil, nil, ErrInvalidCursor
+	}
+	posts := make([]*models.Post, 0, len(ids))
+	for _, id := range ids {
+		if p := r.st.posts[id]; p != nil {
+			posts = append(posts, repository.ClonePost(p))
 		}
 	}
+	return posts, repository.LastID(posts, func(p *models.Post) string { return p.ID }), nil
+}
+
+// ======================== NOTIFICATION REPO ========================
+func (r *MemoryNotificationRepo) AddMentions(ctx context.Context, mentions []*models.Mention) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+	r.st.maybePrune(now)
+
+	for _, m := range mentions {
+		if m == nil || m.UserID == "" || m.PostID == "" {
+			continue
+		}
+		cp := *m
+		cp.ID = uuid.NewString()
+		cp.CreatedAt = now
+		r.st.touch(tableMentions, cp.ID)
+		r.st.mentions[cp.ID] = &cp
+	}
+	return nil
+}
+
+func (r *MemoryNotificationRepo) Create(ctx context.Context, n *models.Notification) (_ *models.Notification, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, ErrNilEntity
+	}
+	if n.UserID == "" || n.PostID == "" {
+		return nil, ErrEmptyID
+	}
+
+	now := time.Now().UTC()
+	cp := *n
+	cp.ID = uuid.NewString()
+	cp.CreatedAt = now
+	cp.ReadAt = nil
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+	r.st.maybePrune(now)
+
+	r.st.touch(tableNotifications, cp.ID)
+	r.st.touch(tableByRecipient, cp.UserID)
+	r.st.notifications[cp.ID] = &cp
+	r.st.byRecipient[cp.UserID] = append(r.st.byRecipient[cp.UserID], cp.ID)
+
+	out := cp
+	return &out, nil
+}
+
+func (r *MemoryNotificationRepo) List(ctx context.Context, userID string, first int32, after *string, unreadOnly bool) ([]*models.Notification, *string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if userID == "" {
+		return nil, nil, ErrEmptyID
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	// Новые первыми.
+	src := r.st.byRecipient[userID]
+	ids := make([]string, 0, len(src))
+	for i := len(src) - 1; i >= 0; i-- {
+		if r.st.notifications[src[i]] != nil {
+			ids = append(ids, src[i])
+		}
+	}
+
+	ids, ok := repository.PaginateIDs(ids, after, first, func(id string) bool {
+		return !unreadOnly || r.st.notifications[id].ReadAt == nil
+	})
+	if !ok {
+		return nil, nil, ErrInvalidCursor
+	}
+	out := make([]*models.Notification, 0, len(ids))
+	for _, id := range ids {
+		cp := *r.st.notifications[id]
+		out = append(out, &cp)
+	}
+	return out, repository.LastID(out, func(n *models.Notification) string { return n.ID }), nil
+}
+
+func (r *MemoryNotificationRepo) CountUnread(ctx context.Context, userID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	cnt := 0
+	for _, id := range r.st.byRecipient[userID] {
+		if n := r.st.notifications[id]; n != nil && n.ReadAt == nil {
+			cnt++
+		}
+	}
+	return cnt, nil
+}
+
+func (r *MemoryNotificationRepo) MarkRead(ctx context.Context, userID string, ids []string, at time.Time) (_ int, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if userID == "" {
+		return 0, ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+
+	target := r.st.byRecipient[userID]
+	if len(ids) > 0 {
+		target = ids
+	}
+
+	at = at.UTC()
+	cnt := 0
+	for _, id := range target {
+		n := r.st.notifications[id]
+		if n == nil || n.UserID != userID || n.ReadAt != nil {
+			continue
+		}
+		r.st.touch(tableNotifications, id)
+		n.ReadAt = &at
+		cnt++
+	}
+	return cnt, nil
+}
+
+// ======================== FOLLOW REPO ========================
+func (r *MemoryFollowRepo) Follow(ctx context.Context, followerID, followeeID string) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if followerID == "" || followeeID == "" {
+		return ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+	r.st.maybePrune(time.Now().UTC())
+
+	if slices.Contains(r.st.following[followerID], followeeID) {
+		return nil
+	}
+	r.st.touch(tableFollowing, followerID)
+	r.st.touch(tableFollowers, followeeID)
+	r.st.following[followerID] = append(r.st.following[followerID], followeeID)
+	r.st.followers[followeeID] = append(r.st.followers[followeeID], followerID)
+	return nil
+}
+
+func (r *MemoryFollowRepo) Unfollow(ctx context.Context, followerID, followeeID string) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if followerID == "" || followeeID == "" {
+		return ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+
+	r.st.touch(tableFollowing, followerID)
+	r.st.touch(tableFollowers, followeeID)
+	r.st.following[followerID] = repository.RemoveID(r.st.following[followerID], followeeID)
+	r.st.followers[followeeID] = repository.RemoveID(r.st.followers[followeeID], followerID)
+	return nil
+}
+
+func (r *MemoryFollowRepo) ListFollowers(ctx context.Context, userID string, first int32, after *string) ([]*models.User, *string, error) {
+	return r.listUsers(ctx, r.st.followers, userID, first, after)
+}
+
+func (r *MemoryFollowRepo) ListFollowing(ctx context.Context, userID string, first int32, after *string) ([]*models.User, *string, error) {
+	return r.listUsers(ctx, r.st.following, userID, first, after)
+}
+
+func (r *MemoryFollowRepo) CountFollowers(ctx context.Context, userID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+	return len(r.st.followers[userID]), nil
+}
+
+func (r *MemoryFollowRepo) CountFollowing(ctx context.Context, userID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+	return len(r.st.following[userID]), nil
+}
+
+func (r *MemoryFollowRepo) Feed(ctx context.Context, userID string, first int32, after *string) ([]*models.Post, *string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if userID == "" {
+		return nil, nil, ErrEmptyID
+	}
+
+	now := time.Now().UTC()
+	r.st.lock(ctx)
+	r.st.maybePrune(now)
+	r.st.unlock(ctx, nil)
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	authors := make(map[string]struct{}, len(r.st.following[userID]))
+	for _, id := range r.st.following[userID] {
+		authors[id] = struct{}{}
+	}
+
+	// Курсор может указывать на любой пост, поэтому порядок строится по всем.
+	feed := make([]*models.Post, 0, len(r.st.postOrder))
+	for _, id := range r.st.postOrder {
+		if p := r.st.posts[id]; p != nil {
+			feed = append(feed, p)
+		}
+	}
+	sort.SliceStable(feed, func(i, j int) bool {
+		a, b := feedTime(feed[i]), feedTime(feed[j])
+		if a.Equal(b) {
+			return feed[i].ID > feed[j].ID
+		}
+		return a.After(b)
+	})
+
+	ids := make([]string, 0, len(feed))
+	for _, p := range feed {
+		ids = append(ids, p.ID)
+	}
+	ids, ok := repository.PaginateIDs(ids, after, first, func(id string) bool {
+		p := r.st.posts[id]
+		if p.Status != models.PostStatusPublished || p.ModerationState != models.ModerationStateVisible || p.Author == nil {
+			return false
+		}
+		_, ok := authors[p.Author.ID]
+		return ok
+	})
+	if !ok {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	posts := make([]*models.Post, 0, len(ids))
+	for _, id := range ids {
+		posts = append(posts, repository.ClonePost(r.st.posts[id]))
+	}
+	return posts, repository.LastID(posts, func(p *models.Post) string { return p.ID }), nil
+}
+
+// ======================== BOOKMARK REPO ========================
+func (r *MemoryBookmarkRepo) Add(ctx context.Context, userID, postID string) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if userID == "" || postID == "" {
+		return ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+	r.st.maybePrune(time.Now().UTC())
+
+	if !slices.Contains(r.st.bookmarks[userID], postID) {
+		r.st.touch(tableBookmarks, userID)
+		r.st.bookmarks[userID] = append(r.st.bookmarks[userID], postID)
+	}
+	return nil
+}
+
+func (r *MemoryBookmarkRepo) Remove(ctx context.Context, userID, postID string) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if userID == "" || postID == "" {
+		return ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+
+	r.st.touch(tableBookmarks, userID)
+	r.st.bookmarks[userID] = repository.RemoveID(r.st.bookmarks[userID], postID)
+	return nil
+}
+
+func (r *MemoryBookmarkRepo) Has(ctx context.Context, userID, postID string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+	return slices.Contains(r.st.bookmarks[userID], postID), nil
+}
+
+func (r *MemoryBookmarkRepo) List(ctx context.Context, userID string, first int32, after *string, viewer auth.Viewer) ([]*models.Post, *string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if userID == "" {
+		return nil, nil, ErrEmptyID
+	}
+
+	now := time.Now().UTC()
+	r.st.lock(ctx)
+	r.st.maybePrune(now)
+	r.st.unlock(ctx, nil)
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	// Новые первыми.
+	src := r.st.bookmarks[userID]
+	ids := make([]string, 0, len(src))
+	for i := len(src) - 1; i >= 0; i-- {
+		ids = append(ids, src[i])
+	}
+
+	ids, ok := repository.PaginateIDs(ids, after, first, r.st.postVisibleLocked(viewer))
+	if !ok {
+		return nil, nil, ErrInvalidCursor
+	}
+	posts := make([]*models.Post, 0, len(ids))
+	for _, id := range ids {
+		posts = append(posts, repository.ClonePost(r.st.posts[id]))
+	}
+	return posts, repository.LastID(posts, func(p *models.Post) string { return p.ID }), nil
+}
+
+// ======================== READ STATE REPO ========================
+func (r *MemoryReadStateRepo) MarkRead(ctx context.Context, userID, postID string, at time.Time) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if userID == "" || postID == "" {
+		return ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+
+	reads := r.st.postReads[userID]
+	if prev, ok := reads[postID]; !ok || at.After(prev) {
+		r.st.touch(tablePostReads, userID)
+		if reads == nil {
+			reads = map[string]time.Time{}
+			r.st.postReads[userID] = reads
+		}
+		reads[postID] = at.UTC()
+	}
+	return nil
+}
+
+func (r *MemoryReadStateRepo) LastReadAt(ctx context.Context, userID, postID string) (*time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	at, ok := r.st.postReads[userID][postID]
+	if !ok {
+		return nil, nil
+	}
+	return &at, nil
+}
+
+func (r *MemoryReadStateRepo) CountUnread(ctx context.Context, postID string, viewer auth.Viewer) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	lastRead, read := r.st.postReads[viewer.UserID][postID]
+	cnt := 0
+	for _, id := range r.st.byPost[postID] {
+		c := r.st.comments[id]
+		if c == nil || (c.Author != nil && c.Author.ID == viewer.UserID) || !CommentVisible(c, viewer) {
+			continue
+		}
+		if !read || c.CreatedAt.After(lastRead) {
+			cnt++
+		}
+	}
+	return cnt, nil
+}
+
+// ======================== REPORT REPO ========================
+
+// reporterID автор жалобы, пустой у системных жалоб.
+func reporterID(rep *models.Report) string {
+	if rep.Reporter == nil {
+		return ""
+	}
+	return rep.Reporter.ID
+}
+
+func (r *MemoryReportRepo) Create(ctx context.Context, rep *models.Report) (_ *models.Report, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if rep == nil {
+		return nil, ErrNilEntity
+	}
+	if rep.TargetID == "" || (rep.Reporter != nil && rep.Reporter.ID == "") {
+		return nil, ErrEmptyID
+	}
+
+	now := time.Now().UTC()
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+	r.st.maybePrune(now)
+
+	for _, id := range r.st.reportOrder {
+		open := r.st.reports[id]
+		if open.Status == models.ReportStatusOpen && open.TargetID == rep.TargetID && reporterID(open) == reporterID(rep) {
+			return nil, ErrAlreadyExist
+		}
+	}
+
+	cp := *rep
+	cp.ID = uuid.NewString()
+	if rep.Reporter != nil {
+		cp.Reporter = &models.User{ID: rep.Reporter.ID}
+	}
+	cp.Status = models.ReportStatusOpen
+	cp.Action, cp.ResolvedBy, cp.ResolvedAt = nil, nil, nil
+	cp.CreatedAt = now
+
+	r.st.touch(tableReports, cp.ID)
+	r.st.reports[cp.ID] = &cp
+	r.st.reportOrder = append(r.st.reportOrder, cp.ID)
+
+	out := cp
+	return &out, nil
+}
+
+func (r *MemoryReportRepo) GetByID(ctx context.Context, id string) (*models.Report, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, ErrEmptyID
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	rep := r.st.reports[id]
+	if rep == nil {
+		return nil, ErrNotFound
+	}
+	cp := *rep
+	return &cp, nil
+}
+
+func (r *MemoryReportRepo) List(ctx context.Context, status *models.ReportStatus, first int32, after *string) ([]*models.Report, *string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	// Новые первыми.
+	ids := make([]string, 0, len(r.st.reportOrder))
+	for i := len(r.st.reportOrder) - 1; i >= 0; i-- {
+		if r.st.reports[r.st.reportOrder[i]] != nil {
+			ids = append(ids, r.st.reportOrder[i])
+		}
+	}
+
+	ids, ok := repository.PaginateIDs(ids, after, first, func(id string) bool {
+		return status == nil || r.st.reports[id].Status == *status
+	})
+	if !ok {
+		return nil, nil, ErrInvalidCursor
+	}
+	out := make([]*models.Report, 0, len(ids))
+	for _, id := range ids {
+		cp := *r.st.reports[id]
+		out = append(out, &cp)
+	}
+	return out, repository.LastID(out, func(rep *models.Report) string { return rep.ID }), nil
+}
+
+func (r *MemoryReportRepo) Resolve(ctx context.Context, id string, status models.ReportStatus, action models.ModerationAction, moderatorID string, at time.Time) (_ *models.Report, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+
+	rep := r.st.reports[id]
+	if rep == nil {
+		return nil, ErrNotFound
+	}
+	if rep.Status != models.ReportStatusOpen {
+		return nil, ErrReportClosed
+	}
+
+	at = at.UTC()
+	r.st.touch(tableReports, id)
+	rep.Status = status
+	rep.Action = &action
+	rep.ResolvedBy = &models.User{ID: moderatorID}
+	rep.ResolvedAt = &at
+
+	cp := *rep
+	return &cp, nil
+}
+
+// ======================== BAN REPO ========================
+func (r *MemoryBanRepo) Create(ctx context.Context, b *models.Ban) (_ *models.Ban, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, ErrNilEntity
+	}
+	if b.User == nil || b.User.ID == "" {
+		return nil, ErrEmptyID
+	}
+
+	now := time.Now().UTC()
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+	r.st.maybePrune(now)
+
+	cp := *b
+	cp.ID = uuid.NewString()
+	cp.User = &models.User{ID: b.User.ID}
+	if b.BannedBy != nil {
+		cp.BannedBy = &models.User{ID: b.BannedBy.ID}
+	}
+	cp.LiftedAt, cp.LiftedBy = nil, nil
+	cp.CreatedAt = now
+
+	r.st.touch(tableBans, cp.User.ID)
+	r.st.bans[cp.User.ID] = append(r.st.bans[cp.User.ID], &cp)
+
+	out := cp
+	return &out, nil
+}
+
+func (r *MemoryBanRepo) Active(ctx context.Context, userID string, at time.Time) (*models.Ban, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, ErrEmptyID
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	// Последняя действующая.
+	bans := r.st.bans[userID]
+	for i := len(bans) - 1; i >= 0; i-- {
+		if bans[i].ActiveAt(at) {
+			cp := *bans[i]
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MemoryBanRepo) Lift(ctx context.Context, userID, moderatorID string, at time.Time) (_ int, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if userID == "" {
+		return 0, ErrEmptyID
+	}
+
+	r.st.lock(ctx)
+	defer r.st.unlock(ctx, &err)
+
+	at = at.UTC()
+	n := 0
+	for _, b := range r.st.bans[userID] {
+		if b.ActiveAt(at) {
+			r.st.touch(tableBans, userID)
+			b.LiftedAt = &at
+			b.LiftedBy = &models.User{ID: moderatorID}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ======================== AUDIT REPO ========================
+
+// DefaultAuditCapacity записей журнала в памяти по умолчанию.
+const DefaultAuditCapacity = 10000
+
+// MemoryAuditRepo журнал мутаций в кольцевом буфере: самые старые записи вытесняются.
+type MemoryAuditRepo struct {
+	mu      sync.RWMutex
+	entries []*models.AuditEntry
+	next    int
+	size    int
+}
+
+func NewMemoryAuditRepo(capacity int) *MemoryAuditRepo {
+	if capacity <= 0 {
+		capacity = DefaultAuditCapacity
+	}
+	return &MemoryAuditRepo{entries: make([]*models.AuditEntry, capacity)}
+}
+
+func (r *MemoryAuditRepo) Append(ctx context.Context, e *models.AuditEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if e == nil {
+		return ErrNilEntity
+	}
+
+	cp := *e
+	cp.ID = uuid.NewString()
+	if cp.CreatedAt.IsZero() {
+		cp.CreatedAt = time.Now().UTC()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = &cp
+	r.next = (r.next + 1) % len(r.entries)
+	if r.size < len(r.entries) {
+		r.size++
+	}
+	return nil
+}
+
+func (r *MemoryAuditRepo) List(ctx context.Context, filter models.AuditFilter, first int32, after *string) ([]*models.AuditEntry, *string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Новые первыми: от последней записанной назад по кольцу.
+	byID := make(map[string]*models.AuditEntry, r.size)
+	ids := make([]string, 0, r.size)
+	for i := 1; i <= r.size; i++ {
+		e := r.entries[(r.next-i+len(r.entries))%len(r.entries)]
+		byID[e.ID] = e
+		ids = append(ids, e.ID)
+	}
+
+	ids, ok := repository.PaginateIDs(ids, after, first, func(id string) bool {
+		return AuditMatches(byID[id], filter)
+	})
+	if !ok {
+		return nil, nil, ErrInvalidCursor
+	}
+	out := make([]*models.AuditEntry, 0, len(ids))
+	for _, id := range ids {
+		cp := *byID[id]
+		out = append(out, &cp)
+	}
+	return out, repository.LastID(out, func(e *models.AuditEntry) string { return e.ID }), nil
+}
+
+// listUsers пользователи из списка подписок, новые подписки первыми.
+func (r *MemoryFollowRepo) listUsers(ctx context.Context, index map[string][]string, userID string, first int32, after *string) ([]*models.User, *string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if userID == "" {
+		return nil, nil, ErrEmptyID
+	}
+
+	r.st.rlock(ctx)
+	defer r.st.runlock(ctx)
+
+	src := index[userID]
+	ids := make([]string, 0, len(src))
+	for i := len(src) - 1; i >= 0; i-- {
+		ids = append(ids, src[i])
+	}
+
+	ids, ok := repository.PaginateIDs(ids, after, first, func(id string) bool {
+		return r.st.users[id] != nil
+	})
+	if !ok {
+		return nil, nil, ErrInvalidCursor
+	}
+	users := make([]*models.User, 0, len(ids))
+	for _, id := range ids {
+		users = append(users, repository.CloneUser(r.st.users[id]))
+	}
+	return users, repository.LastID(users, func(u *models.User) string { return u.ID }), nil
+}
+
+// feedTime время поста в ленте.
+func feedTime(p *models.Post) time.Time {
+	if p.PublishedAt != nil {
+		return *p.PublishedAt
+	}
+	return p.CreatedAt
+}
+
+// postVisibleLocked фильтр страницы: посты, которые видит пользователь.
+func (st *MemoryStorage) postVisibleLocked(viewer auth.Viewer) func(id string) bool {
+	return func(id string) bool {
+		return PostVisible(st.posts[id], viewer)
+	}
+}
+
+// tagLocked копия тега со счетчиком видимых всем постов.
+func (st *MemoryStorage) tagLocked(slug string) *models.Tag {
+	t := st.tags[slug]
+	if t == nil {
+		return nil
+	}
+	cp := repository.CloneTag(t)
+	cp.PostsCount = int32(st.publicTagPostsLocked(slug))
+	return cp
+}
+
+// publicTagPostsLocked количество постов тега, видимых анониму.
+func (st *MemoryStorage) publicTagPostsLocked(slug string) int {
+	n := 0
+	for _, id := range st.tagPosts[slug] {
+		if PostVisible(st.posts[id], auth.Viewer{}) {
+			n++
+		}
+	}
+	return n
+}
+
+func (st *MemoryStorage) unlinkPostTagsLocked(postID string) {
+	for _, slug := range st.postTags[postID] {
+		st.touch(tableTagPosts, slug)
+		st.tagPosts[slug] = repository.RemoveID(st.tagPosts[slug], postID)
+		if len(st.tagPosts[slug]) == 0 {
+			delete(st.tagPosts, slug)
+		}
+	}
+	st.touch(tablePostTags, postID)
+	delete(st.postTags, postID)
+}
+
+func (st *MemoryStorage) unlinkFollowsLocked(userID string) {
+	for _, id := range st.following[userID] {
+		st.followers[id] = repository.RemoveID(st.followers[id], userID)
+	}
+	for _, id := range st.followers[userID] {
+		st.following[id] = repository.RemoveID(st.following[id], userID)
+	}
+	delete(st.following, userID)
+	delete(st.followers, userID)
+}
+
+func (st *MemoryStorage) unlinkPostReadersLocked(postID string) {
+	for userID := range st.bookmarks {
+		st.bookmarks[userID] = repository.RemoveID(st.bookmarks[userID], postID)
+	}
+	for _, reads := range st.postReads {
+		delete(reads, postID)
+	}
+}
+
+// TRASH...
+
+func (st *MemoryStorage) maybePrune(now time.Time) {
+	// Очистку не откатить, в транзакции ее нет.
+	if st.ttl <= 0 || st.tx != nil {
+		return
+	}
+	if !st.lastPrune.IsZero() && now.Sub(st.lastPrune) < st.pruneInterval {
+		return
+	}
+	st.lastPrune = now
+	before := st.sizeLocked()
+	st.pruneExpired(now)
+	if st.sizeLocked() != before {
+		st.touchAll()
+	}
+}
+
+// sizeLocked количество записей, по изменению которого видно, что очистка что-то удалила.
+func (st *MemoryStorage) sizeLocked() int {
+	return len(st.users) + len(st.posts) + len(st.comments) + len(st.notifications) + len(st.mentions) + len(st.reports)
+}
+
+func (st *MemoryStorage) pruneExpired(now time.Time) {
+	if st.ttl > 0 {
+		for id, ts := range st.postCreated {
+			if now.Sub(ts) > st.ttl {
+				delete(st.posts, id)
+				delete(st.postCreated, id)
+				st.postOrder = repository.RemoveID(st.postOrder, id)
+				st.unlinkPostTagsLocked(id)
+				st.unlinkPostReadersLocked(id)
+				for _, cid := range st.byPost[id] {
+					st.deleteCommentLocked(cid)
+				}
+				delete(st.byPost, id)
+			}
+		}
+		for id, ts := range st.userCreated {
+			if now.Sub(ts) > st.ttl {
+				delete(st.users, id)
+				delete(st.userCreated, id)
+				st.unlinkFollowsLocked(id)
+				delete(st.bookmarks, id)
+				delete(st.postReads, id)
+				delete(st.bans, id)
+			}
+		}
+		for id, ts := range st.commentCreated {
+			if now.Sub(ts) > st.ttl {
+				st.deleteCommentLocked(id)
+			}
+		}
+		for id, n := range st.notifications {
+			if now.Sub(n.CreatedAt) > st.ttl {
+				delete(st.notifications, id)
+				st.byRecipient[n.UserID] = repository.RemoveID(st.byRecipient[n.UserID], id)
+			}
+		}
+		for id, m := range st.mentions {
+			if now.Sub(m.CreatedAt) > st.ttl {
+				delete(st.mentions, id)
+			}
+		}
+		for id, rep := range st.reports {
+			if now.Sub(rep.CreatedAt) > st.ttl {
+				delete(st.reports, id)
+				st.reportOrder = repository.RemoveID(st.reportOrder, id)
+			}
+		}
+	}
+}
+
+// addChildLocked меняет счетчик видимых ответов родителя на delta.
+func (st *MemoryStorage) addChildLocked(parentID *string, delta int32) {
+	if parentID == nil || *parentID == "" {
+		return
+	}
+	parent := st.comments[*parentID]
+	if parent == nil || parent.ChildrenCount+delta < 0 {
+		return
+	}
+	st.touch(tableComments, parent.ID)
+	parent.ChildrenCount += delta
 }
 
 func (st *MemoryStorage) deleteCommentLocked(id string) {
@@ -407,8 +1726,8 @@ func (st *MemoryStorage) deleteCommentLocked(id string) {
 	if c.ParentID != nil {
 		parentKey := *c.ParentID
 		st.byParent[parentKey] = repository.RemoveID(st.byParent[parentKey], id)
-		if parent := st.comments[parentKey]; parent != nil && parent.ChildrenCount > 0 {
-			parent.ChildrenCount--
+		if c.ModerationState == models.ModerationStateVisible {
+			st.addChildLocked(c.ParentID, -1)
 		}
 	} else {
 		st.byParent[""] = repository.RemoveID(st.byParent[""], id)
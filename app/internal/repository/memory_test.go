@@ -2,8 +2,11 @@ package repository
 
 import (
 	"context"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/RoGogDBD/GQLGo/internal/auth"
 	"github.com/RoGogDBD/GQLGo/internal/models"
 )
 
@@ -28,17 +31,17 @@ func TestMemoryPostRepo_CreateList(t *testing.T) {
 			repo := NewMemoryPostRepo(storTTL)
 
 			for i := 0; i < tc.createCnt; i++ {
-				_, err := repo.Create(context.Background(), models.CreatePostInput{
-					AuthorID: "author",
-					Title:    "title",
-					Body:     "body",
+				_, err := repo.Create(context.Background(), &models.Post{
+					Author: &models.User{ID: "author"},
+					Title:  "title",
+					Body:   "body",
 				})
 				if err != nil {
 					t.Fatalf("при создинии поста: %v", err)
 				}
 			}
 
-			list, cursor, err := repo.List(context.Background(), tc.listFirst, nil)
+			list, cursor, err := repo.List(context.Background(), tc.listFirst, nil, auth.Viewer{})
 			if err != nil {
 				t.Fatalf("список постов: %v", err)
 			}
@@ -73,7 +76,7 @@ func TestMemoryCommentRepo_ListByParent(t *testing.T) {
 			st := NewMemoryStorageWithTTL(0)
 			repo := NewMemoryCommentRepo(st)
 
-			root, err := repo.Create(context.Background(), "p", "u", nil, "root", 0)
+			root, err := repo.Create(context.Background(), &models.Comment{PostID: "p", Author: &models.User{ID: "u"}, Body: "root"})
 			if err != nil {
 				t.Fatalf("при создании корневого комментария: %v", err)
 			}
@@ -84,12 +87,12 @@ func TestMemoryCommentRepo_ListByParent(t *testing.T) {
 			}
 
 			if tc.createChild {
-				if _, err := repo.Create(context.Background(), "p", "u", &root.ID, "child", 1); err != nil {
+				if _, err := repo.Create(context.Background(), &models.Comment{PostID: "p", Author: &models.User{ID: "u"}, ParentID: &root.ID, Body: "child", Depth: 1}); err != nil {
 					t.Fatalf("при создании дочернего комментария: %v", err)
 				}
 			}
 
-			list, _, err := repo.ListByParent(context.Background(), "p", parentID, 10, nil, models.CommentOrderNewest)
+			list, _, err := repo.ListByParent(context.Background(), "p", parentID, 10, nil, models.CommentOrderNewest, auth.Viewer{})
 			if err != nil {
 				t.Fatalf("список комментариев: %v", err)
 			}
@@ -99,3 +102,283 @@ func TestMemoryCommentRepo_ListByParent(t *testing.T) {
 		})
 	}
 }
+
+// Тест на привязку тегов и счетчики постов.
+func TestMemoryTagRepo_SetPostTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    []string
+		second   []string
+		postTags int
+		goCount  int32
+	}{
+		{name: "Один пост", first: []string{"go"}, postTags: 1, goCount: 1},
+		{name: "Два поста с общим тегом", first: []string{"go"}, second: []string{"go", "sql"}, postTags: 1, goCount: 2},
+		{name: "Без тегов", first: nil, second: []string{"go"}, postTags: 0, goCount: 1},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			st := NewMemoryStorageWithTTL(0)
+			posts := NewMemoryPostRepo(st)
+			repo := NewMemoryTagRepo(st)
+			ctx := context.Background()
+
+			p1, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: "author"}, Title: "t", Body: "b"})
+			if err != nil {
+				t.Fatalf("при создинии поста: %v", err)
+			}
+			p2, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: "author"}, Title: "t", Body: "b"})
+			if err != nil {
+				t.Fatalf("при создинии поста: %v", err)
+			}
+
+			if err := repo.SetPostTags(ctx, p1.ID, memoryTags(tc.first...)); err != nil {
+				t.Fatalf("теги первого поста: %v", err)
+			}
+			if err := repo.SetPostTags(ctx, p2.ID, memoryTags(tc.second...)); err != nil {
+				t.Fatalf("теги второго поста: %v", err)
+			}
+
+			tags, err := repo.ListByPost(ctx, p1.ID)
+			if err != nil {
+				t.Fatalf("теги поста: %v", err)
+			}
+			if len(tags) != tc.postTags {
+				t.Fatalf("ожидалось %d тегов, а получили %d", tc.postTags, len(tags))
+			}
+
+			list, _, err := repo.List(ctx, 10, nil)
+			if err != nil {
+				t.Fatalf("список тегов: %v", err)
+			}
+			for _, tag := range list {
+				if tag.Slug == "go" && tag.PostsCount != tc.goCount {
+					t.Fatalf("ожидалось %d постов с go, а получили %d", tc.goCount, tag.PostsCount)
+				}
+			}
+
+			byTag, _, err := repo.ListPosts(ctx, "go", 10, nil, auth.Viewer{})
+			if err != nil {
+				t.Fatalf("посты по тегу: %v", err)
+			}
+			if int32(len(byTag)) != tc.goCount {
+				t.Fatalf("ожидалось %d постов по тегу, а получили %d", tc.goCount, len(byTag))
+			}
+		})
+	}
+}
+
+func memoryTags(slugs ...string) []*models.Tag {
+	tags := make([]*models.Tag, 0, len(slugs))
+	for _, s := range slugs {
+		tags = append(tags, &models.Tag{Name: s, Slug: s})
+	}
+	return tags
+}
+
+// Тест на видимость черновиков и публикацию отложенных постов.
+func TestMemoryPostRepo_StatusVisibility(t *testing.T) {
+	past := time.Now().UTC().Add(-time.Minute)
+	future := time.Now().UTC().Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		status    models.PostStatus
+		publishAt *time.Time
+		viewer    auth.Viewer
+		visible   bool
+		due       int
+	}{
+		{name: "Опубликованный", status: models.PostStatusPublished, visible: true},
+		{name: "Черновик для анонима", status: models.PostStatusDraft, visible: false},
+		{name: "Черновик для автора", status: models.PostStatusDraft, viewer: auth.Viewer{UserID: "author"}, visible: true},
+		{name: "Черновик для чужого", status: models.PostStatusDraft, viewer: auth.Viewer{UserID: "other"}, visible: false},
+		{name: "Отложенный в будущем", status: models.PostStatusScheduled, publishAt: &future, visible: false},
+		{name: "Отложенный к публикации", status: models.PostStatusScheduled, publishAt: &past, visible: true, due: 1},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			repo := NewMemoryPostRepo(NewMemoryStorageWithTTL(0))
+			ctx := context.Background()
+
+			_, err := repo.Create(ctx, &models.Post{
+				Author:    &models.User{ID: "author"},
+				Title:     "t",
+				Body:      "b",
+				Status:    tc.status,
+				PublishAt: tc.publishAt,
+			})
+			if err != nil {
+				t.Fatalf("при создинии поста: %v", err)
+			}
+
+			due, err := repo.PublishDue(ctx, time.Now().UTC(), 10)
+			if err != nil {
+				t.Fatalf("публикация отложенных: %v", err)
+			}
+			if len(due) != tc.due {
+				t.Fatalf("ожидалось %d опубликованных, а получили %d", tc.due, len(due))
+			}
+
+			list, _, err := repo.List(ctx, 10, nil, tc.viewer)
+			if err != nil {
+				t.Fatalf("список постов: %v", err)
+			}
+			if tc.visible != (len(list) == 1) {
+				t.Fatalf("ожидалась видимость %v, а получили %d постов", tc.visible, len(list))
+			}
+		})
+	}
+}
+
+// Тест на подписки и ленту.
+func TestMemoryFollowRepo_Feed(t *testing.T) {
+	tests := []struct {
+		name      string
+		follow    []string
+		unfollow  []string
+		feed      []string
+		followers int
+	}{
+		{name: "Без подписок", feed: []string{}},
+		{name: "Одна подписка", follow: []string{"bob"}, feed: []string{"bob-2", "bob-1"}, followers: 1},
+		{name: "Две подписки", follow: []string{"bob", "carol", "bob"}, feed: []string{"carol-1", "bob-2", "bob-1"}, followers: 1},
+		{name: "Отписка", follow: []string{"bob", "carol"}, unfollow: []string{"bob"}, feed: []string{"carol-1"}, followers: 0},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			st := NewMemoryStorageWithTTL(0)
+			st.SeedUsers(
+				&models.User{ID: "alice", Username: "alice"},
+				&models.User{ID: "bob", Username: "bob"},
+				&models.User{ID: "carol", Username: "carol"},
+			)
+			posts := NewMemoryPostRepo(st)
+			repo := NewMemoryFollowRepo(st)
+			ctx := context.Background()
+
+			base := time.Now().UTC()
+			for i, p := range []struct{ author, title string }{
+				{"bob", "bob-1"}, {"bob", "bob-2"}, {"carol", "carol-1"}, {"alice", "alice-1"},
+			} {
+				at := base.Add(time.Duration(i) * time.Second)
+				_, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: p.author}, Title: p.title, Body: "b", PublishedAt: &at})
+				if err != nil {
+					t.Fatalf("при создинии поста: %v", err)
+				}
+			}
+			_, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: "bob"}, Title: "draft", Body: "b", Status: models.PostStatusDraft})
+			if err != nil {
+				t.Fatalf("при создинии поста: %v", err)
+			}
+
+			for _, id := range tc.follow {
+				if err := repo.Follow(ctx, "alice", id); err != nil {
+					t.Fatalf("подписка: %v", err)
+				}
+			}
+			for _, id := range tc.unfollow {
+				if err := repo.Unfollow(ctx, "alice", id); err != nil {
+					t.Fatalf("отписка: %v", err)
+				}
+			}
+
+			// Постранично по одному, чтобы проверить курсор.
+			got := make([]string, 0)
+			var after *string
+			for {
+				page, cursor, err := repo.Feed(ctx, "alice", 1, after)
+				if err != nil {
+					t.Fatalf("лента: %v", err)
+				}
+				if len(page) == 0 {
+					break
+				}
+				got = append(got, page[0].Title)
+				after = cursor
+			}
+			if !slices.Equal(got, tc.feed) {
+				t.Fatalf("ожидалось %v, а получили %v", tc.feed, got)
+			}
+
+			followers, err := repo.CountFollowers(ctx, "bob")
+			if err != nil {
+				t.Fatalf("количество подписчиков: %v", err)
+			}
+			if followers != tc.followers {
+				t.Fatalf("ожидалось %d подписчиков, а получили %d", tc.followers, followers)
+			}
+		})
+	}
+}
+
+// Тест на счетчик непрочитанных комментариев.
+func TestMemoryReadStateRepo_CountUnread(t *testing.T) {
+	tests := []struct {
+		name    string
+		before  []string
+		after   []string
+		markAt  bool
+		unread  int
+		hasRead bool
+	}{
+		{name: "Пост не читали", before: []string{"bob", "carol"}, unread: 2},
+		{name: "Свои не считаются", before: []string{"alice", "bob"}, unread: 1},
+		{name: "Прочитан", before: []string{"bob"}, markAt: true, unread: 0, hasRead: true},
+		{name: "Новые после прочтения", before: []string{"bob"}, after: []string{"carol", "alice"}, markAt: true, unread: 1, hasRead: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			st := NewMemoryStorageWithTTL(0)
+			comments := NewMemoryCommentRepo(st)
+			repo := NewMemoryReadStateRepo(st)
+			ctx := context.Background()
+
+			add := func(authors []string) {
+				for _, a := range authors {
+					_, err := comments.Create(ctx, &models.Comment{PostID: "p1", Author: &models.User{ID: a}, Body: "b"})
+					if err != nil {
+						t.Fatalf("создание комментария: %v", err)
+					}
+				}
+			}
+
+			add(tc.before)
+			if tc.markAt {
+				if err := repo.MarkRead(ctx, "alice", "p1", time.Now().UTC()); err != nil {
+					t.Fatalf("прочтение: %v", err)
+				}
+				// Более раннее прочтение не сдвигает время назад.
+				if err := repo.MarkRead(ctx, "alice", "p1", time.Now().UTC().Add(-time.Hour)); err != nil {
+					t.Fatalf("прочтение: %v", err)
+				}
+				time.Sleep(time.Millisecond)
+			}
+			add(tc.after)
+
+			unread, err := repo.CountUnread(ctx, "p1", auth.Viewer{UserID: "alice"})
+			if err != nil {
+				t.Fatalf("непрочитанные: %v", err)
+			}
+			if unread != tc.unread {
+				t.Fatalf("ожидалось %d непрочитанных, а получили %d", tc.unread, unread)
+			}
+
+			at, err := repo.LastReadAt(ctx, "alice", "p1")
+			if err != nil {
+				t.Fatalf("время прочтения: %v", err)
+			}
+			if tc.hasRead != (at != nil) {
+				t.Fatalf("ожидалось прочтение %v, а получили %v", tc.hasRead, at)
+			}
+		})
+	}
+}
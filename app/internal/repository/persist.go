@@ -0,0 +1,764 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/models"
+)
+
+// FsyncPolicy когда журнал сбрасывается на диск.
+type FsyncPolicy string
+
+const (
+	// FsyncAlways после каждой записи: подтвержденная запись переживает падение машины.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncInterval раз в FsyncInterval: при падении машины теряется не больше интервала.
+	FsyncInterval FsyncPolicy = "interval"
+	// FsyncNever сброс на усмотрение ОС, переживает только падение процесса.
+	FsyncNever FsyncPolicy = "never"
+)
+
+func (p FsyncPolicy) IsValid() bool {
+	switch p {
+	case FsyncAlways, FsyncInterval, FsyncNever:
+		return true
+	}
+	return false
+}
+
+const (
+	snapshotFile = "snapshot.bin"
+	walFile      = "wal.log"
+
+	DefaultFsyncInterval   = time.Second
+	DefaultCompactInterval = 5 * time.Minute
+
+	// maxFrameSize больше - битый заголовок кадра, а не данные.
+	maxFrameSize = 1 << 30
+)
+
+var ErrCorruptSnapshot = errors.New("снимок хранилища поврежден")
+
+// PersistConfig сохранение хранилища в памяти на диск: снимок + журнал изменений.
+type PersistConfig struct {
+	// Dir каталог снимка и журнала, пустой - без сохранения.
+	Dir   string
+	Fsync FsyncPolicy
+	// FsyncInterval период сброса журнала для FsyncInterval.
+	FsyncInterval time.Duration
+	// CompactInterval как часто журнал сворачивается в снимок.
+	CompactInterval time.Duration
+	// OnError ошибки фоновой записи (fsync по таймеру, сворачивание, очистка по TTL):
+	// вернуть их некому. Ошибки записи изменений возвращает сам вызов репозитория.
+	OnError func(error)
+}
+
+// walBatch изменения одного вызова репозитория, Seq растет с каждой записью.
+type walBatch struct {
+	Seq uint64
+	Ops []walOp
+}
+
+// walOp новое значение ключа таблицы, Value == nil - ключ удален.
+type walOp struct {
+	Table string
+	Key   string
+	Value []byte
+}
+
+type tableKey struct {
+	table string
+	key   string
+}
+
+// memoryTable таблица хранилища, которую можно сохранить и восстановить по ключам.
+type memoryTable struct {
+	get  func(st *MemoryStorage, key string) (any, bool)
+	put  func(st *MemoryStorage, key string, raw []byte) error
+	del  func(st *MemoryStorage, key string)
+	keys func(st *MemoryStorage) []string
+}
+
+func mapTable[V any](field func(st *MemoryStorage) map[string]V) memoryTable {
+	return memoryTable{
+		get: func(st *MemoryStorage, key string) (any, bool) {
+			v, ok := field(st)[key]
+			return v, ok
+		},
+		put: func(st *MemoryStorage, key string, raw []byte) error {
+			var v V
+			if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&v); err != nil {
+				return err
+			}
+			field(st)[key] = v
+			return nil
+		},
+		del: func(st *MemoryStorage, key string) { delete(field(st), key) },
+		keys: func(st *MemoryStorage) []string {
+			keys := make([]string, 0, len(field(st)))
+			for k := range field(st) {
+				keys = append(keys, k)
+			}
+			return keys
+		},
+	}
+}
+
+// Таблицы хранилища. postOrder и reportOrder не сохраняются, а строятся заново по времени создания.
+const (
+	tableUsers          = "users"
+	tableUserCreated    = "user_created"
+	tablePosts          = "posts"
+	tablePostCreated    = "post_created"
+	tableComments       = "comments"
+	tableCommentCreated = "comment_created"
+	tableByPost         = "comments_by_post"
+	tableByParent       = "comments_by_parent"
+	tableTags           = "tags"
+	tablePostTags       = "post_tags"
+	tableTagPosts       = "tag_posts"
+	tableNotifications  = "notifications"
+	tableByRecipient    = "notifications_by_recipient"
+	tableMentions       = "mentions"
+	tableFollowing      = "following"
+	tableFollowers      = "followers"
+	tableBookmarks      = "bookmarks"
+	tablePostReads      = "post_reads"
+	tableReports        = "reports"
+	tableBans           = "bans"
+)
+
+var memoryTables = map[string]memoryTable{
+	tableUsers:          mapTable(func(st *MemoryStorage) map[string]*models.User { return st.users }),
+	tableUserCreated:    mapTable(func(st *MemoryStorage) map[string]time.Time { return st.userCreated }),
+	tablePosts:          mapTable(func(st *MemoryStorage) map[string]*models.Post { return st.posts }),
+	tablePostCreated:    mapTable(func(st *MemoryStorage) map[string]time.Time { return st.postCreated }),
+	tableComments:       mapTable(func(st *MemoryStorage) map[string]*models.Comment { return st.comments }),
+	tableCommentCreated: mapTable(func(st *MemoryStorage) map[string]time.Time { return st.commentCreated }),
+	tableByPost:         mapTable(func(st *MemoryStorage) map[string][]string { return st.byPost }),
+	tableByParent:       mapTable(func(st *MemoryStorage) map[string][]string { return st.byParent }),
+	tableTags:           mapTable(func(st *MemoryStorage) map[string]*models.Tag { return st.tags }),
+	tablePostTags:       mapTable(func(st *MemoryStorage) map[string][]string { return st.postTags }),
+	tableTagPosts:       mapTable(func(st *MemoryStorage) map[string][]string { return st.tagPosts }),
+	tableNotifications:  mapTable(func(st *MemoryStorage) map[string]*models.Notification { return st.notifications }),
+	tableByRecipient:    mapTable(func(st *MemoryStorage) map[string][]string { return st.byRecipient }),
+	tableMentions:       mapTable(func(st *MemoryStorage) map[string]*models.Mention { return st.mentions }),
+	tableFollowing:      mapTable(func(st *MemoryStorage) map[string][]string { return st.following }),
+	tableFollowers:      mapTable(func(st *MemoryStorage) map[string][]string { return st.followers }),
+	tableBookmarks:      mapTable(func(st *MemoryStorage) map[string][]string { return st.bookmarks }),
+	tablePostReads:      mapTable(func(st *MemoryStorage) map[string]map[string]time.Time { return st.postReads }),
+	tableReports:        mapTable(func(st *MemoryStorage) map[string]*models.Report { return st.reports }),
+	tableBans:           mapTable(func(st *MemoryStorage) map[string][]*models.Ban { return st.bans }),
+}
+
+// persister снимок и журнал изменений хранилища в каталоге.
+type persister struct {
+	cfg PersistConfig
+
+	// mu журнал и счетчики ниже.
+	mu      sync.Mutex
+	wal     *os.File
+	walSize int64
+	seq     uint64
+	// snapSeq последняя запись журнала, вошедшая в снимок.
+	snapSeq uint64
+	// needSnapshot журналу нельзя верить (оборванный кадр, ошибка fsync) или изменения
+	// не описать кадром: следующая запись пишет полный снимок вместо кадра.
+	needSnapshot bool
+	// failures счетчик ошибок записи, снимок снимает needSnapshot, только если
+	// после его сборки ошибок не было.
+	failures uint64
+	// pending собранные, но еще не записанные снимки; пока они есть, кадры
+	// журнала копируются в tail, чтобы перенести их в новый журнал.
+	pending int
+	tail    []walFrame
+
+	// compactMu снимки пишутся на диск по одному.
+	compactMu sync.Mutex
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// walFrame записанный кадр журнала.
+type walFrame struct {
+	seq  uint64
+	data []byte
+}
+
+// snapshot состояние хранилища на момент записи журнала Seq.
+type snapshot struct {
+	batch    walBatch
+	failures uint64
+}
+
+// openPersistence восстанавливает хранилище из снимка и журнала и начинает новый журнал.
+func (st *MemoryStorage) openPersistence(cfg PersistConfig) error {
+	if cfg.Fsync == "" {
+		cfg.Fsync = FsyncAlways
+	}
+	if !cfg.Fsync.IsValid() {
+		return fmt.Errorf("неверная политика fsync %q", cfg.Fsync)
+	}
+	if cfg.FsyncInterval <= 0 {
+		cfg.FsyncInterval = DefaultFsyncInterval
+	}
+	if cfg.CompactInterval <= 0 {
+		cfg.CompactInterval = DefaultCompactInterval
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return fmt.Errorf("каталог хранилища: %w", err)
+	}
+
+	p := &persister{cfg: cfg, stop: make(chan struct{})}
+
+	st.mu.Lock()
+	seq, err := st.replayLocked(filepath.Join(cfg.Dir, snapshotFile), 0, true)
+	if err == nil {
+		seq, err = st.replayLocked(filepath.Join(cfg.Dir, walFile), seq, false)
+	}
+	if err != nil {
+		st.mu.Unlock()
+		return err
+	}
+	st.rebuildOrderLocked()
+
+	p.seq = seq
+	st.persist = p
+	// Сразу сворачиваем: журнал с оборванным хвостом заменяется чистым.
+	snap, err := st.captureLocked()
+	st.mu.Unlock()
+	if err == nil {
+		err = p.writeSnapshot(snap)
+	}
+	if err != nil {
+		st.persist = nil
+		if p.wal != nil {
+			_ = p.wal.Close()
+		}
+		return err
+	}
+
+	p.done.Add(1)
+	go st.persistLoop()
+	return nil
+}
+
+// replayLocked применяет кадры файла с Seq больше after, возвращает последний Seq.
+// Оборванный или битый хвост журнала отбрасывается, битый снимок - ошибка.
+func (st *MemoryStorage) replayLocked(path string, after uint64, strict bool) (uint64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return after, nil
+	}
+	if err != nil {
+		return after, fmt.Errorf("открытие %s: %w", path, err)
+	}
+	defer f.Close()
+
+	seq := after
+	r := bufio.NewReader(f)
+	for {
+		batch, err := readFrame(r)
+		if errors.Is(err, io.EOF) {
+			return seq, nil
+		}
+		if err != nil {
+			if strict {
+				return seq, fmt.Errorf("%w: %v", ErrCorruptSnapshot, err)
+			}
+			return seq, nil
+		}
+		// Снимок применяется всегда, записи журнала - только новее снимка.
+		if !strict && batch.Seq <= seq {
+			continue
+		}
+		for _, op := range batch.Ops {
+			if err := st.applyLocked(op); err != nil {
+				return seq, fmt.Errorf("восстановление %s/%s: %w", op.Table, op.Key, err)
+			}
+		}
+		seq = batch.Seq
+	}
+}
+
+func (st *MemoryStorage) applyLocked(op walOp) error {
+	t, ok := memoryTables[op.Table]
+	if !ok {
+		return fmt.Errorf("неизвестная таблица")
+	}
+	if op.Value == nil {
+		t.del(st, op.Key)
+		return nil
+	}
+	return t.put(st, op.Key, op.Value)
+}
+
+// rebuildOrderLocked порядок постов и жалоб по времени создания.
+func (st *MemoryStorage) rebuildOrderLocked() {
+	st.postOrder = st.postOrder[:0]
+	for id := range st.posts {
+		st.postOrder = append(st.postOrder, id)
+	}
+	sort.Slice(st.postOrder, func(i, j int) bool {
+		a, b := st.postCreated[st.postOrder[i]], st.postCreated[st.postOrder[j]]
+		if a.Equal(b) {
+			return st.postOrder[i] < st.postOrder[j]
+		}
+		return a.Before(b)
+	})
+
+	st.reportOrder = st.reportOrder[:0]
+	for id := range st.reports {
+		st.reportOrder = append(st.reportOrder, id)
+	}
+	sort.Slice(st.reportOrder, func(i, j int) bool {
+		a, b := st.reports[st.reportOrder[i]].CreatedAt, st.reports[st.reportOrder[j]].CreatedAt
+		if a.Equal(b) {
+			return st.reportOrder[i] < st.reportOrder[j]
+		}
+		return a.Before(b)
+	})
+}
+
+// touch отмечает ключи таблицы измененными, они попадут в журнал при unlock.
+// Вызывается до изменения: в транзакции прежнее значение уходит в журнал отката.
+func (st *MemoryStorage) touch(table string, keys ...string) {
+	if st.tx != nil {
+		st.tx.remember(table, keys...)
+	}
+	if st.persist == nil {
+		return
+	}
+	if st.dirty == nil {
+		st.dirty = make(map[tableKey]struct{})
+	}
+	for _, k := range keys {
+		st.dirty[tableKey{table: table, key: k}] = struct{}{}
+	}
+}
+
+// touchAll изменений слишком много для журнала (очистка по TTL), при unlock пишется снимок.
+func (st *MemoryStorage) touchAll() {
+	if st.persist != nil {
+		st.dirtyAll = true
+	}
+}
+
+// unlock пишет изменения в журнал и отпускает блокировку записи. Ошибка записи
+// попадает в *err: изменение уже видно в памяти, но на диск не легло и не
+// подтверждается. Без err (или если там уже ошибка) она уходит в OnError.
+// Внутри транзакции ничего не делает: изменения пишутся одним кадром при ее завершении.
+func (st *MemoryStorage) unlock(ctx context.Context, err *error) {
+	if st.inTx(ctx) {
+		return
+	}
+	p := st.persist
+	snap, ferr := st.flushLocked()
+	st.mu.Unlock()
+	if snap != nil {
+		ferr = p.writeSnapshot(snap)
+	}
+	if ferr == nil {
+		return
+	}
+	if err != nil && *err == nil {
+		*err = ferr
+		return
+	}
+	if p != nil && p.cfg.OnError != nil {
+		p.cfg.OnError(ferr)
+	}
+}
+
+// flushLocked пишет изменения одним кадром журнала. Пока кадр не записан
+// (и не сброшен на диск при FsyncAlways), изменения остаются в dirty.
+// Если нужен полный снимок, он собирается здесь, а пишется вызывающим после
+// снятия блокировки хранилища.
+func (st *MemoryStorage) flushLocked() (*snapshot, error) {
+	p := st.persist
+	if p == nil || (len(st.dirty) == 0 && !st.dirtyAll) {
+		return nil, nil
+	}
+	p.mu.Lock()
+	needSnapshot := p.needSnapshot
+	p.mu.Unlock()
+	if st.dirtyAll || needSnapshot {
+		return st.captureLocked()
+	}
+
+	batch := walBatch{Seq: p.seq + 1, Ops: make([]walOp, 0, len(st.dirty))}
+	for tk := range st.dirty {
+		op, err := st.opLocked(tk.table, tk.key)
+		if err != nil {
+			return nil, err
+		}
+		batch.Ops = append(batch.Ops, op)
+	}
+	frame, err := encodeFrame(batch)
+	if err != nil {
+		return nil, fmt.Errorf("кодирование журнала хранилища: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.appendLocked(batch.Seq, frame); err != nil {
+		return nil, err
+	}
+	st.dirty = nil
+	return nil, nil
+}
+
+// appendLocked дописывает кадр в журнал. Неудачная запись обрезается обратно;
+// если и это не вышло или не удался fsync, журнал заменяется снимком.
+func (p *persister) appendLocked(seq uint64, frame []byte) error {
+	if _, err := p.wal.Write(frame); err != nil {
+		if terr := p.wal.Truncate(p.walSize); terr != nil {
+			p.failLocked()
+		}
+		return fmt.Errorf("запись журнала хранилища: %w", err)
+	}
+	if p.cfg.Fsync == FsyncAlways {
+		if err := p.wal.Sync(); err != nil {
+			p.failLocked()
+			return fmt.Errorf("fsync журнала хранилища: %w", err)
+		}
+	}
+	p.walSize += int64(len(frame))
+	p.seq = seq
+	if p.pending > 0 {
+		p.tail = append(p.tail, walFrame{seq: seq, data: frame})
+	}
+	return nil
+}
+
+// failLocked журналу больше нельзя верить до следующего снимка.
+func (p *persister) failLocked() {
+	p.needSnapshot = true
+	p.failures++
+}
+
+// opLocked текущее значение ключа для журнала.
+func (st *MemoryStorage) opLocked(table, key string) (walOp, error) {
+	op := walOp{Table: table, Key: key}
+	v, ok := memoryTables[table].get(st, key)
+	if !ok {
+		return op, nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return op, fmt.Errorf("кодирование %s/%s: %w", table, key, err)
+	}
+	op.Value = buf.Bytes()
+	return op, nil
+}
+
+// Compact сворачивает журнал в снимок.
+func (st *MemoryStorage) Compact() error {
+	st.mu.Lock()
+	p := st.persist
+	if p == nil {
+		st.mu.Unlock()
+		return nil
+	}
+	snap, err := st.flushLocked()
+	if err == nil && snap == nil {
+		snap, err = st.captureLocked()
+	}
+	st.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return p.writeSnapshot(snap)
+}
+
+// captureLocked собирает снимок под блокировкой хранилища, без записи на диск.
+// Изменения, которых еще нет в журнале, входят в снимок под новым Seq.
+func (st *MemoryStorage) captureLocked() (*snapshot, error) {
+	p := st.persist
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seq := p.seq
+	if len(st.dirty) > 0 || st.dirtyAll {
+		seq++
+	}
+	snap := &snapshot{batch: walBatch{Seq: seq}, failures: p.failures}
+	names := make([]string, 0, len(memoryTables))
+	for name := range memoryTables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, key := range memoryTables[name].keys(st) {
+			op, err := st.opLocked(name, key)
+			if err != nil {
+				return nil, err
+			}
+			snap.batch.Ops = append(snap.batch.Ops, op)
+		}
+	}
+
+	p.seq = seq
+	p.pending++
+	st.dirty, st.dirtyAll = nil, false
+	return snap, nil
+}
+
+// writeSnapshot пишет снимок во временный файл и подменяет им старый, затем начинает
+// журнал заново с кадров, записанных после сборки снимка. Блокировка хранилища
+// не нужна: запись идет параллельно, новые кадры попадают в tail.
+// Падение на любом шаге оставляет либо старый снимок с журналом, либо новый снимок:
+// записи журнала, уже вошедшие в снимок, пропускаются по Seq.
+func (p *persister) writeSnapshot(snap *snapshot) error {
+	p.compactMu.Lock()
+	defer p.compactMu.Unlock()
+
+	p.mu.Lock()
+	stale := snap.batch.Seq <= p.snapSeq && p.wal != nil
+	p.mu.Unlock()
+
+	var err error
+	if !stale {
+		err = writeFileAtomic(p.cfg.Dir, snapshotFile, func(w io.Writer) error {
+			return writeFrame(w, snap.batch)
+		})
+		if err != nil {
+			err = fmt.Errorf("снимок хранилища: %w", err)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending--
+	defer func() {
+		if p.pending == 0 {
+			p.tail = nil
+		}
+	}()
+	if stale {
+		return nil
+	}
+	if err == nil {
+		err = p.rotateLocked(snap.batch.Seq)
+	}
+	if err != nil {
+		p.failLocked()
+		return err
+	}
+	p.snapSeq = snap.batch.Seq
+	if p.failures == snap.failures {
+		p.needSnapshot = false
+	}
+	return nil
+}
+
+// rotateLocked заменяет журнал новым из кадров tail новее снимка seq.
+func (p *persister) rotateLocked(seq uint64) error {
+	tail := p.tail[:0]
+	var size int64
+	for _, f := range p.tail {
+		if f.seq > seq {
+			tail = append(tail, f)
+			size += int64(len(f.data))
+		}
+	}
+	p.tail = tail
+
+	if err := writeFileAtomic(p.cfg.Dir, walFile, func(w io.Writer) error {
+		for _, f := range tail {
+			if _, err := w.Write(f.data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("новый журнал хранилища: %w", err)
+	}
+	wal, err := os.OpenFile(filepath.Join(p.cfg.Dir, walFile), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("открытие журнала хранилища: %w", err)
+	}
+	if p.wal != nil {
+		_ = p.wal.Close()
+	}
+	p.wal, p.walSize = wal, size
+	return nil
+}
+
+// persistLoop периодический fsync журнала и сворачивание в снимок.
+func (st *MemoryStorage) persistLoop() {
+	p := st.persist
+	defer p.done.Done()
+
+	compact := time.NewTicker(p.cfg.CompactInterval)
+	defer compact.Stop()
+	var fsync <-chan time.Time
+	if p.cfg.Fsync == FsyncInterval {
+		t := time.NewTicker(p.cfg.FsyncInterval)
+		defer t.Stop()
+		fsync = t.C
+	}
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-fsync:
+			p.mu.Lock()
+			err := p.wal.Sync()
+			if err != nil {
+				p.failLocked()
+			}
+			p.mu.Unlock()
+			if err != nil && p.cfg.OnError != nil {
+				p.cfg.OnError(fmt.Errorf("fsync журнала хранилища: %w", err))
+			}
+		case <-compact.C:
+			if err := st.compactIfNeeded(p); err != nil && p.cfg.OnError != nil {
+				p.cfg.OnError(err)
+			}
+		}
+	}
+}
+
+// compactIfNeeded сворачивает журнал, если в нем есть записи новее снимка
+// или журналу нельзя верить.
+func (st *MemoryStorage) compactIfNeeded(p *persister) error {
+	st.mu.Lock()
+	p.mu.Lock()
+	needed := p.seq > p.snapSeq || p.needSnapshot || len(st.dirty) > 0 || st.dirtyAll
+	p.mu.Unlock()
+	if !needed {
+		st.mu.Unlock()
+		return nil
+	}
+	snap, err := st.captureLocked()
+	st.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return p.writeSnapshot(snap)
+}
+
+// Close сворачивает журнал в снимок и закрывает файлы, без сохранения ничего не делает.
+func (st *MemoryStorage) Close() error {
+	p := st.persist
+	if p == nil {
+		return nil
+	}
+	close(p.stop)
+	p.done.Wait()
+
+	err := st.compactIfNeeded(p)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	p.compactMu.Lock()
+	defer p.compactMu.Unlock()
+	if cerr := p.wal.Close(); err == nil {
+		err = cerr
+	}
+	st.persist = nil
+	return err
+}
+
+// writeFrame пишет кадр пакета одним Write: при падении кадр либо целый,
+// либо оборван и отбрасывается по crc.
+func writeFrame(w io.Writer, batch walBatch) error {
+	frame, err := encodeFrame(batch)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(frame)
+	return err
+}
+
+// encodeFrame кадр: длина, crc32 и gob пакета.
+func encodeFrame(batch walBatch) ([]byte, error) {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(batch); err != nil {
+		return nil, err
+	}
+	frame := make([]byte, 8, 8+payload.Len())
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(payload.Len()))
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+	return append(frame, payload.Bytes()...), nil
+}
+
+func readFrame(r io.Reader) (walBatch, error) {
+	var batch walBatch
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return batch, fmt.Errorf("оборванный заголовок кадра")
+		}
+		return batch, err
+	}
+	size := binary.LittleEndian.Uint32(header[0:4])
+	if size > maxFrameSize {
+		return batch, fmt.Errorf("размер кадра %d", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return batch, fmt.Errorf("оборванный кадр: %w", err)
+	}
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+		return batch, fmt.Errorf("crc кадра не совпадает")
+	}
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&batch); err != nil {
+		return batch, fmt.Errorf("разбор кадра: %w", err)
+	}
+	return batch, nil
+}
+
+// writeFileAtomic пишет файл через временный, fsync и rename, затем fsync каталога.
+func writeFileAtomic(dir, name string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(dir, name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	bw := bufio.NewWriter(tmp)
+	if err := write(bw); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+)
+
+// Тест на восстановление хранилища с диска.
+func TestMemoryStorage_Persistence(t *testing.T) {
+	tests := []struct {
+		name string
+		// restart как завершается первый процесс.
+		restart func(t *testing.T, st *MemoryStorage, dir string)
+	}{
+		{name: "Падение процесса: только журнал", restart: func(t *testing.T, st *MemoryStorage, dir string) {}},
+		{name: "Штатная остановка: снимок", restart: func(t *testing.T, st *MemoryStorage, dir string) {
+			if err := st.Close(); err != nil {
+				t.Fatalf("закрытие: %v", err)
+			}
+		}},
+		{name: "Оборванный хвост журнала", restart: func(t *testing.T, st *MemoryStorage, dir string) {
+			f, err := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				t.Fatalf("журнал: %v", err)
+			}
+			defer f.Close()
+			if _, err := f.Write([]byte{42, 0, 0, 0, 1, 2, 3}); err != nil {
+				t.Fatalf("запись мусора: %v", err)
+			}
+		}},
+		{name: "Свернутый журнал и новые записи", restart: func(t *testing.T, st *MemoryStorage, dir string) {
+			if err := st.Compact(); err != nil {
+				t.Fatalf("сворачивание: %v", err)
+			}
+			if err := NewMemoryFollowRepo(st).Follow(context.Background(), "carol", "bob"); err != nil {
+				t.Fatalf("подписка: %v", err)
+			}
+		}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			cfg := PersistConfig{Dir: dir, Fsync: FsyncNever}
+
+			st, err := NewMemoryStorage(WithTTL(0), WithPersistence(cfg))
+			if err != nil {
+				t.Fatalf("открытие: %v", err)
+			}
+			posts := NewMemoryPostRepo(st)
+			first, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: "bob"}, Title: "first", Body: "b"})
+			if err != nil {
+				t.Fatalf("при создинии поста: %v", err)
+			}
+			second, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: "bob"}, Title: "second", Body: "b"})
+			if err != nil {
+				t.Fatalf("при создинии поста: %v", err)
+			}
+			if _, err := posts.SetCommentsEnabled(ctx, first.ID, false); err != nil {
+				t.Fatalf("комментарии: %v", err)
+			}
+			comment, err := NewMemoryCommentRepo(st).Create(ctx, &models.Comment{PostID: first.ID, Author: &models.User{ID: "alice"}, Body: "c"})
+			if err != nil {
+				t.Fatalf("комментарий: %v", err)
+			}
+			if _, err := NewMemoryCommentRepo(st).Create(ctx, &models.Comment{PostID: first.ID, Author: &models.User{ID: "bob"}, Body: "r", ParentID: &comment.ID, Depth: 1}); err != nil {
+				t.Fatalf("ответ: %v", err)
+			}
+			if err := NewMemoryFollowRepo(st).Follow(ctx, "alice", "bob"); err != nil {
+				t.Fatalf("подписка: %v", err)
+			}
+			if _, err := NewMemoryBanRepo(st).Create(ctx, &models.Ban{User: &models.User{ID: "mallory"}, Reason: "спам"}); err != nil {
+				t.Fatalf("блокировка: %v", err)
+			}
+
+			tc.restart(t, st, dir)
+
+			restored, err := NewMemoryStorage(WithTTL(0), WithPersistence(cfg))
+			if err != nil {
+				t.Fatalf("восстановление: %v", err)
+			}
+			t.Cleanup(func() { _ = restored.Close() })
+
+			list, _, err := NewMemoryPostRepo(restored).List(ctx, 10, nil, auth.Viewer{})
+			if err != nil {
+				t.Fatalf("список постов: %v", err)
+			}
+			if len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+				t.Fatalf("ожидались два поста по порядку создания, а получили %d", len(list))
+			}
+			if list[0].CommentsEnabled {
+				t.Fatalf("ожидались выключенные комментарии")
+			}
+			if list[0].CreatedAt.IsZero() {
+				t.Fatalf("время создания поста потеряно")
+			}
+
+			got, err := NewMemoryCommentRepo(restored).GetByID(ctx, comment.ID)
+			if err != nil || got == nil {
+				t.Fatalf("комментарий не восстановлен: %v", err)
+			}
+			if got.ChildrenCount != 1 {
+				t.Fatalf("ожидался один ответ, а получили %d", got.ChildrenCount)
+			}
+			n, err := NewMemoryFollowRepo(restored).CountFollowers(ctx, "bob")
+			if err != nil {
+				t.Fatalf("подписчики: %v", err)
+			}
+			if n == 0 {
+				t.Fatalf("подписки не восстановлены")
+			}
+			ban, err := NewMemoryBanRepo(restored).Active(ctx, "mallory", time.Now())
+			if err != nil || ban == nil {
+				t.Fatalf("блокировка не восстановлена: %v", err)
+			}
+		})
+	}
+}
+
+// Тест на ошибку записи журнала: вызов возвращает ошибку, изменения не теряются
+// и уходят на диск со следующей записью.
+func TestMemoryStorage_WALFailure(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cfg := PersistConfig{Dir: dir, Fsync: FsyncAlways}
+
+	st, err := NewMemoryStorage(WithTTL(0), WithPersistence(cfg))
+	if err != nil {
+		t.Fatalf("открытие: %v", err)
+	}
+	posts := NewMemoryPostRepo(st)
+
+	// Закрытый файл: запись кадра и его обрезка не удаются.
+	_ = st.persist.wal.Close()
+	if _, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: "bob"}, Title: "first", Body: "b"}); err == nil {
+		t.Fatal("ожидалась ошибка записи журнала")
+	}
+	if len(st.dirty) == 0 {
+		t.Fatal("незаписанные изменения должны остаться в dirty")
+	}
+	if _, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: "bob"}, Title: "second", Body: "b"}); err != nil {
+		t.Fatalf("запись после ошибки: %v", err)
+	}
+	if _, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: "bob"}, Title: "third", Body: "b"}); err != nil {
+		t.Fatalf("запись в новый журнал: %v", err)
+	}
+
+	// Без Close: восстанавливаемся из снимка и нового журнала.
+	restored, err := NewMemoryStorage(WithTTL(0), WithPersistence(cfg))
+	if err != nil {
+		t.Fatalf("восстановление: %v", err)
+	}
+	t.Cleanup(func() { _ = restored.Close() })
+	list, _, err := NewMemoryPostRepo(restored).List(ctx, 10, nil, auth.Viewer{})
+	if err != nil {
+		t.Fatalf("список постов: %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("ожидались три поста, а получили %d", len(list))
+	}
+}
+
+// Тест на запись во время сворачивания: кадры после сборки снимка переносятся в новый журнал.
+func TestMemoryStorage_WriteDuringCompaction(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cfg := PersistConfig{Dir: dir, Fsync: FsyncNever}
+
+	st, err := NewMemoryStorage(WithTTL(0), WithPersistence(cfg))
+	if err != nil {
+		t.Fatalf("открытие: %v", err)
+	}
+	posts := NewMemoryPostRepo(st)
+	if _, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: "bob"}, Title: "first", Body: "b"}); err != nil {
+		t.Fatalf("пост: %v", err)
+	}
+
+	// Снимок собран, но еще не записан: следующая запись идет в старый журнал.
+	st.mu.Lock()
+	snap, err := st.captureLocked()
+	st.mu.Unlock()
+	if err != nil {
+		t.Fatalf("снимок: %v", err)
+	}
+	if _, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: "bob"}, Title: "second", Body: "b"}); err != nil {
+		t.Fatalf("пост: %v", err)
+	}
+	if err := st.persist.writeSnapshot(snap); err != nil {
+		t.Fatalf("запись снимка: %v", err)
+	}
+
+	restored, err := NewMemoryStorage(WithTTL(0), WithPersistence(cfg))
+	if err != nil {
+		t.Fatalf("восстановление: %v", err)
+	}
+	t.Cleanup(func() { _ = restored.Close() })
+	list, _, err := NewMemoryPostRepo(restored).List(ctx, 10, nil, auth.Viewer{})
+	if err != nil {
+		t.Fatalf("список постов: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("ожидались два поста, а получили %d", len(list))
+	}
+}
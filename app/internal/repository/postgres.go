@@ -5,65 +5,233 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/RoGogDBD/GQLGo/internal/auth"
 	"github.com/RoGogDBD/GQLGo/internal/models"
 	"github.com/RoGogDBD/GQLGo/internal/utils/repository"
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
 )
 
+// pgInvalidTextRepresentation код ошибки Postgres для значения не того типа.
+const pgInvalidTextRepresentation = "22P02"
+
 type (
 	PostgresUserRepo struct {
-		db *bun.DB
+		db      *bun.DB
+		replica *bun.DB
 	}
 
 	PostgresPostRepo struct {
-		db *bun.DB
+		db      *bun.DB
+		replica *bun.DB
 	}
 
 	PostgresCommentRepo struct {
+		db      *bun.DB
+		replica *bun.DB
+	}
+
+	PostgresTagRepo struct {
+		db      *bun.DB
+		replica *bun.DB
+	}
+
+	PostgresNotificationRepo struct {
+		db      *bun.DB
+		replica *bun.DB
+	}
+
+	PostgresFollowRepo struct {
+		db      *bun.DB
+		replica *bun.DB
+	}
+
+	PostgresBookmarkRepo struct {
+		db      *bun.DB
+		replica *bun.DB
+	}
+
+	PostgresReadStateRepo struct {
+		db      *bun.DB
+		replica *bun.DB
+	}
+
+	PostgresReportRepo struct {
+		db      *bun.DB
+		replica *bun.DB
+	}
+
+	PostgresBanRepo struct {
 		db *bun.DB
 	}
+
+	PostgresAuditRepo struct {
+		db      *bun.DB
+		replica *bun.DB
+	}
 )
 
 type commentInsertRow struct {
 	bun.BaseModel `bun:"table:comments"`
 
-	ID            string    `bun:"id"`
-	PostID        string    `bun:"post_id"`
-	AuthorID      string    `bun:"author_id"`
-	ParentID      *string   `bun:"parent_id"`
-	Body          string    `bun:"body"`
-	Depth         int       `bun:"depth"`
-	ChildrenCount int       `bun:"children_count"`
-	CreatedAt     time.Time `bun:"created_at"`
-	UpdatedAt     time.Time `bun:"updated_at"`
+	ID              string                 `bun:"id"`
+	PostID          string                 `bun:"post_id"`
+	AuthorID        string                 `bun:"author_id"`
+	ParentID        *string                `bun:"parent_id"`
+	Body            string                 `bun:"body"`
+	BodyHTML        string                 `bun:"body_html"`
+	Depth           int                    `bun:"depth"`
+	ChildrenCount   int                    `bun:"children_count"`
+	ModerationState models.ModerationState `bun:"moderation_state"`
+	CreatedAt       time.Time              `bun:"created_at"`
+	UpdatedAt       time.Time              `bun:"updated_at"`
+}
+
+// PostgresOption настройка репозиториев Postgres.
+type PostgresOption func(*postgresOptions)
+
+type postgresOptions struct {
+	replica *bun.DB
+}
+
+// WithReplica списки и счетчики вне транзакции читаются из реплики.
+// Поиск по id, проверки перед записью и все запросы мутаций идут в основную базу.
+func WithReplica(replica *bun.DB) PostgresOption {
+	return func(o *postgresOptions) { o.replica = replica }
+}
+
+func postgresOptionsOf(opts []PostgresOption) postgresOptions {
+	var o postgresOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func NewPostgresUserRepo(db *bun.DB, opts ...PostgresOption) (*PostgresUserRepo, error) {
+	return &PostgresUserRepo{db: db, replica: postgresOptionsOf(opts).replica}, nil
+}
+func NewPostgresPostRepo(db *bun.DB, opts ...PostgresOption) (*PostgresPostRepo, error) {
+	return &PostgresPostRepo{db: db, replica: postgresOptionsOf(opts).replica}, nil
+}
+func NewPostgresCommentRepo(db *bun.DB, opts ...PostgresOption) (*PostgresCommentRepo, error) {
+	return &PostgresCommentRepo{db: db, replica: postgresOptionsOf(opts).replica}, nil
+}
+func NewPostgresTagRepo(db *bun.DB, opts ...PostgresOption) (*PostgresTagRepo, error) {
+	return &PostgresTagRepo{db: db, replica: postgresOptionsOf(opts).replica}, nil
+}
+func NewPostgresNotificationRepo(db *bun.DB, opts ...PostgresOption) (*PostgresNotificationRepo, error) {
+	return &PostgresNotificationRepo{db: db, replica: postgresOptionsOf(opts).replica}, nil
+}
+func NewPostgresFollowRepo(db *bun.DB, opts ...PostgresOption) (*PostgresFollowRepo, error) {
+	return &PostgresFollowRepo{db: db, replica: postgresOptionsOf(opts).replica}, nil
+}
+func NewPostgresBookmarkRepo(db *bun.DB, opts ...PostgresOption) (*PostgresBookmarkRepo, error) {
+	return &PostgresBookmarkRepo{db: db, replica: postgresOptionsOf(opts).replica}, nil
+}
+func NewPostgresReadStateRepo(db *bun.DB, opts ...PostgresOption) (*PostgresReadStateRepo, error) {
+	return &PostgresReadStateRepo{db: db, replica: postgresOptionsOf(opts).replica}, nil
+}
+func NewPostgresReportRepo(db *bun.DB, opts ...PostgresOption) (*PostgresReportRepo, error) {
+	return &PostgresReportRepo{db: db, replica: postgresOptionsOf(opts).replica}, nil
+}
+func NewPostgresBanRepo(db *bun.DB) (*PostgresBanRepo, error) {
+	return &PostgresBanRepo{db: db}, nil
+}
+func NewPostgresAuditRepo(db *bun.DB, opts ...PostgresOption) (*PostgresAuditRepo, error) {
+	return &PostgresAuditRepo{db: db, replica: postgresOptionsOf(opts).replica}, nil
+}
+
+// selectPosts базовый запрос постов вместе с автором.
+func selectPosts(db bun.IDB) *bun.SelectQuery {
+	return db.NewSelect().
+		TableExpr("posts AS p").
+		Column("p.id", "p.title", "p.body", "p.body_html", "p.comments_enabled", "p.status", "p.moderation_state", "p.publish_at", "p.published_at", "p.created_at").
+		ColumnExpr("u.id AS author__id, u.username AS author__username").
+		Join("JOIN users AS u ON u.id = p.author_id")
+}
+
+// applyPostVisibility скрывает чужие неопубликованные посты и не прошедшие модерацию.
+func applyPostVisibility(query *bun.SelectQuery, viewer auth.Viewer) {
+	if !viewer.IsModerator() {
+		applyModerationVisibility(query, "p", viewer)
+	}
+	if viewer.UserID == "" {
+		query.Where("p.status = ?", models.PostStatusPublished)
+		return
+	}
+	query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("p.status = ?", models.PostStatusPublished).
+			WhereOr("CAST(p.author_id AS TEXT) = ?", viewer.UserID)
+	})
 }
 
-func NewPostgresUserRepo(db *bun.DB) (*PostgresUserRepo, error) {
-	return &PostgresUserRepo{db: db}, nil
+// applyModerationVisibility оставляет одобренный контент и свой контент на модерации.
+func applyModerationVisibility(query *bun.SelectQuery, alias string, viewer auth.Viewer) {
+	query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("?.moderation_state = ?", bun.Ident(alias), models.ModerationStateVisible).
+			WhereOr("?.moderation_state = ? AND CAST(?.author_id AS TEXT) = ?",
+				bun.Ident(alias), models.ModerationStatePending, bun.Ident(alias), viewer.UserID)
+	})
 }
-func NewPostgresPostRepo(db *bun.DB) (*PostgresPostRepo, error) {
-	return &PostgresPostRepo{db: db}, nil
+
+// checkCursor ErrInvalidCursor, если курсор after не указывает на строку table.
+// after подставляется последним параметром условия where.
+func checkCursor(ctx context.Context, db bun.IDB, after *string, table, where string, args ...any) error {
+	if after == nil || *after == "" {
+		return nil
+	}
+	ok, err := db.NewSelect().
+		Table(table).
+		Where(where, append(args, *after)...).
+		Exists(ctx)
+	if err != nil {
+		// Курсор не того типа, например не uuid.
+		var pgErr pgdriver.Error
+		if errors.As(err, &pgErr) && pgErr.Field('C') == pgInvalidTextRepresentation {
+			return ErrInvalidCursor
+		}
+		return fmt.Errorf("проверка курсора: %w", err)
+	}
+	if !ok {
+		return ErrInvalidCursor
+	}
+	return nil
 }
-func NewPostgresCommentRepo(db *bun.DB) (*PostgresCommentRepo, error) {
-	return &PostgresCommentRepo{db: db}, nil
+
+// initPostComments заполняет пустые связи комментариев.
+func initPostComments(posts ...*models.Post) {
+	for _, p := range posts {
+		if p.Comments == nil {
+			p.Comments = &models.CommentConnection{
+				Edges:      []*models.CommentEdge{},
+				PageInfo:   &models.PageInfo{HasNextPage: false, EndCursor: nil},
+				TotalCount: 0,
+			}
+		}
+	}
 }
 
 // ============================== USER REPO ==============================
 
 // GetByID возвращает пользователя по id.
 func (r *PostgresUserRepo) GetByID(ctx context.Context, id string) (*models.User, error) {
+	if id == "" {
+		return nil, ErrEmptyID
+	}
 	u := new(models.User)
 
-	err := r.db.NewSelect().
+	err := conn(ctx, r.db).NewSelect().
 		Model(u).
 		Where("id = ?", id).
 		Scan(ctx)
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
+		return nil, ErrNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("получение пользователя: %w", err)
@@ -79,11 +247,14 @@ func (r *PostgresUserRepo) List(ctx context.Context, first int32, after *string)
 
 	users := make([]*models.User, 0, first)
 
-	query := r.db.NewSelect().
+	query := readConn(ctx, r.db, r.replica).NewSelect().
 		Model(&users).
 		Order("id ASC").
 		Limit(int(first))
 
+	if err := checkCursor(ctx, readConn(ctx, r.db, r.replica), after, "users", "id = ?"); err != nil {
+		return nil, nil, err
+	}
 	repository.ApplyAfterByID(query, after, "id")
 	if err := query.Scan(ctx); err != nil {
 		return nil, nil, fmt.Errorf("список юзеров: %w", err)
@@ -92,47 +263,101 @@ func (r *PostgresUserRepo) List(ctx context.Context, first int32, after *string)
 	return users, repository.LastID(users, func(u *models.User) string { return u.ID }), nil
 }
 
+// GetByUsernames возвращает пользователей по именам без учета регистра.
+func (r *PostgresUserRepo) GetByUsernames(ctx context.Context, usernames []string) ([]*models.User, error) {
+	users := make([]*models.User, 0, len(usernames))
+	if len(usernames) == 0 {
+		return users, nil
+	}
+
+	lowered := make([]string, 0, len(usernames))
+	for _, name := range usernames {
+		lowered = append(lowered, strings.ToLower(name))
+	}
+
+	err := conn(ctx, r.db).NewSelect().
+		Model(&users).
+		Where("lower(username) IN (?)", bun.In(lowered)).
+		Order("id ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("пользователи по именам: %w", err)
+	}
+	return users, nil
+}
+
 // ============================== POST REPO ==============================
 
 // GetByID возвращает пост по id.
 func (r *PostgresPostRepo) GetByID(ctx context.Context, id string) (*models.Post, error) {
+	if id == "" {
+		return nil, ErrEmptyID
+	}
 	p := new(models.Post)
 	p.Author = &models.User{}
 
-	err := r.db.NewSelect().
-		TableExpr("posts AS p").
-		Column("p.id", "p.title", "p.body", "p.comments_enabled").
-		ColumnExpr("u.id AS author__id, u.username AS author__username").
-		Join("JOIN users AS u ON u.id = p.author_id").
+	err := selectPosts(conn(ctx, r.db)).
 		Where("p.id = ?", id).
-		Scan(ctx)
+		Scan(ctx, p)
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
+		return nil, ErrNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("получение поста: %w", err)
 	}
-	p.Comments = &models.CommentConnection{
-		Edges:      []*models.CommentEdge{},
-		PageInfo:   &models.PageInfo{HasNextPage: false, EndCursor: nil},
-		TotalCount: 0,
+	initPostComments(p)
+	return p, nil
+}
+
+// GetForUpdate возвращает пост по id и блокирует его строку до конца транзакции.
+func (r *PostgresPostRepo) GetForUpdate(ctx context.Context, id string) (*models.Post, error) {
+	if id == "" {
+		return nil, ErrEmptyID
+	}
+	p := new(models.Post)
+	p.Author = &models.User{}
+
+	err := selectPosts(conn(ctx, r.db)).
+		Where("p.id = ?", id).
+		For("UPDATE OF p").
+		Scan(ctx, p)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("блокировка поста: %w", err)
 	}
+	initPostComments(p)
 	return p, nil
 }
 
-func (r *PostgresPostRepo) Create(ctx context.Context, in models.CreatePostInput) (*models.Post, error) {
-	commentsEnabled := true
-	if in.CommentsEnabled != nil {
-		commentsEnabled = *in.CommentsEnabled
+// Create создает пост.
+func (r *PostgresPostRepo) Create(ctx context.Context, p *models.Post) (*models.Post, error) {
+	if p == nil {
+		return nil, ErrNilEntity
+	}
+	if p.Author == nil || p.Author.ID == "" {
+		return nil, ErrEmptyID
+	}
+	status := p.Status
+	if status == "" {
+		status = models.PostStatusPublished
+	}
+	state := p.ModerationState
+	if state == "" {
+		state = models.ModerationStateVisible
 	}
 
 	id := uuid.NewString()
+	// Время создания задает приложение, как и для комментариев: по нему сортируется список.
+	now := time.Now()
 
-	_, err := r.db.NewRaw(`
-		INSERT INTO posts (id, title, body, comments_enabled, author_id)
-		VALUES (?, ?, ?, ?, ?)
-	`, id, in.Title, in.Body, commentsEnabled, in.AuthorID).Exec(ctx)
+	_, err := conn(ctx, r.db).NewRaw(`
+		INSERT INTO posts (id, title, body, body_html, comments_enabled, author_id, status, moderation_state, publish_at, published_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, p.Title, p.Body, p.BodyHTML, p.CommentsEnabled, p.Author.ID, status, state, p.PublishAt, p.PublishedAt, now, now).Exec(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("создание поста: %w", err)
 	}
@@ -140,37 +365,30 @@ func (r *PostgresPostRepo) Create(ctx context.Context, in models.CreatePostInput
 	return r.GetByID(ctx, id)
 }
 
-// List возвращает список постов с пагинацией.
-func (r *PostgresPostRepo) List(ctx context.Context, first int32, after *string) ([]*models.Post, *string, error) {
+// List возвращает список постов в порядке создания с пагинацией.
+func (r *PostgresPostRepo) List(ctx context.Context, first int32, after *string, viewer auth.Viewer) ([]*models.Post, *string, error) {
 	if first <= 0 {
 		first = DefaultPageSize
 	}
 
 	posts := make([]*models.Post, 0, first)
 
-	query := r.db.NewSelect().
-		TableExpr("posts AS p").
-		Column("p.id", "p.title", "p.body", "p.comments_enabled").
-		ColumnExpr("u.id AS author__id, u.username AS author__username").
-		Join("JOIN users AS u ON u.id = p.author_id").
-		Order("p.id ASC").
+	query := selectPosts(readConn(ctx, r.db, r.replica)).
+		Order("p.created_at ASC", "p.id ASC").
 		Limit(int(first))
 
-	repository.ApplyAfterByID(query, after, "p.id")
+	applyPostVisibility(query, viewer)
+	if err := checkCursor(ctx, readConn(ctx, r.db, r.replica), after, "posts", "id = ?"); err != nil {
+		return nil, nil, err
+	}
+	if after != nil && *after != "" {
+		query.Where("(p.created_at, p.id) > (SELECT created_at, id FROM posts WHERE id = ?)", *after)
+	}
 
 	if err := query.Scan(ctx, &posts); err != nil {
 		return nil, nil, fmt.Errorf("список постов: %w", err)
 	}
-
-	for _, p := range posts {
-		if p.Comments == nil {
-			p.Comments = &models.CommentConnection{
-				Edges:      []*models.CommentEdge{},
-				PageInfo:   &models.PageInfo{HasNextPage: false, EndCursor: nil},
-				TotalCount: 0,
-			}
-		}
-	}
+	initPostComments(posts...)
 
 	return posts, repository.LastID(posts, func(p *models.Post) string { return p.ID }), nil
 }
@@ -178,13 +396,13 @@ func (r *PostgresPostRepo) List(ctx context.Context, first int32, after *string)
 // SetCommentsEnabled включает или выключает комментарии для поста.
 func (r *PostgresPostRepo) SetCommentsEnabled(ctx context.Context, postID string, enabled bool) (*models.Post, error) {
 	if postID == "" {
-		return nil, fmt.Errorf("требуется id поста")
+		return nil, ErrEmptyID
 	}
 
-	res, err := r.db.NewUpdate().
+	res, err := conn(ctx, r.db).NewUpdate().
 		Table("posts").
 		Set("comments_enabled = ?", enabled).
-		Set("updated_at = now()").
+		Set("updated_at = CURRENT_TIMESTAMP").
 		Where("id = ?", postID).
 		Exec(ctx)
 	if err != nil {
@@ -193,30 +411,201 @@ func (r *PostgresPostRepo) SetCommentsEnabled(ctx context.Context, postID string
 
 	if res != nil {
 		if rows, err := res.RowsAffected(); err == nil && rows == 0 {
-			return nil, nil
+			return nil, ErrNotFound
+		}
+	}
+
+	return r.GetByID(ctx, postID)
+}
+
+// UpdateStatus переводит пост в новый статус, если текущий статус из from.
+func (r *PostgresPostRepo) UpdateStatus(ctx context.Context, postID string, from []models.PostStatus, to models.PostStatus, at time.Time) (*models.Post, error) {
+	if postID == "" {
+		return nil, ErrEmptyID
+	}
+
+	query := conn(ctx, r.db).NewUpdate().
+		Table("posts").
+		Set("status = ?", to).
+		Set("updated_at = ?", at).
+		Where("id = ?", postID).
+		Where("status IN (?)", bun.In(from))
+	if to == models.PostStatusPublished {
+		query.Set("published_at = ?", at)
+	}
+
+	res, err := query.Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("обновление статуса: %w", err)
+	}
+
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		if _, err := r.GetByID(ctx, postID); err != nil {
+			return nil, err
 		}
+		return nil, ErrStatusConflict
+	}
+
+	return r.GetByID(ctx, postID)
+}
+
+// PublishDue публикует отложенные посты, время которых пришло.
+// SKIP LOCKED не дает нескольким репликам опубликовать один пост дважды.
+func (r *PostgresPostRepo) PublishDue(ctx context.Context, now time.Time, limit int) ([]*models.Post, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	var ids []string
+	err := conn(ctx, r.db).NewRaw(`
+		WITH due AS (
+			SELECT id FROM posts
+			WHERE status = ? AND publish_at <= ?
+			ORDER BY publish_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE posts AS p
+		SET status = ?, published_at = ?, updated_at = ?
+		FROM due
+		WHERE p.id = due.id
+		RETURNING p.id
+	`, models.PostStatusScheduled, now, limit, models.PostStatusPublished, now, now).Scan(ctx, &ids)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("публикация отложенных постов: %w", err)
 	}
+	if len(ids) == 0 {
+		return []*models.Post{}, nil
+	}
+
+	posts := make([]*models.Post, 0, len(ids))
+	err = selectPosts(conn(ctx, r.db)).
+		Where("p.id IN (?)", bun.In(ids)).
+		Order("p.publish_at ASC").
+		Scan(ctx, &posts)
+	if err != nil {
+		return nil, fmt.Errorf("опубликованные посты: %w", err)
+	}
+	initPostComments(posts...)
+	return posts, nil
+}
 
+// SetModerationState скрывает пост или возвращает его.
+func (r *PostgresPostRepo) SetModerationState(ctx context.Context, postID string, state models.ModerationState) (*models.Post, error) {
+	if postID == "" {
+		return nil, ErrEmptyID
+	}
+	res, err := conn(ctx, r.db).NewUpdate().
+		Table("posts").
+		Set("moderation_state = ?", state).
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("id = ?", postID).
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("модерация поста: %w", err)
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return nil, ErrNotFound
+	}
 	return r.GetByID(ctx, postID)
 }
 
+// HideByAuthor скрывает все посты автора.
+func (r *PostgresPostRepo) HideByAuthor(ctx context.Context, authorID string) (int, error) {
+	return hideByAuthor(ctx, conn(ctx, r.db), "posts", authorID)
+}
+
+// hideByAuthor переводит в HIDDEN весь контент автора в таблице table.
+func hideByAuthor(ctx context.Context, db bun.IDB, table, authorID string) (int, error) {
+	res, err := db.NewUpdate().
+		Table(table).
+		Set("moderation_state = ?", models.ModerationStateHidden).
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("author_id = ?", authorID).
+		Where("moderation_state <> ?", models.ModerationStateHidden).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("скрытие контента автора (%s): %w", table, err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("скрытие контента автора (%s): %w", table, err)
+	}
+	return int(rows), nil
+}
+
 // ============================== COMMENT REPO ==============================
 
+// selectComments базовый запрос комментариев вместе с автором.
+func selectComments(db bun.IDB) *bun.SelectQuery {
+	return db.NewSelect().
+		TableExpr("comments AS c").
+		Column(
+			"c.id",
+			"c.post_id",
+			"c.parent_id",
+			"c.body",
+			"c.body_html",
+			"c.depth",
+			"c.children_count",
+			"c.moderation_state",
+			"c.created_at",
+		).
+		ColumnExpr("u.id AS author__id, u.username AS author__username").
+		Join("JOIN users AS u ON u.id = c.author_id")
+}
+
+// initCommentChildren заполняет пустые связи дочерних комментариев.
+func initCommentChildren(comments ...*models.Comment) {
+	for _, c := range comments {
+		if c.Children == nil {
+			c.Children = &models.CommentConnection{
+				Edges:      []*models.CommentEdge{},
+				PageInfo:   &models.PageInfo{HasNextPage: false, EndCursor: nil},
+				TotalCount: 0,
+			}
+		}
+	}
+}
+
+// GetByID возвращает комментарий по id.
+func (r *PostgresCommentRepo) GetByID(ctx context.Context, id string) (*models.Comment, error) {
+	if id == "" {
+		return nil, ErrEmptyID
+	}
+	c := new(models.Comment)
+
+	err := selectComments(conn(ctx, r.db)).
+		Where("c.id = ?", id).
+		Scan(ctx, c)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("получение комментария: %w", err)
+	}
+	initCommentChildren(c)
+	return c, nil
+}
+
 // GetMeta возвращает минимальные данные о комментарии.
 func (r *PostgresCommentRepo) GetMeta(ctx context.Context, id string) (string, int, error) {
+	if id == "" {
+		return "", 0, ErrEmptyID
+	}
 	var meta struct {
 		PostID string `bun:"post_id"`
 		Depth  int    `bun:"depth"`
 	}
 
-	err := r.db.NewSelect().
+	err := conn(ctx, r.db).NewSelect().
 		Table("comments").
 		Column("post_id", "depth").
 		Where("id = ?", id).
 		Scan(ctx, &meta)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", 0, sql.ErrNoRows
+			return "", 0, ErrNotFound
 		}
 		return "", 0, fmt.Errorf("получение комментария: %w", err)
 	}
@@ -224,12 +613,23 @@ func (r *PostgresCommentRepo) GetMeta(ctx context.Context, id string) (string, i
 	return meta.PostID, meta.Depth, nil
 }
 
-// Create создает комментарий и может обновить счетчик.
-func (r *PostgresCommentRepo) Create(ctx context.Context, postID, authorID string, parentID *string, body string, depth int) (*models.Comment, error) {
+// Create создает комментарий; счетчик родителя растет только для видимого ответа.
+func (r *PostgresCommentRepo) Create(ctx context.Context, c *models.Comment) (*models.Comment, error) {
+	if c == nil {
+		return nil, ErrNilEntity
+	}
+	if c.PostID == "" || c.Author == nil || c.Author.ID == "" {
+		return nil, ErrEmptyID
+	}
 	id := uuid.NewString()
 	now := time.Now()
+	postID, authorID, parentID, body, depth := c.PostID, c.Author.ID, c.ParentID, c.Body, int(c.Depth)
+	state := c.ModerationState
+	if state == "" {
+		state = models.ModerationStateVisible
+	}
 
-	tx, err := r.db.BeginTx(ctx, nil)
+	tx, err := conn(ctx, r.db).BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("begin tx: %w", err)
 	}
@@ -240,15 +640,17 @@ func (r *PostgresCommentRepo) Create(ctx context.Context, postID, authorID strin
 	}()
 
 	row := &commentInsertRow{
-		ID:            id,
-		PostID:        postID,
-		AuthorID:      authorID,
-		ParentID:      parentID,
-		Body:          body,
-		Depth:         depth,
-		ChildrenCount: 0,
-		CreatedAt:     now,
-		UpdatedAt:     now,
+		ID:              id,
+		PostID:          postID,
+		AuthorID:        authorID,
+		ParentID:        parentID,
+		Body:            body,
+		BodyHTML:        c.BodyHTML,
+		Depth:           depth,
+		ChildrenCount:   0,
+		ModerationState: state,
+		CreatedAt:       now,
+		UpdatedAt:       now,
 	}
 	_, err = tx.NewInsert().
 		Model(row).
@@ -257,7 +659,7 @@ func (r *PostgresCommentRepo) Create(ctx context.Context, postID, authorID strin
 		return nil, fmt.Errorf("создание комментария: %w", err)
 	}
 
-	if parentID != nil && *parentID != "" {
+	if parentID != nil && *parentID != "" && state == models.ModerationStateVisible {
 		_, err = tx.NewUpdate().
 			Table("comments").
 			Set("children_count = children_count + 1").
@@ -274,13 +676,15 @@ func (r *PostgresCommentRepo) Create(ctx context.Context, postID, authorID strin
 	}
 
 	return &models.Comment{
-		ID:            id,
-		PostID:        postID,
-		Author:        &models.User{ID: authorID},
-		Body:          body,
-		ParentID:      parentID,
-		Depth:         int32(depth),
-		ChildrenCount: 0,
+		ID:              id,
+		PostID:          postID,
+		Author:          &models.User{ID: authorID},
+		Body:            body,
+		BodyHTML:        c.BodyHTML,
+		ParentID:        parentID,
+		Depth:           int32(depth),
+		ChildrenCount:   0,
+		ModerationState: state,
 		Children: &models.CommentConnection{
 			Edges:      []*models.CommentEdge{},
 			PageInfo:   &models.PageInfo{HasNextPage: false, EndCursor: nil},
@@ -291,9 +695,9 @@ func (r *PostgresCommentRepo) Create(ctx context.Context, postID, authorID strin
 }
 
 // ListByParent список комментариев для поста и род с пагинацией и сортировкой.
-func (r *PostgresCommentRepo) ListByParent(ctx context.Context, postID string, parentID *string, first int32, after *string, order models.CommentOrder) ([]*models.Comment, *string, error) {
+func (r *PostgresCommentRepo) ListByParent(ctx context.Context, postID string, parentID *string, first int32, after *string, order models.CommentOrder, viewer auth.Viewer) ([]*models.Comment, *string, error) {
 	if postID == "" {
-		return nil, nil, fmt.Errorf("требуется id поста")
+		return nil, nil, ErrEmptyID
 	}
 	if first <= 0 {
 		first = DefaultPageSize
@@ -304,19 +708,7 @@ func (r *PostgresCommentRepo) ListByParent(ctx context.Context, postID string, p
 
 	comments := make([]*models.Comment, 0, first)
 
-	query := r.db.NewSelect().
-		TableExpr("comments AS c").
-		Column(
-			"c.id",
-			"c.post_id",
-			"c.parent_id",
-			"c.body",
-			"c.depth",
-			"c.children_count",
-			"c.created_at",
-		).
-		ColumnExpr("u.id AS author__id, u.username AS author__username").
-		Join("JOIN users AS u ON u.id = c.author_id").
+	query := selectComments(readConn(ctx, r.db, r.replica)).
 		Where("c.post_id = ?", postID).
 		Limit(int(first))
 
@@ -325,7 +717,13 @@ func (r *PostgresCommentRepo) ListByParent(ctx context.Context, postID string, p
 	} else {
 		query.Where("c.parent_id = ?", *parentID)
 	}
+	if !viewer.IsModerator() {
+		applyModerationVisibility(query, "c", viewer)
+	}
 
+	if err := checkCursor(ctx, readConn(ctx, r.db, r.replica), after, "comments", "id = ?"); err != nil {
+		return nil, nil, err
+	}
 	if after != nil && *after != "" {
 		switch order {
 		case models.CommentOrderNewest:
@@ -348,16 +746,928 @@ func (r *PostgresCommentRepo) ListByParent(ctx context.Context, postID string, p
 	if err := query.Scan(ctx, &comments); err != nil {
 		return nil, nil, fmt.Errorf("список комментариев: %w", err)
 	}
+	initCommentChildren(comments...)
 
-	for _, c := range comments {
-		if c.Children == nil {
-			c.Children = &models.CommentConnection{
-				Edges:      []*models.CommentEdge{},
-				PageInfo:   &models.PageInfo{HasNextPage: false, EndCursor: nil},
-				TotalCount: 0,
+	return comments, repository.LastID(comments, func(c *models.Comment) string { return c.ID }), nil
+}
+
+// SetModerationState скрывает комментарий или возвращает его и пересчитывает ответы родителя.
+func (r *PostgresCommentRepo) SetModerationState(ctx context.Context, id string, state models.ModerationState) (*models.Comment, error) {
+	if id == "" {
+		return nil, ErrEmptyID
+	}
+	err := conn(ctx, r.db).RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewUpdate().
+			Table("comments").
+			Set("moderation_state = ?", state).
+			Set("updated_at = CURRENT_TIMESTAMP").
+			Where("id = ?", id).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("модерация комментария: %w", err)
+		}
+		if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+			return ErrNotFound
+		}
+		return recountChildren(ctx, tx, "id = ?", id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// HideByAuthor скрывает все комментарии автора и пересчитывает ответы их родителей.
+func (r *PostgresCommentRepo) HideByAuthor(ctx context.Context, authorID string) (int, error) {
+	var n int
+	err := conn(ctx, r.db).RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var err error
+		if n, err = hideByAuthor(ctx, tx, "comments", authorID); err != nil {
+			return err
+		}
+		return recountChildren(ctx, tx, "author_id = ?", authorID)
+	})
+	return n, err
+}
+
+// recountChildren пересчитывает children_count у родителей комментариев из where:
+// считаются только видимые ответы.
+func recountChildren(ctx context.Context, db bun.IDB, where string, args ...any) error {
+	_, err := db.NewUpdate().
+		Table("comments").
+		Set("children_count = (SELECT count(*) FROM comments AS ch WHERE ch.parent_id = comments.id AND ch.moderation_state = ?)", models.ModerationStateVisible).
+		Where("id IN (SELECT parent_id FROM comments WHERE "+where+")", args...).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("пересчет ответов: %w", err)
+	}
+	return nil
+}
+
+// ============================== TAG REPO ==============================
+
+// publicTagPosts посты тега t, видимые всем: опубликованные и не на модерации.
+const publicTagPosts = `FROM post_tags AS c JOIN posts AS cp ON cp.id = c.post_id
+	WHERE c.tag_id = t.id AND cp.status = ? AND cp.moderation_state = ?`
+
+// selectTags базовый запрос тегов со счетчиком видимых всем постов.
+func selectTags(db bun.IDB) *bun.SelectQuery {
+	return db.NewSelect().
+		TableExpr("tags AS t").
+		Column("t.id", "t.name", "t.slug").
+		ColumnExpr("(SELECT count(*) "+publicTagPosts+") AS posts_count", models.PostStatusPublished, models.ModerationStateVisible)
+}
+
+// SetPostTags заменяет набор тегов поста, создавая недостающие теги.
+func (r *PostgresTagRepo) SetPostTags(ctx context.Context, postID string, tags []*models.Tag) error {
+	if postID == "" {
+		return fmt.Errorf("требуется id поста")
+	}
+
+	slugs := make([]string, 0, len(tags))
+	err := conn(ctx, r.db).RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, t := range tags {
+			if t == nil || t.Slug == "" {
+				continue
 			}
+			_, err := tx.NewRaw(`
+				INSERT INTO tags (id, name, slug)
+				VALUES (?, ?, ?)
+				ON CONFLICT (slug) DO NOTHING
+			`, uuid.NewString(), t.Name, t.Slug).Exec(ctx)
+			if err != nil {
+				return fmt.Errorf("создание тега: %w", err)
+			}
+			slugs = append(slugs, t.Slug)
+		}
+
+		if _, err := tx.NewRaw(`DELETE FROM post_tags WHERE post_id = ?`, postID).Exec(ctx); err != nil {
+			return fmt.Errorf("очистка тегов поста: %w", err)
+		}
+		if len(slugs) == 0 {
+			return nil
 		}
+
+		_, err := tx.NewRaw(`
+			INSERT INTO post_tags (post_id, tag_id)
+			SELECT ?, id FROM tags WHERE slug IN (?)
+		`, postID, bun.In(slugs)).Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("привязка тегов: %w", err)
+		}
+		return nil
+	})
+	return err
+}
+
+// ListByPost возвращает теги поста.
+func (r *PostgresTagRepo) ListByPost(ctx context.Context, postID string) ([]*models.Tag, error) {
+	tags := make([]*models.Tag, 0)
+
+	err := selectTags(readConn(ctx, r.db, r.replica)).
+		Join("JOIN post_tags AS pt ON pt.tag_id = t.id").
+		Where("pt.post_id = ?", postID).
+		Order("t.slug ASC").
+		Scan(ctx, &tags)
+	if err != nil {
+		return nil, fmt.Errorf("теги поста: %w", err)
 	}
+	return tags, nil
+}
 
-	return comments, repository.LastID(comments, func(c *models.Comment) string { return c.ID }), nil
+// List возвращает список тегов с пагинацией по slug.
+func (r *PostgresTagRepo) List(ctx context.Context, first int32, after *string) ([]*models.Tag, *string, error) {
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+
+	tags := make([]*models.Tag, 0, first)
+
+	// Теги без видимых всем постов не показываются: по ним видны черновики и скрытый контент.
+	query := selectTags(readConn(ctx, r.db, r.replica)).
+		Where("EXISTS (SELECT 1 "+publicTagPosts+")", models.PostStatusPublished, models.ModerationStateVisible).
+		Order("t.slug ASC").
+		Limit(int(first))
+
+	if err := checkCursor(ctx, readConn(ctx, r.db, r.replica), after, "tags", "slug = ?"); err != nil {
+		return nil, nil, err
+	}
+	repository.ApplyAfterByID(query, after, "t.slug")
+	if err := query.Scan(ctx, &tags); err != nil {
+		return nil, nil, fmt.Errorf("список тегов: %w", err)
+	}
+
+	return tags, repository.LastID(tags, func(t *models.Tag) string { return t.Slug }), nil
+}
+
+// ListPosts возвращает посты с тегом в порядке создания, как PostRepo.List.
+func (r *PostgresTagRepo) ListPosts(ctx context.Context, slug string, first int32, after *string, viewer auth.Viewer) ([]*models.Post, *string, error) {
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+
+	posts := make([]*models.Post, 0, first)
+
+	query := selectPosts(readConn(ctx, r.db, r.replica)).
+		Join("JOIN post_tags AS pt ON pt.post_id = p.id").
+		Join("JOIN tags AS t ON t.id = pt.tag_id").
+		Where("t.slug = ?", slug).
+		Order("p.created_at ASC", "p.id ASC").
+		Limit(int(first))
+
+	applyPostVisibility(query, viewer)
+	if err := checkCursor(ctx, readConn(ctx, r.db, r.replica), after, "posts", "id = ?"); err != nil {
+		return nil, nil, err
+	}
+	if after != nil && *after != "" {
+		query.Where("(p.created_at, p.id) > (SELECT created_at, id FROM posts WHERE id = ?)", *after)
+	}
+
+	if err := query.Scan(ctx, &posts); err != nil {
+		return nil, nil, fmt.Errorf("посты по тегу: %w", err)
+	}
+	initPostComments(posts...)
+
+	return posts, repository.LastID(posts, func(p *models.Post) string { return p.ID }), nil
+}
+
+// ============================== NOTIFICATION REPO ==============================
+
+type mentionRow struct {
+	bun.BaseModel `bun:"table:mentions"`
+
+	ID        string    `bun:"id"`
+	UserID    string    `bun:"user_id"`
+	AuthorID  string    `bun:"author_id"`
+	PostID    string    `bun:"post_id"`
+	CommentID *string   `bun:"comment_id"`
+	CreatedAt time.Time `bun:"created_at"`
+}
+
+type notificationRow struct {
+	bun.BaseModel `bun:"table:notifications"`
+
+	ID        string                  `bun:"id"`
+	UserID    string                  `bun:"user_id"`
+	Kind      models.NotificationKind `bun:"kind"`
+	ActorID   string                  `bun:"actor_id"`
+	PostID    string                  `bun:"post_id"`
+	CommentID *string                 `bun:"comment_id"`
+	CreatedAt time.Time               `bun:"created_at"`
+}
+
+// AddMentions сохраняет упоминания пользователей.
+func (r *PostgresNotificationRepo) AddMentions(ctx context.Context, mentions []*models.Mention) error {
+	rows := make([]*mentionRow, 0, len(mentions))
+	now := time.Now()
+	for _, m := range mentions {
+		if m == nil {
+			continue
+		}
+		rows = append(rows, &mentionRow{
+			ID:        uuid.NewString(),
+			UserID:    m.UserID,
+			AuthorID:  m.AuthorID,
+			PostID:    m.PostID,
+			CommentID: m.CommentID,
+			CreatedAt: now,
+		})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	if _, err := conn(ctx, r.db).NewInsert().Model(&rows).Exec(ctx); err != nil {
+		return fmt.Errorf("сохранение упоминаний: %w", err)
+	}
+	return nil
+}
+
+// Create создает уведомление.
+func (r *PostgresNotificationRepo) Create(ctx context.Context, n *models.Notification) (*models.Notification, error) {
+	if n == nil || n.Actor == nil {
+		return nil, fmt.Errorf("требуется автор уведомления")
+	}
+
+	row := &notificationRow{
+		ID:        uuid.NewString(),
+		UserID:    n.UserID,
+		Kind:      n.Kind,
+		ActorID:   n.Actor.ID,
+		PostID:    n.PostID,
+		CommentID: n.CommentID,
+		CreatedAt: time.Now(),
+	}
+	if _, err := conn(ctx, r.db).NewInsert().Model(row).Exec(ctx); err != nil {
+		return nil, fmt.Errorf("создание уведомления: %w", err)
+	}
+
+	out := *n
+	out.ID = row.ID
+	out.CreatedAt = row.CreatedAt
+	out.ReadAt = nil
+	return &out, nil
+}
+
+// List возвращает уведомления пользователя, новые первыми.
+func (r *PostgresNotificationRepo) List(ctx context.Context, userID string, first int32, after *string, unreadOnly bool) ([]*models.Notification, *string, error) {
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+
+	items := make([]*models.Notification, 0, first)
+
+	query := readConn(ctx, r.db, r.replica).NewSelect().
+		TableExpr("notifications AS n").
+		Column("n.id", "n.user_id", "n.kind", "n.post_id", "n.comment_id", "n.read_at", "n.created_at").
+		ColumnExpr("a.id AS actor__id, a.username AS actor__username").
+		Join("JOIN users AS a ON a.id = n.actor_id").
+		Where("n.user_id = ?", userID).
+		Order("n.created_at DESC", "n.id DESC").
+		Limit(int(first))
+
+	if unreadOnly {
+		query.Where("n.read_at IS NULL")
+	}
+	if err := checkCursor(ctx, readConn(ctx, r.db, r.replica), after, "notifications", "id = ?"); err != nil {
+		return nil, nil, err
+	}
+	if after != nil && *after != "" {
+		query.Where("(n.created_at, n.id) < (SELECT created_at, id FROM notifications WHERE id = ?)", *after)
+	}
+
+	if err := query.Scan(ctx, &items); err != nil {
+		return nil, nil, fmt.Errorf("список уведомлений: %w", err)
+	}
+
+	return items, repository.LastID(items, func(n *models.Notification) string { return n.ID }), nil
+}
+
+// CountUnread количество непрочитанных уведомлений.
+func (r *PostgresNotificationRepo) CountUnread(ctx context.Context, userID string) (int, error) {
+	cnt, err := readConn(ctx, r.db, r.replica).NewSelect().
+		Table("notifications").
+		Where("user_id = ?", userID).
+		Where("read_at IS NULL").
+		Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("непрочитанные уведомления: %w", err)
+	}
+	return cnt, nil
+}
+
+// MarkRead отмечает уведомления прочитанными.
+func (r *PostgresNotificationRepo) MarkRead(ctx context.Context, userID string, ids []string, at time.Time) (int, error) {
+	query := conn(ctx, r.db).NewUpdate().
+		Table("notifications").
+		Set("read_at = ?", at).
+		Where("user_id = ?", userID).
+		Where("read_at IS NULL")
+	if len(ids) > 0 {
+		query.Where("id IN (?)", bun.In(ids))
+	}
+
+	res, err := query.Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("прочтение уведомлений: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("прочтение уведомлений: %w", err)
+	}
+	return int(rows), nil
+}
+
+// ============================== FOLLOW REPO ==============================
+
+// Follow подписывает followerID на followeeID, повторная подписка ничего не меняет.
+func (r *PostgresFollowRepo) Follow(ctx context.Context, followerID, followeeID string) error {
+	_, err := conn(ctx, r.db).NewRaw(`
+		INSERT INTO follows (follower_id, followee_id)
+		VALUES (?, ?)
+		ON CONFLICT (follower_id, followee_id) DO NOTHING
+	`, followerID, followeeID).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("подписка: %w", err)
+	}
+	return nil
+}
+
+// Unfollow отменяет подписку.
+func (r *PostgresFollowRepo) Unfollow(ctx context.Context, followerID, followeeID string) error {
+	_, err := conn(ctx, r.db).NewRaw(`
+		DELETE FROM follows WHERE follower_id = ? AND followee_id = ?
+	`, followerID, followeeID).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("отписка: %w", err)
+	}
+	return nil
+}
+
+// ListFollowers возвращает подписчиков пользователя.
+func (r *PostgresFollowRepo) ListFollowers(ctx context.Context, userID string, first int32, after *string) ([]*models.User, *string, error) {
+	return r.listUsers(ctx, "followee_id", "follower_id", userID, first, after)
+}
+
+// ListFollowing возвращает пользователей, на которых подписан userID.
+func (r *PostgresFollowRepo) ListFollowing(ctx context.Context, userID string, first int32, after *string) ([]*models.User, *string, error) {
+	return r.listUsers(ctx, "follower_id", "followee_id", userID, first, after)
+}
+
+// CountFollowers количество подписчиков.
+func (r *PostgresFollowRepo) CountFollowers(ctx context.Context, userID string) (int, error) {
+	cnt, err := readConn(ctx, r.db, r.replica).NewSelect().
+		Table("follows").
+		Where("followee_id = ?", userID).
+		Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("количество подписчиков: %w", err)
+	}
+	return cnt, nil
+}
+
+// CountFollowing количество подписок.
+func (r *PostgresFollowRepo) CountFollowing(ctx context.Context, userID string) (int, error) {
+	cnt, err := readConn(ctx, r.db, r.replica).NewSelect().
+		Table("follows").
+		Where("follower_id = ?", userID).
+		Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("количество подписок: %w", err)
+	}
+	return cnt, nil
+}
+
+// Feed лента из постов авторов, на которых подписан пользователь.
+// Собирается при чтении, по индексу posts_feed_idx для каждого автора.
+func (r *PostgresFollowRepo) Feed(ctx context.Context, userID string, first int32, after *string) ([]*models.Post, *string, error) {
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+
+	posts := make([]*models.Post, 0, first)
+
+	query := selectPosts(readConn(ctx, r.db, r.replica)).
+		Where("p.author_id IN (SELECT followee_id FROM follows WHERE follower_id = ?)", userID).
+		Where("p.status = ?", models.PostStatusPublished).
+		Where("p.moderation_state = ?", models.ModerationStateVisible).
+		Order("p.published_at DESC", "p.id DESC").
+		Limit(int(first))
+
+	if err := checkCursor(ctx, readConn(ctx, r.db, r.replica), after, "posts", "id = ?"); err != nil {
+		return nil, nil, err
+	}
+	if after != nil && *after != "" {
+		query.Where("(p.published_at, p.id) < (SELECT published_at, id FROM posts WHERE id = ?)", *after)
+	}
+
+	if err := query.Scan(ctx, &posts); err != nil {
+		return nil, nil, fmt.Errorf("лента: %w", err)
+	}
+	initPostComments(posts...)
+
+	return posts, repository.LastID(posts, func(p *models.Post) string { return p.ID }), nil
+}
+
+// listUsers пользователи по связи подписки, новые подписки первыми.
+// by колонка с userID, other колонка с возвращаемыми пользователями.
+func (r *PostgresFollowRepo) listUsers(ctx context.Context, by, other, userID string, first int32, after *string) ([]*models.User, *string, error) {
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+
+	users := make([]*models.User, 0, first)
+
+	query := readConn(ctx, r.db, r.replica).NewSelect().
+		TableExpr("follows AS f").
+		ColumnExpr("u.id, u.username").
+		Join(fmt.Sprintf("JOIN users AS u ON u.id = f.%s", other)).
+		Where(fmt.Sprintf("f.%s = ?", by), userID).
+		OrderExpr(fmt.Sprintf("f.created_at DESC, f.%s DESC", other)).
+		Limit(int(first))
+
+	if err := checkCursor(ctx, readConn(ctx, r.db, r.replica), after, "follows", fmt.Sprintf("%s = ? AND %s = ?", by, other), userID); err != nil {
+		return nil, nil, err
+	}
+	if after != nil && *after != "" {
+		query.Where(fmt.Sprintf(
+			"(f.created_at, f.%[2]s) < (SELECT created_at, %[2]s FROM follows WHERE %[1]s = ? AND %[2]s = ?)", by, other,
+		), userID, *after)
+	}
+
+	if err := query.Scan(ctx, &users); err != nil {
+		return nil, nil, fmt.Errorf("список подписок: %w", err)
+	}
+
+	return users, repository.LastID(users, func(u *models.User) string { return u.ID }), nil
+}
+
+// ============================== BOOKMARK REPO ==============================
+
+// Add добавляет пост в закладки, повторное добавление ничего не меняет.
+func (r *PostgresBookmarkRepo) Add(ctx context.Context, userID, postID string) error {
+	_, err := conn(ctx, r.db).NewRaw(`
+		INSERT INTO bookmarks (user_id, post_id)
+		VALUES (?, ?)
+		ON CONFLICT (user_id, post_id) DO NOTHING
+	`, userID, postID).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("добавление закладки: %w", err)
+	}
+	return nil
+}
+
+// Remove удаляет пост из закладок.
+func (r *PostgresBookmarkRepo) Remove(ctx context.Context, userID, postID string) error {
+	_, err := conn(ctx, r.db).NewRaw(`
+		DELETE FROM bookmarks WHERE user_id = ? AND post_id = ?
+	`, userID, postID).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("удаление закладки: %w", err)
+	}
+	return nil
+}
+
+// Has проверяет, что пост в закладках.
+func (r *PostgresBookmarkRepo) Has(ctx context.Context, userID, postID string) (bool, error) {
+	ok, err := readConn(ctx, r.db, r.replica).NewSelect().
+		Table("bookmarks").
+		Where("user_id = ?", userID).
+		Where("post_id = ?", postID).
+		Exists(ctx)
+	if err != nil {
+		return false, fmt.Errorf("проверка закладки: %w", err)
+	}
+	return ok, nil
+}
+
+// List возвращает закладки пользователя, новые первыми.
+func (r *PostgresBookmarkRepo) List(ctx context.Context, userID string, first int32, after *string, viewer auth.Viewer) ([]*models.Post, *string, error) {
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+
+	posts := make([]*models.Post, 0, first)
+
+	query := selectPosts(readConn(ctx, r.db, r.replica)).
+		Join("JOIN bookmarks AS b ON b.post_id = p.id").
+		Where("b.user_id = ?", userID).
+		Order("b.created_at DESC", "b.post_id DESC").
+		Limit(int(first))
+
+	applyPostVisibility(query, viewer)
+	if err := checkCursor(ctx, readConn(ctx, r.db, r.replica), after, "bookmarks", "user_id = ? AND post_id = ?", userID); err != nil {
+		return nil, nil, err
+	}
+	if after != nil && *after != "" {
+		query.Where("(b.created_at, b.post_id) < (SELECT created_at, post_id FROM bookmarks WHERE user_id = ? AND post_id = ?)", userID, *after)
+	}
+
+	if err := query.Scan(ctx, &posts); err != nil {
+		return nil, nil, fmt.Errorf("список закладок: %w", err)
+	}
+	initPostComments(posts...)
+
+	return posts, repository.LastID(posts, func(p *models.Post) string { return p.ID }), nil
+}
+
+// ============================== READ STATE REPO ==============================
+
+// MarkRead сохраняет время прочтения поста, не сдвигая его назад.
+func (r *PostgresReadStateRepo) MarkRead(ctx context.Context, userID, postID string, at time.Time) error {
+	_, err := conn(ctx, r.db).NewRaw(`
+		INSERT INTO post_reads (user_id, post_id, last_read_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (user_id, post_id)
+		DO UPDATE SET last_read_at = GREATEST(post_reads.last_read_at, EXCLUDED.last_read_at)
+	`, userID, postID, at).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("прочтение поста: %w", err)
+	}
+	return nil
+}
+
+// LastReadAt время последнего прочтения поста, nil если пост не читали.
+func (r *PostgresReadStateRepo) LastReadAt(ctx context.Context, userID, postID string) (*time.Time, error) {
+	var at time.Time
+	err := readConn(ctx, r.db, r.replica).NewSelect().
+		Table("post_reads").
+		Column("last_read_at").
+		Where("user_id = ?", userID).
+		Where("post_id = ?", postID).
+		Scan(ctx, &at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("время прочтения: %w", err)
+	}
+	return &at, nil
+}
+
+// CountUnread количество чужих видимых комментариев к посту после прочтения.
+func (r *PostgresReadStateRepo) CountUnread(ctx context.Context, postID string, viewer auth.Viewer) (int, error) {
+	query := readConn(ctx, r.db, r.replica).NewSelect().
+		TableExpr("comments AS c").
+		Where("c.post_id = ?", postID).
+		Where("CAST(c.author_id AS TEXT) <> ?", viewer.UserID).
+		Where("c.created_at > COALESCE((SELECT last_read_at FROM post_reads WHERE user_id = ? AND post_id = ?), '-infinity')", viewer.UserID, postID)
+	if !viewer.IsModerator() {
+		applyModerationVisibility(query, "c", viewer)
+	}
+	cnt, err := query.Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("непрочитанные комментарии: %w", err)
+	}
+	return cnt, nil
+}
+
+// ============================== REPORT REPO ==============================
+
+type reportRow struct {
+	bun.BaseModel `bun:"table:reports"`
+
+	ID         string                  `bun:"id"`
+	TargetType models.ReportTargetType `bun:"target_type"`
+	TargetID   string                  `bun:"target_id"`
+	ReporterID *string                 `bun:"reporter_id"`
+	Reason     models.ReportReason     `bun:"reason"`
+	Note       *string                 `bun:"note"`
+	Status     models.ReportStatus     `bun:"status"`
+	CreatedAt  time.Time               `bun:"created_at"`
+}
+
+// selectReports базовый запрос жалоб вместе с автором и модератором, у системных жалоб автора нет.
+func selectReports(db bun.IDB) *bun.SelectQuery {
+	return db.NewSelect().
+		TableExpr("reports AS r").
+		Column("r.id", "r.target_type", "r.target_id", "r.reason", "r.note", "r.status", "r.action", "r.resolved_at", "r.created_at").
+		ColumnExpr("u.id AS reporter__id, u.username AS reporter__username").
+		ColumnExpr("m.id AS resolved_by__id, m.username AS resolved_by__username").
+		Join("LEFT JOIN users AS u ON u.id = r.reporter_id").
+		Join("LEFT JOIN users AS m ON m.id = r.resolved_by")
+}
+
+// fixReportUsers убирает пустого автора у системных жалоб и пустого модератора у открытых.
+func fixReportUsers(reports ...*models.Report) {
+	for _, rep := range reports {
+		if rep.Reporter != nil && rep.Reporter.ID == "" {
+			rep.Reporter = nil
+		}
+		if rep.ResolvedBy != nil && rep.ResolvedBy.ID == "" {
+			rep.ResolvedBy = nil
+		}
+	}
+}
+
+// Create создает жалобу, одна открытая жалоба от пользователя на контент.
+// Жалоба без автора - системная, от фильтров контента, тоже одна на контент.
+func (r *PostgresReportRepo) Create(ctx context.Context, rep *models.Report) (*models.Report, error) {
+	if rep == nil || (rep.Reporter != nil && rep.Reporter.ID == "") {
+		return nil, fmt.Errorf("неверный автор жалобы")
+	}
+
+	row := &reportRow{
+		ID:         uuid.NewString(),
+		TargetType: rep.TargetType,
+		TargetID:   rep.TargetID,
+		Reason:     rep.Reason,
+		Note:       rep.Note,
+		Status:     models.ReportStatusOpen,
+		CreatedAt:  time.Now(),
+	}
+	conflict := "CONFLICT (target_id) WHERE status = 'OPEN' AND reporter_id IS NULL DO NOTHING"
+	if rep.Reporter != nil {
+		row.ReporterID = &rep.Reporter.ID
+		conflict = "CONFLICT (reporter_id, target_id) WHERE status = 'OPEN' DO NOTHING"
+	}
+	res, err := conn(ctx, r.db).NewInsert().
+		Model(row).
+		On(conflict).
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("создание жалобы: %w", err)
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return nil, ErrAlreadyExist
+	}
+
+	return r.GetByID(ctx, row.ID)
+}
+
+// GetByID возвращает жалобу по id.
+func (r *PostgresReportRepo) GetByID(ctx context.Context, id string) (*models.Report, error) {
+	rep := new(models.Report)
+
+	err := selectReports(conn(ctx, r.db)).
+		Where("r.id = ?", id).
+		Scan(ctx, rep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("получение жалобы: %w", err)
+	}
+	fixReportUsers(rep)
+	return rep, nil
+}
+
+// List возвращает жалобы, новые первыми.
+func (r *PostgresReportRepo) List(ctx context.Context, status *models.ReportStatus, first int32, after *string) ([]*models.Report, *string, error) {
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+
+	reports := make([]*models.Report, 0, first)
+
+	query := selectReports(readConn(ctx, r.db, r.replica)).
+		Order("r.created_at DESC", "r.id DESC").
+		Limit(int(first))
+
+	if status != nil {
+		query.Where("r.status = ?", *status)
+	}
+	if err := checkCursor(ctx, readConn(ctx, r.db, r.replica), after, "reports", "id = ?"); err != nil {
+		return nil, nil, err
+	}
+	if after != nil && *after != "" {
+		query.Where("(r.created_at, r.id) < (SELECT created_at, id FROM reports WHERE id = ?)", *after)
+	}
+
+	if err := query.Scan(ctx, &reports); err != nil {
+		return nil, nil, fmt.Errorf("список жалоб: %w", err)
+	}
+	fixReportUsers(reports...)
+
+	return reports, repository.LastID(reports, func(rep *models.Report) string { return rep.ID }), nil
+}
+
+// Resolve закрывает открытую жалобу.
+func (r *PostgresReportRepo) Resolve(ctx context.Context, id string, status models.ReportStatus, action models.ModerationAction, moderatorID string, at time.Time) (*models.Report, error) {
+	res, err := conn(ctx, r.db).NewUpdate().
+		Table("reports").
+		Set("status = ?", status).
+		Set("action = ?", action).
+		Set("resolved_by = ?", moderatorID).
+		Set("resolved_at = ?", at).
+		Where("id = ?", id).
+		Where("status = ?", models.ReportStatusOpen).
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("закрытие жалобы: %w", err)
+	}
+
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		if _, err := r.GetByID(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrReportClosed
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// ============================== BAN REPO ==============================
+
+type banRow struct {
+	bun.BaseModel `bun:"table:bans"`
+
+	ID        string     `bun:"id"`
+	UserID    string     `bun:"user_id"`
+	Reason    string     `bun:"reason"`
+	Until     *time.Time `bun:"until"`
+	BannedBy  *string    `bun:"banned_by"`
+	CreatedAt time.Time  `bun:"created_at"`
+}
+
+// selectBans базовый запрос блокировок вместе с пользователем и модераторами.
+func selectBans(db bun.IDB) *bun.SelectQuery {
+	return db.NewSelect().
+		TableExpr("bans AS b").
+		Column("b.id", "b.reason", "b.until", "b.created_at", "b.lifted_at").
+		ColumnExpr("u.id AS user__id, u.username AS user__username").
+		ColumnExpr("m.id AS banned_by__id, m.username AS banned_by__username").
+		ColumnExpr("l.id AS lifted_by__id, l.username AS lifted_by__username").
+		Join("JOIN users AS u ON u.id = b.user_id").
+		Join("LEFT JOIN users AS m ON m.id = b.banned_by").
+		Join("LEFT JOIN users AS l ON l.id = b.lifted_by")
+}
+
+// Create создает блокировку.
+func (r *PostgresBanRepo) Create(ctx context.Context, b *models.Ban) (*models.Ban, error) {
+	if b == nil || b.User == nil || b.User.ID == "" {
+		return nil, fmt.Errorf("требуется id пользователя")
+	}
+
+	row := &banRow{
+		ID:        uuid.NewString(),
+		UserID:    b.User.ID,
+		Reason:    b.Reason,
+		Until:     b.Until,
+		CreatedAt: time.Now(),
+	}
+	if b.BannedBy != nil && b.BannedBy.ID != "" {
+		row.BannedBy = &b.BannedBy.ID
+	}
+	if _, err := conn(ctx, r.db).NewInsert().Model(row).Exec(ctx); err != nil {
+		return nil, fmt.Errorf("создание блокировки: %w", err)
+	}
+
+	ban := new(models.Ban)
+	if err := selectBans(conn(ctx, r.db)).Where("b.id = ?", row.ID).Scan(ctx, ban); err != nil {
+		return nil, fmt.Errorf("получение блокировки: %w", err)
+	}
+	fixBanUsers(ban)
+	return ban, nil
+}
+
+// Active последняя действующая блокировка пользователя.
+func (r *PostgresBanRepo) Active(ctx context.Context, userID string, at time.Time) (*models.Ban, error) {
+	ban := new(models.Ban)
+
+	err := selectBans(conn(ctx, r.db)).
+		Where("b.user_id = ?", userID).
+		Where("b.lifted_at IS NULL").
+		Where("(b.until IS NULL OR b.until > ?)", at).
+		Order("b.created_at DESC").
+		Limit(1).
+		Scan(ctx, ban)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("действующая блокировка: %w", err)
+	}
+	fixBanUsers(ban)
+	return ban, nil
+}
+
+// Lift снимает действующие блокировки пользователя.
+func (r *PostgresBanRepo) Lift(ctx context.Context, userID, moderatorID string, at time.Time) (int, error) {
+	res, err := conn(ctx, r.db).NewUpdate().
+		Table("bans").
+		Set("lifted_at = ?", at).
+		Set("lifted_by = ?", moderatorID).
+		Where("user_id = ?", userID).
+		Where("lifted_at IS NULL").
+		Where("(until IS NULL OR until > ?)", at).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("снятие блокировки: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("снятие блокировки: %w", err)
+	}
+	return int(rows), nil
+}
+
+// fixBanUsers убирает пустых модераторов из LEFT JOIN.
+func fixBanUsers(bans ...*models.Ban) {
+	for _, b := range bans {
+		if b.BannedBy != nil && b.BannedBy.ID == "" {
+			b.BannedBy = nil
+		}
+		if b.LiftedBy != nil && b.LiftedBy.ID == "" {
+			b.LiftedBy = nil
+		}
+	}
+}
+
+// ============================== AUDIT REPO ==============================
+
+type auditRow struct {
+	bun.BaseModel `bun:"table:audit_log"`
+
+	ID         string    `bun:"id"`
+	ActorID    *string   `bun:"actor_id"`
+	Action     string    `bun:"action"`
+	TargetType *string   `bun:"target_type"`
+	TargetID   *string   `bun:"target_id"`
+	Diff       *string   `bun:"diff"`
+	RequestID  *string   `bun:"request_id"`
+	IP         *string   `bun:"ip"`
+	CreatedAt  time.Time `bun:"created_at"`
+}
+
+// Append пишет запись журнала, в транзакции мутации, если она есть в контексте.
+func (r *PostgresAuditRepo) Append(ctx context.Context, e *models.AuditEntry) error {
+	if e == nil {
+		return fmt.Errorf("требуется запись журнала")
+	}
+
+	row := &auditRow{
+		ID:         uuid.NewString(),
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Diff:       e.Diff,
+		RequestID:  e.RequestID,
+		IP:         e.IP,
+		CreatedAt:  e.CreatedAt,
+	}
+	if e.Actor != nil && e.Actor.ID != "" {
+		row.ActorID = &e.Actor.ID
+	}
+	if row.CreatedAt.IsZero() {
+		row.CreatedAt = time.Now()
+	}
+	if _, err := conn(ctx, r.db).NewInsert().Model(row).Exec(ctx); err != nil {
+		return fmt.Errorf("запись журнала: %w", err)
+	}
+	return nil
+}
+
+// List записи журнала под фильтр, новые первыми.
+func (r *PostgresAuditRepo) List(ctx context.Context, filter models.AuditFilter, first int32, after *string) ([]*models.AuditEntry, *string, error) {
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+
+	entries := make([]*models.AuditEntry, 0, first)
+
+	query := readConn(ctx, r.db, r.replica).NewSelect().
+		TableExpr("audit_log AS a").
+		Column("a.id", "a.action", "a.target_type", "a.target_id", "a.request_id", "a.ip", "a.created_at").
+		ColumnExpr("CAST(a.diff AS TEXT) AS diff").
+		ColumnExpr("a.actor_id AS actor__id, u.username AS actor__username").
+		Join("LEFT JOIN users AS u ON CAST(u.id AS TEXT) = a.actor_id").
+		Order("a.created_at DESC", "a.id DESC").
+		Limit(int(first))
+
+	if filter.ActorID != nil {
+		query.Where("a.actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != nil {
+		query.Where("a.action = ?", *filter.Action)
+	}
+	if filter.TargetType != nil {
+		query.Where("a.target_type = ?", *filter.TargetType)
+	}
+	if filter.TargetID != nil {
+		query.Where("a.target_id = ?", *filter.TargetID)
+	}
+	if filter.Since != nil {
+		query.Where("a.created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query.Where("a.created_at < ?", *filter.Until)
+	}
+	if err := checkCursor(ctx, readConn(ctx, r.db, r.replica), after, "audit_log", "id = ?"); err != nil {
+		return nil, nil, err
+	}
+	if after != nil && *after != "" {
+		query.Where("(a.created_at, a.id) < (SELECT created_at, id FROM audit_log WHERE id = ?)", *after)
+	}
+
+	if err := query.Scan(ctx, &entries); err != nil {
+		return nil, nil, fmt.Errorf("журнал мутаций: %w", err)
+	}
+	for _, e := range entries {
+		if e.Actor != nil && e.Actor.ID == "" {
+			e.Actor = nil
+		}
+	}
+
+	return entries, repository.LastID(entries, func(e *models.AuditEntry) string { return e.ID }), nil
 }
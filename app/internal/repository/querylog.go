@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+)
+
+// QueryLogHook пишет SQL запросы bun в лог с полями запроса из контекста:
+// успешные на уровне debug, ошибки (кроме sql.ErrNoRows) на уровне warn.
+// Текст запроса не пишется: в нем подставлены значения аргументов, в том числе
+// тела постов и токены, поэтому в лог идут только операция, таблица и длительность.
+type QueryLogHook struct {
+	Logger logger.Logger
+}
+
+var _ bun.QueryHook = QueryLogHook{}
+
+func (h QueryLogHook) BeforeQuery(ctx context.Context, _ *bun.QueryEvent) context.Context {
+	return ctx
+}
+
+func (h QueryLogHook) AfterQuery(ctx context.Context, event *bun.QueryEvent) {
+	l := h.Logger.Ctx(ctx).With("table", queryTable(event), "duration", time.Since(event.StartTime))
+	if event.Err != nil && !errors.Is(event.Err, sql.ErrNoRows) {
+		l.Warnf("sql %s: ошибка запроса", event.Operation())
+		return
+	}
+	l.Debugf("sql %s", event.Operation())
+}
+
+// queryTable таблица запроса, пусто для сырых запросов.
+func queryTable(event *bun.QueryEvent) string {
+	if q, ok := event.IQuery.(interface{ GetTableName() string }); ok {
+		return q.GetTableName()
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+)
+
+// recordLogger запоминает поля и сообщения всех записей.
+type recordLogger struct {
+	fields []any
+	lines  *[]string
+}
+
+func (l recordLogger) record(level, format string, args ...any) {
+	*l.lines = append(*l.lines, level+" "+fmt.Sprintf(format, args...)+" "+fmt.Sprint(l.fields...))
+}
+
+func (l recordLogger) Debugf(format string, args ...any) { l.record("debug", format, args...) }
+func (l recordLogger) Infof(format string, args ...any)  { l.record("info", format, args...) }
+func (l recordLogger) Warnf(format string, args ...any)  { l.record("warn", format, args...) }
+func (l recordLogger) Errorf(format string, args ...any) { l.record("error", format, args...) }
+func (l recordLogger) With(kv ...any) logger.Logger {
+	return recordLogger{fields: append(append([]any(nil), l.fields...), kv...), lines: l.lines}
+}
+func (l recordLogger) Ctx(context.Context) logger.Logger { return l }
+
+// Тест на лог запросов: значения аргументов не попадают в лог ни при успехе, ни при ошибке.
+func TestQueryLogHook(t *testing.T) {
+	const secret = "секретное-тело-поста"
+	db := newSQLiteTestDB(t)
+	var lines []string
+	db.AddQueryHook(QueryLogHook{Logger: recordLogger{lines: &lines}})
+	posts, _ := NewSQLitePostRepo(db)
+
+	ctx := context.Background()
+	if _, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: conformanceUsers[1].ID}, Title: "t", Body: secret}); err != nil {
+		t.Fatalf("пост: %v", err)
+	}
+	if _, err := db.NewInsert().Model(&struct {
+		ID   string `bun:"id"`
+		Body string `bun:"body"`
+	}{ID: secret, Body: secret}).ModelTableExpr("missing").Exec(ctx); err == nil {
+		t.Fatal("ожидалась ошибка запроса")
+	}
+
+	var warned bool
+	for _, line := range lines {
+		if strings.Contains(line, secret) {
+			t.Fatalf("значение аргумента в логе: %s", line)
+		}
+		warned = warned || strings.HasPrefix(line, "warn")
+	}
+	if !warned || !strings.Contains(strings.Join(lines, "\n"), "posts") {
+		t.Fatalf("ожидались операции с таблицами и warn на ошибке: %v", lines)
+	}
+}
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"github.com/RoGogDBD/GQLGo/internal/auth"
 	"github.com/RoGogDBD/GQLGo/internal/models"
 )
 
@@ -10,20 +12,158 @@ type (
 	UserRepo interface {
 		GetByID(ctx context.Context, id string) (*models.User, error)
 		List(ctx context.Context, first int32, after *string) ([]*models.User, *string, error)
+		GetByUsernames(ctx context.Context, usernames []string) ([]*models.User, error)
 	}
 
 	PostRepo interface {
 		GetByID(ctx context.Context, id string) (*models.Post, error)
-		Create(ctx context.Context, in models.CreatePostInput) (*models.Post, error)
-		List(ctx context.Context, first int32, after *string) ([]*models.Post, *string, error)
+		// GetForUpdate пост с блокировкой до конца транзакции из контекста.
+		GetForUpdate(ctx context.Context, id string) (*models.Post, error)
+		Create(ctx context.Context, p *models.Post) (*models.Post, error)
+		List(ctx context.Context, first int32, after *string, viewer auth.Viewer) ([]*models.Post, *string, error)
 		SetCommentsEnabled(ctx context.Context, postID string, enabled bool) (*models.Post, error)
+		UpdateStatus(ctx context.Context, postID string, from []models.PostStatus, to models.PostStatus, at time.Time) (*models.Post, error)
+		PublishDue(ctx context.Context, now time.Time, limit int) ([]*models.Post, error)
+		SetModerationState(ctx context.Context, postID string, state models.ModerationState) (*models.Post, error)
+		// HideByAuthor скрывает все посты автора, возвращает количество скрытых.
+		HideByAuthor(ctx context.Context, authorID string) (int, error)
 	}
 
 	CommentRepo interface {
+		GetByID(ctx context.Context, id string) (*models.Comment, error)
 		GetMeta(ctx context.Context, id string) (postID string, depth int, err error)
-		Create(ctx context.Context, postID, authorID string, parentID *string, body string, depth int) (*models.Comment, error)
-		ListByParent(ctx context.Context, postID string, parentID *string, first int32, after *string, order models.CommentOrder) ([]*models.Comment, *string, error)
+		Create(ctx context.Context, c *models.Comment) (*models.Comment, error)
+		// ListByParent скрытые модерацией комментарии видят только модераторы, ожидающие - еще и автор.
+		ListByParent(ctx context.Context, postID string, parentID *string, first int32, after *string, order models.CommentOrder, viewer auth.Viewer) ([]*models.Comment, *string, error)
+		SetModerationState(ctx context.Context, id string, state models.ModerationState) (*models.Comment, error)
+		// HideByAuthor скрывает все комментарии автора, возвращает количество скрытых.
+		HideByAuthor(ctx context.Context, authorID string) (int, error)
+	}
+
+	TagRepo interface {
+		SetPostTags(ctx context.Context, postID string, tags []*models.Tag) error
+		ListByPost(ctx context.Context, postID string) ([]*models.Tag, error)
+		List(ctx context.Context, first int32, after *string) ([]*models.Tag, *string, error)
+		ListPosts(ctx context.Context, slug string, first int32, after *string, viewer auth.Viewer) ([]*models.Post, *string, error)
+	}
+
+	NotificationRepo interface {
+		AddMentions(ctx context.Context, mentions []*models.Mention) error
+		Create(ctx context.Context, n *models.Notification) (*models.Notification, error)
+		List(ctx context.Context, userID string, first int32, after *string, unreadOnly bool) ([]*models.Notification, *string, error)
+		CountUnread(ctx context.Context, userID string) (int, error)
+		// MarkRead отмечает прочитанными уведомления ids, все при пустом ids.
+		MarkRead(ctx context.Context, userID string, ids []string, at time.Time) (int, error)
+	}
+
+	FollowRepo interface {
+		Follow(ctx context.Context, followerID, followeeID string) error
+		Unfollow(ctx context.Context, followerID, followeeID string) error
+		// ListFollowers подписчики userID, новые подписки первыми.
+		ListFollowers(ctx context.Context, userID string, first int32, after *string) ([]*models.User, *string, error)
+		// ListFollowing на кого подписан userID, новые подписки первыми.
+		ListFollowing(ctx context.Context, userID string, first int32, after *string) ([]*models.User, *string, error)
+		CountFollowers(ctx context.Context, userID string) (int, error)
+		CountFollowing(ctx context.Context, userID string) (int, error)
+		// Feed опубликованные посты авторов, на которых подписан userID, новые первыми.
+		Feed(ctx context.Context, userID string, first int32, after *string) ([]*models.Post, *string, error)
+	}
+
+	BookmarkRepo interface {
+		Add(ctx context.Context, userID, postID string) error
+		Remove(ctx context.Context, userID, postID string) error
+		Has(ctx context.Context, userID, postID string) (bool, error)
+		// List закладки пользователя, новые первыми.
+		List(ctx context.Context, userID string, first int32, after *string, viewer auth.Viewer) ([]*models.Post, *string, error)
+	}
+
+	ReadStateRepo interface {
+		// MarkRead сдвигает lastReadAt вперед, более раннее время игнорируется.
+		MarkRead(ctx context.Context, userID, postID string, at time.Time) error
+		LastReadAt(ctx context.Context, userID, postID string) (*time.Time, error)
+		// CountUnread видимые viewer комментарии к посту новее его lastReadAt, кроме своих.
+		CountUnread(ctx context.Context, postID string, viewer auth.Viewer) (int, error)
+	}
+
+	ReportRepo interface {
+		// Create возвращает ErrAlreadyExist, если у пользователя уже есть открытая жалоба на этот контент.
+		Create(ctx context.Context, r *models.Report) (*models.Report, error)
+		GetByID(ctx context.Context, id string) (*models.Report, error)
+		// List жалобы со статусом status (все при nil), новые первыми.
+		List(ctx context.Context, status *models.ReportStatus, first int32, after *string) ([]*models.Report, *string, error)
+		// Resolve закрывает открытую жалобу, ErrReportClosed если она уже закрыта.
+		Resolve(ctx context.Context, id string, status models.ReportStatus, action models.ModerationAction, moderatorID string, at time.Time) (*models.Report, error)
+	}
+
+	BanRepo interface {
+		Create(ctx context.Context, b *models.Ban) (*models.Ban, error)
+		// Active действующая на момент at блокировка пользователя, nil если ее нет.
+		Active(ctx context.Context, userID string, at time.Time) (*models.Ban, error)
+		// Lift снимает действующие блокировки пользователя, возвращает их количество.
+		Lift(ctx context.Context, userID, moderatorID string, at time.Time) (int, error)
+	}
+
+	AuditRepo interface {
+		// Append добавляет запись, изменять и удалять записи нельзя.
+		Append(ctx context.Context, e *models.AuditEntry) error
+		// List записи под фильтр, новые первыми.
+		List(ctx context.Context, filter models.AuditFilter, first int32, after *string) ([]*models.AuditEntry, *string, error)
 	}
 )
 
 const DefaultPageSize = 10
+
+// PostVisible неопубликованные посты видит только автор, скрытые модерацией - только модераторы,
+// ожидающие модерации - автор и модераторы.
+func PostVisible(p *models.Post, viewer auth.Viewer) bool {
+	if p == nil {
+		return false
+	}
+	isAuthor := p.Author != nil && viewer.IsAuthor(p.Author.ID)
+	if !moderationVisible(p.ModerationState, isAuthor, viewer) {
+		return false
+	}
+	if p.Status == models.PostStatusPublished {
+		return true
+	}
+	return isAuthor
+}
+
+// CommentVisible скрытые модерацией комментарии видят только модераторы,
+// ожидающие модерации - автор и модераторы.
+func CommentVisible(c *models.Comment, viewer auth.Viewer) bool {
+	if c == nil {
+		return false
+	}
+	return moderationVisible(c.ModerationState, c.Author != nil && viewer.IsAuthor(c.Author.ID), viewer)
+}
+
+// AuditMatches подходит ли запись журнала под фильтр.
+func AuditMatches(e *models.AuditEntry, f models.AuditFilter) bool {
+	switch {
+	case f.ActorID != nil && (e.Actor == nil || e.Actor.ID != *f.ActorID):
+		return false
+	case f.Action != nil && e.Action != *f.Action:
+		return false
+	case f.TargetType != nil && (e.TargetType == nil || *e.TargetType != *f.TargetType):
+		return false
+	case f.TargetID != nil && (e.TargetID == nil || *e.TargetID != *f.TargetID):
+		return false
+	case f.Since != nil && e.CreatedAt.Before(*f.Since):
+		return false
+	case f.Until != nil && !e.CreatedAt.Before(*f.Until):
+		return false
+	}
+	return true
+}
+
+func moderationVisible(state models.ModerationState, isAuthor bool, viewer auth.Viewer) bool {
+	switch state {
+	case models.ModerationStateHidden:
+		return viewer.IsModerator()
+	case models.ModerationStatePending:
+		return isAuthor || viewer.IsModerator()
+	default:
+		return true
+	}
+}
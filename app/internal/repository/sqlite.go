@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// SQLite репозитории. Запросы те же, что для Postgres, через bun с диалектом sqlite;
+// переопределены только методы с конструкциями, которых в SQLite нет.
+type (
+	SQLiteUserRepo         struct{ *PostgresUserRepo }
+	SQLitePostRepo         struct{ *PostgresPostRepo }
+	SQLiteCommentRepo      struct{ *PostgresCommentRepo }
+	SQLiteTagRepo          struct{ *PostgresTagRepo }
+	SQLiteNotificationRepo struct{ *PostgresNotificationRepo }
+	SQLiteFollowRepo       struct{ *PostgresFollowRepo }
+	SQLiteBookmarkRepo     struct{ *PostgresBookmarkRepo }
+	SQLiteReadStateRepo    struct{ *PostgresReadStateRepo }
+	SQLiteReportRepo       struct{ *PostgresReportRepo }
+	SQLiteBanRepo          struct{ *PostgresBanRepo }
+	SQLiteAuditRepo        struct{ *PostgresAuditRepo }
+)
+
+func NewSQLiteUserRepo(db *bun.DB) (*SQLiteUserRepo, error) {
+	return &SQLiteUserRepo{&PostgresUserRepo{db: db}}, nil
+}
+func NewSQLitePostRepo(db *bun.DB) (*SQLitePostRepo, error) {
+	return &SQLitePostRepo{&PostgresPostRepo{db: db}}, nil
+}
+func NewSQLiteCommentRepo(db *bun.DB) (*SQLiteCommentRepo, error) {
+	return &SQLiteCommentRepo{&PostgresCommentRepo{db: db}}, nil
+}
+func NewSQLiteTagRepo(db *bun.DB) (*SQLiteTagRepo, error) {
+	return &SQLiteTagRepo{&PostgresTagRepo{db: db}}, nil
+}
+func NewSQLiteNotificationRepo(db *bun.DB) (*SQLiteNotificationRepo, error) {
+	return &SQLiteNotificationRepo{&PostgresNotificationRepo{db: db}}, nil
+}
+func NewSQLiteFollowRepo(db *bun.DB) (*SQLiteFollowRepo, error) {
+	return &SQLiteFollowRepo{&PostgresFollowRepo{db: db}}, nil
+}
+func NewSQLiteBookmarkRepo(db *bun.DB) (*SQLiteBookmarkRepo, error) {
+	return &SQLiteBookmarkRepo{&PostgresBookmarkRepo{db: db}}, nil
+}
+func NewSQLiteReadStateRepo(db *bun.DB) (*SQLiteReadStateRepo, error) {
+	return &SQLiteReadStateRepo{&PostgresReadStateRepo{db: db}}, nil
+}
+func NewSQLiteReportRepo(db *bun.DB) (*SQLiteReportRepo, error) {
+	return &SQLiteReportRepo{&PostgresReportRepo{db: db}}, nil
+}
+func NewSQLiteBanRepo(db *bun.DB) (*SQLiteBanRepo, error) {
+	return &SQLiteBanRepo{&PostgresBanRepo{db: db}}, nil
+}
+func NewSQLiteAuditRepo(db *bun.DB) (*SQLiteAuditRepo, error) {
+	return &SQLiteAuditRepo{&PostgresAuditRepo{db: db}}, nil
+}
+
+// NewSQLiteTransactor транзакции SQLite работают так же, как в Postgres.
+func NewSQLiteTransactor(db *bun.DB) *PostgresTransactor {
+	return NewPostgresTransactor(db)
+}
+
+// GetForUpdate пост по id. FOR UPDATE в SQLite нет: транзакция с _txlock=immediate
+// уже держит блокировку записи на всю базу.
+func (r *SQLitePostRepo) GetForUpdate(ctx context.Context, id string) (*models.Post, error) {
+	return r.GetByID(ctx, id)
+}
+
+// PublishDue публикует отложенные посты, время которых пришло.
+// В SQLite один писатель, поэтому SKIP LOCKED не нужен.
+func (r *SQLitePostRepo) PublishDue(ctx context.Context, now time.Time, limit int) ([]*models.Post, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	var ids []string
+	err := conn(ctx, r.db).NewRaw(`
+		UPDATE posts
+		SET status = ?, published_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM posts
+			WHERE status = ? AND publish_at <= ?
+			ORDER BY publish_at
+			LIMIT ?
+		)
+		RETURNING id
+	`, models.PostStatusPublished, now, now, models.PostStatusScheduled, now, limit).Scan(ctx, &ids)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("публикация отложенных постов: %w", err)
+	}
+	if len(ids) == 0 {
+		return []*models.Post{}, nil
+	}
+
+	posts := make([]*models.Post, 0, len(ids))
+	err = selectPosts(conn(ctx, r.db)).
+		Where("p.id IN (?)", bun.In(ids)).
+		Order("p.publish_at ASC").
+		Scan(ctx, &posts)
+	if err != nil {
+		return nil, fmt.Errorf("опубликованные посты: %w", err)
+	}
+	initPostComments(posts...)
+	return posts, nil
+}
+
+// MarkRead сохраняет время прочтения поста, не сдвигая его назад.
+// Вместо GREATEST в SQLite - MAX с двумя аргументами.
+func (r *SQLiteReadStateRepo) MarkRead(ctx context.Context, userID, postID string, at time.Time) error {
+	_, err := conn(ctx, r.db).NewRaw(`
+		INSERT INTO post_reads (user_id, post_id, last_read_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT (user_id, post_id)
+		DO UPDATE SET last_read_at = MAX(post_reads.last_read_at, excluded.last_read_at)
+	`, userID, postID, at).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("прочтение поста: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/config/migrate"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	sqliteAlice = "11111111-1111-1111-1111-111111111111"
+	sqliteBob   = "22222222-2222-2222-2222-222222222222"
+)
+
+// newSQLiteTestDB временная база SQLite со схемой из migrations/sqlite.
+func newSQLiteTestDB(t *testing.T) *bun.DB {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := migrate.RunSQLiteMigrations(path); err != nil {
+		t.Fatalf("миграция: %v", err)
+	}
+	sqldb, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate")
+	if err != nil {
+		t.Fatalf("открытие: %v", err)
+	}
+	t.Cleanup(func() { _ = sqldb.Close() })
+	return bun.NewDB(sqldb, sqlitedialect.New())
+}
+
+// Тест на методы, переопределенные для SQLite.
+func TestSQLiteRepo_Overrides(t *testing.T) {
+	ctx := context.Background()
+	db := newSQLiteTestDB(t)
+	posts, _ := NewSQLitePostRepo(db)
+	comments, _ := NewSQLiteCommentRepo(db)
+	reads, _ := NewSQLiteReadStateRepo(db)
+
+	now := time.Now().UTC()
+	due, later := now.Add(-time.Minute), now.Add(time.Hour)
+	scheduled := models.PostStatusScheduled
+	for _, at := range []*time.Time{&due, &later} {
+		_, err := posts.Create(ctx, &models.Post{Author: &models.User{ID: sqliteBob}, Title: "t", Body: "b", Status: scheduled, PublishAt: at})
+		if err != nil {
+			t.Fatalf("при создинии поста: %v", err)
+		}
+	}
+
+	published, err := posts.PublishDue(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("публикация: %v", err)
+	}
+	if len(published) != 1 || published[0].Status != models.PostStatusPublished {
+		t.Fatalf("ожидался один опубликованный пост, а получили %d", len(published))
+	}
+	if again, err := posts.PublishDue(ctx, now, 10); err != nil || len(again) != 0 {
+		t.Fatalf("пост опубликован повторно: %d, %v", len(again), err)
+	}
+
+	postID := published[0].ID
+	if _, err := comments.Create(ctx, &models.Comment{PostID: postID, Author: &models.User{ID: sqliteBob}, Body: "c"}); err != nil {
+		t.Fatalf("комментарий: %v", err)
+	}
+	if err := reads.MarkRead(ctx, sqliteAlice, postID, time.Now().UTC()); err != nil {
+		t.Fatalf("прочтение: %v", err)
+	}
+	// Более раннее прочтение не сдвигает время назад.
+	if err := reads.MarkRead(ctx, sqliteAlice, postID, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("прочтение: %v", err)
+	}
+	unread, err := reads.CountUnread(ctx, postID, auth.Viewer{UserID: sqliteAlice})
+	if err != nil {
+		t.Fatalf("непрочитанные: %v", err)
+	}
+	if unread != 0 {
+		t.Fatalf("ожидалось 0 непрочитанных, а получили %d", unread)
+	}
+}
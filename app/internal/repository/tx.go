@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/uptrace/bun"
+)
+
+// Transactor единица работы: выполняет fn в одной транзакции хранилища.
+// Репозитории, вызванные с контекстом fn, работают внутри этой транзакции,
+// а PostRepo.GetForUpdate блокирует пост до ее конца.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type (
+	// PostgresTransactor кладет транзакцию в контекст, репозитории берут ее через conn.
+	PostgresTransactor struct {
+		db *bun.DB
+	}
+
+	// MemoryTransactor единица работы держит блокировку записи хранилища, поэтому
+	// транзакции и одиночные вызовы репозиториев не пересекаются. При ошибке
+	// изменения откатываются по журналу отката.
+	MemoryTransactor struct {
+		st *MemoryStorage
+	}
+)
+
+// txHolder транзакция в контексте. После завершения обнуляется, чтобы
+// резолверы дочерних полей, которые gqlgen запускает позже, шли в пул.
+type txHolder struct {
+	tx atomic.Pointer[bun.Tx]
+	afterCommit
+}
+
+type txKey struct{}
+
+// memoryTx транзакция памяти в контексте. undo прежние значения ключей,
+// измененных в транзакции, в виде операций журнала.
+type memoryTx struct {
+	st      *MemoryStorage
+	active  atomic.Bool
+	undo    map[tableKey]walOp
+	undoErr error
+	afterCommit
+}
+
+type memoryTxKey struct{}
+
+func NewPostgresTransactor(db *bun.DB) *PostgresTransactor {
+	return &PostgresTransactor{db: db}
+}
+
+// InTx открывает транзакцию, вложенный вызов работает в уже открытой.
+func (t *PostgresTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if h, ok := ctx.Value(txKey{}).(*txHolder); ok && h.tx.Load() != nil {
+		return fn(ctx)
+	}
+
+	h := &txHolder{}
+	err := t.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		h.tx.Store(&tx)
+		defer h.tx.Store(nil)
+		return fn(context.WithValue(ctx, txKey{}, h))
+	})
+	if err != nil {
+		return err
+	}
+	h.run()
+	return nil
+}
+
+func NewMemoryTransactor(st *MemoryStorage) *MemoryTransactor {
+	return &MemoryTransactor{st: st}
+}
+
+// InTx берет блокировку записи хранилища, вложенный вызов работает под уже взятой.
+// Ошибка или паника fn откатывает изменения, успешная транзакция пишется в журнал
+// на диске одним кадром.
+func (t *MemoryTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	st := t.st
+	if st.inTx(ctx) {
+		return fn(ctx)
+	}
+
+	st.mu.Lock()
+	tx := &memoryTx{st: st, undo: map[tableKey]walOp{}}
+	tx.active.Store(true)
+	st.tx = tx
+	committed := false
+	defer func() {
+		tx.active.Store(false)
+		st.tx = nil
+		if !committed {
+			if rerr := st.rollbackLocked(tx); rerr != nil {
+				err = errors.Join(err, rerr)
+			}
+		}
+		st.unlock(ctx, &err)
+		if committed && err == nil {
+			tx.run()
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, memoryTxKey{}, tx)); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// afterCommit действия, отложенные до фиксации транзакции.
+type afterCommit struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+func (a *afterCommit) add(fn func()) {
+	a.mu.Lock()
+	a.fns = append(a.fns, fn)
+	a.mu.Unlock()
+}
+
+func (a *afterCommit) run() {
+	a.mu.Lock()
+	fns := a.fns
+	a.fns = nil
+	a.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// AfterCommit выполняет fn после фиксации транзакции из контекста, вне транзакции сразу.
+// При откате fn не выполняется. Нужен побочным эффектам: рассылке подписчикам и
+// уведомлениям, которые не должны видеть незафиксированные данные.
+func AfterCommit(ctx context.Context, fn func()) {
+	if h, ok := ctx.Value(txKey{}).(*txHolder); ok && h.tx.Load() != nil {
+		h.add(fn)
+		return
+	}
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok && tx.active.Load() {
+		tx.add(fn)
+		return
+	}
+	fn()
+}
+
+// inTx вызов внутри транзакции этого хранилища: блокировка уже взята.
+func (st *MemoryStorage) inTx(ctx context.Context) bool {
+	tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx)
+	return ok && tx.st == st && tx.active.Load()
+}
+
+// remember запоминает значения ключей до первого изменения в транзакции.
+func (tx *memoryTx) remember(table string, keys ...string) {
+	for _, key := range keys {
+		tk := tableKey{table: table, key: key}
+		if _, ok := tx.undo[tk]; ok {
+			continue
+		}
+		op, err := tx.st.opLocked(table, key)
+		if err != nil {
+			tx.undoErr = errors.Join(tx.undoErr, err)
+			continue
+		}
+		tx.undo[tk] = op
+	}
+}
+
+// rollbackLocked возвращает ключам значения до транзакции.
+func (st *MemoryStorage) rollbackLocked(tx *memoryTx) error {
+	errs := []error{tx.undoErr}
+	for _, op := range tx.undo {
+		if err := st.applyLocked(op); err != nil {
+			errs = append(errs, fmt.Errorf("откат %s/%s: %w", op.Table, op.Key, err))
+		}
+	}
+	st.rebuildOrderLocked()
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("откат транзакции: %w", err)
+	}
+	return nil
+}
+
+type primaryKey struct{}
+
+// WithPrimary все чтения с этим контекстом идут в основную базу, а не в реплику.
+// Нужен мутациям: ответ должен видеть только что записанные данные.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// readConn для читающих методов: транзакция из контекста, иначе реплика, если она задана.
+func readConn(ctx context.Context, db, replica *bun.DB) bun.IDB {
+	if replica == nil || ctx.Value(primaryKey{}) != nil {
+		return conn(ctx, db)
+	}
+	if h, ok := ctx.Value(txKey{}).(*txHolder); ok && h.tx.Load() != nil {
+		return conn(ctx, db)
+	}
+	return replica
+}
+
+// conn транзакция из контекста или пул.
+func conn(ctx context.Context, db *bun.DB) bun.IDB {
+	if h, ok := ctx.Value(txKey{}).(*txHolder); ok {
+		if tx := h.tx.Load(); tx != nil {
+			return *tx
+		}
+	}
+	return db
+}
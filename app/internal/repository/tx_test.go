@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+)
+
+// Тест на единицу работы: проверка и запись в одной транзакции.
+func TestTransactor_InTx(t *testing.T) {
+	tests := []struct {
+		name string
+		open func(t *testing.T) (Transactor, conformanceRepos)
+	}{
+		{name: "memory", open: func(t *testing.T) (Transactor, conformanceRepos) {
+			st := NewMemoryStorageWithTTL(0)
+			st.SeedUsers(conformanceUsers...)
+			return NewMemoryTransactor(st), conformanceRepos{users: NewMemoryUserRepo(st), posts: NewMemoryPostRepo(st), comments: NewMemoryCommentRepo(st)}
+		}},
+		{name: "sqlite", open: func(t *testing.T) (Transactor, conformanceRepos) {
+			db := newSQLiteTestDB(t)
+			posts, _ := NewSQLitePostRepo(db)
+			comments, _ := NewSQLiteCommentRepo(db)
+			return NewSQLiteTransactor(db), conformanceRepos{posts: posts, comments: comments}
+		}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			tx, r := tc.open(t)
+			post := createConformPost(t, r, conformanceUsers[1].ID, "")
+
+			// Каждый пишет комментарий и выключает комментарии: пройти должен только первый.
+			var wg sync.WaitGroup
+			errs := make(chan error, 8)
+			for i := 0; i < cap(errs); i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- tx.InTx(ctx, func(ctx context.Context) error {
+						p, err := r.posts.GetForUpdate(ctx, post.ID)
+						if err != nil || !p.CommentsEnabled {
+							return err
+						}
+						if _, err := r.comments.Create(ctx, &models.Comment{PostID: post.ID, Author: &models.User{ID: conformanceUsers[0].ID}, Body: "c"}); err != nil {
+							return err
+						}
+						// Вложенная единица работы идет в той же транзакции.
+						return tx.InTx(ctx, func(ctx context.Context) error {
+							_, err := r.posts.SetCommentsEnabled(ctx, post.ID, false)
+							return err
+						})
+					})
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatalf("транзакция: %v", err)
+				}
+			}
+
+			list, _, err := r.comments.ListByParent(ctx, post.ID, nil, 20, nil, models.CommentOrderOldest, auth.Viewer{})
+			if err != nil {
+				t.Fatalf("список комментариев: %v", err)
+			}
+			if len(list) != 1 {
+				t.Fatalf("ожидался один комментарий, а получили %d", len(list))
+			}
+
+			errBoom := errors.New("boom")
+			var created *models.Post
+			err = tx.InTx(ctx, func(ctx context.Context) error {
+				if _, err := r.posts.SetCommentsEnabled(ctx, post.ID, true); err != nil {
+					return err
+				}
+				var err error
+				if created, err = r.posts.Create(ctx, &models.Post{Author: &models.User{ID: conformanceUsers[1].ID}, Title: "t", Body: "b"}); err != nil {
+					return err
+				}
+				return errBoom
+			})
+			if !errors.Is(err, errBoom) {
+				t.Fatalf("ожидалась ошибка %v, а получили %v", errBoom, err)
+			}
+			p, err := r.posts.GetByID(ctx, post.ID)
+			if err != nil {
+				t.Fatalf("пост: %v", err)
+			}
+			if p.CommentsEnabled {
+				t.Fatal("откат: ожидалось commentsEnabled=false")
+			}
+			if _, err := r.posts.GetByID(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("откат: пост из транзакции остался, ошибка %v", err)
+			}
+			posts, _, err := r.posts.List(ctx, 10, nil, auth.Viewer{})
+			if err != nil {
+				t.Fatalf("список постов: %v", err)
+			}
+			if len(posts) != 1 {
+				t.Fatalf("откат: ожидался один пост в списке, а получили %d", len(posts))
+			}
+		})
+	}
+}
+
+// Тест на чтение из реплики: списки вне транзакции идут в реплику,
+// поиск по id, транзакции и мутации - в основную базу.
+func TestReadConn_Replica(t *testing.T) {
+	primary, replica := newSQLiteTestDB(t), newSQLiteTestDB(t)
+	posts, _ := NewPostgresPostRepo(primary, WithReplica(replica))
+	post := createConformPost(t, conformanceRepos{posts: posts}, conformanceUsers[1].ID, "")
+
+	tests := []struct {
+		name string
+		ctx  func(ctx context.Context, fn func(ctx context.Context))
+		want int
+	}{
+		{name: "Вне транзакции из реплики", ctx: func(ctx context.Context, fn func(ctx context.Context)) { fn(ctx) }, want: 0},
+		{name: "В транзакции из основной", ctx: func(ctx context.Context, fn func(ctx context.Context)) {
+			_ = NewPostgresTransactor(primary).InTx(ctx, func(ctx context.Context) error { fn(ctx); return nil })
+		}, want: 1},
+		{name: "Мутация из основной", ctx: func(ctx context.Context, fn func(ctx context.Context)) { fn(WithPrimary(ctx)) }, want: 1},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.ctx(context.Background(), func(ctx context.Context) {
+				list, _, err := posts.List(ctx, 10, nil, auth.Viewer{})
+				if err != nil {
+					t.Fatalf("список постов: %v", err)
+				}
+				if len(list) != tc.want {
+					t.Fatalf("ожидалось %d постов, а получили %d", tc.want, len(list))
+				}
+			})
+		})
+	}
+
+	if p, err := posts.GetByID(context.Background(), post.ID); err != nil || p.ID != post.ID {
+		t.Fatalf("GetByID должен читать основную базу: %v, %v", p, err)
+	}
+}
+
+// Тест на отложенные действия: выполняются после фиксации внешней транзакции, при откате - нет.
+func TestAfterCommit(t *testing.T) {
+	tests := []struct {
+		name string
+		open func(t *testing.T) Transactor
+	}{
+		{name: "memory", open: func(t *testing.T) Transactor { return NewMemoryTransactor(NewMemoryStorageWithTTL(0)) }},
+		{name: "sqlite", open: func(t *testing.T) Transactor { return NewSQLiteTransactor(newSQLiteTestDB(t)) }},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			tx := tc.open(t)
+
+			ran := 0
+			AfterCommit(ctx, func() { ran++ })
+			if ran != 1 {
+				t.Fatal("вне транзакции действие выполняется сразу")
+			}
+
+			err := tx.InTx(ctx, func(ctx context.Context) error {
+				return tx.InTx(ctx, func(ctx context.Context) error {
+					AfterCommit(ctx, func() { ran++ })
+					if ran != 1 {
+						t.Error("действие выполнено до фиксации")
+					}
+					return nil
+				})
+			})
+			if err != nil || ran != 2 {
+				t.Fatalf("после фиксации ожидалось 2 выполнения, а получили %d: %v", ran, err)
+			}
+
+			errBoom := errors.New("boom")
+			err = tx.InTx(ctx, func(ctx context.Context) error {
+				AfterCommit(ctx, func() { ran++ })
+				return errBoom
+			})
+			if !errors.Is(err, errBoom) || ran != 2 {
+				t.Fatalf("при откате действие не выполняется: %d, %v", ran, err)
+			}
+		})
+	}
+}
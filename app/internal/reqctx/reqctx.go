@@ -0,0 +1,65 @@
+package reqctx
+
+import (
+	"context"
+	"sync"
+)
+
+// HeaderRequestID заголовок с id запроса, входящий используется как есть.
+const HeaderRequestID = "X-Request-ID"
+
+// Info данные HTTP запроса, нужные ниже по стеку.
+type Info struct {
+	RequestID string
+	IP        string
+	// Operation операция GraphQL вида "query Feed", пусто вне операции.
+	Operation string
+}
+
+type infoKey struct{}
+
+// With кладет данные запроса в контекст.
+func With(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// From достает данные запроса, пустые вне HTTP запроса.
+func From(ctx context.Context) Info {
+	info, _ := ctx.Value(infoKey{}).(Info)
+	return info
+}
+
+// operations операции GraphQL одного HTTP запроса для журнала доступа.
+type operations struct {
+	mu    sync.Mutex
+	names []string
+}
+
+type operationsKey struct{}
+
+// WithOperations начинает сбор операций запроса, см. AddOperation.
+func WithOperations(ctx context.Context) context.Context {
+	return context.WithValue(ctx, operationsKey{}, &operations{})
+}
+
+// AddOperation запоминает операцию, без WithOperations ничего не делает.
+func AddOperation(ctx context.Context, name string) {
+	ops, ok := ctx.Value(operationsKey{}).(*operations)
+	if !ok {
+		return
+	}
+	ops.mu.Lock()
+	ops.names = append(ops.names, name)
+	ops.mu.Unlock()
+}
+
+// Operations операции, выполненные в запросе.
+func Operations(ctx context.Context) []string {
+	ops, ok := ctx.Value(operationsKey{}).(*operations)
+	if !ok {
+		return nil
+	}
+	ops.mu.Lock()
+	defer ops.mu.Unlock()
+	return append([]string(nil), ops.names...)
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+	"github.com/RoGogDBD/GQLGo/internal/reqctx"
+)
+
+// AuditService журнал мутаций: кто, что и с каким результатом изменил.
+type AuditService struct {
+	repo    repository.AuditRepo
+	tx      repository.Transactor
+	posts   repository.PostRepo
+	users   repository.UserRepo
+	reports repository.ReportRepo
+}
+
+func NewAuditService(repo repository.AuditRepo, tx repository.Transactor, posts repository.PostRepo, users repository.UserRepo, reports repository.ReportRepo) *AuditService {
+	return &AuditService{repo: repo, tx: tx, posts: posts, users: users, reports: reports}
+}
+
+// Mutation выполняет мутацию run в транзакции вместе с записью журнала.
+// targetType - тип результата мутации, по нему загружается состояние цели до изменения.
+// Неудачные мутации не записываются.
+func (s *AuditService) Mutation(ctx context.Context, action, targetType, targetID string, run func(ctx context.Context) (any, error)) (any, error) {
+	var res any
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		before, err := s.load(ctx, targetType, targetID)
+		if err != nil {
+			return err
+		}
+		out, err := run(ctx)
+		if err != nil {
+			return err
+		}
+		res = out
+
+		entry, err := s.entry(ctx, action, targetID, before, out)
+		if err != nil {
+			return err
+		}
+		return s.repo.Append(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// List журнал мутаций, доступен только администраторам.
+func (s *AuditService) List(ctx context.Context, filter *models.AuditFilter, first int32, after *string) ([]*models.AuditEntry, *string, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return nil, nil, ErrUnauthorized
+	}
+	if viewer.Role != models.RoleAdmin {
+		return nil, nil, ErrForbidden
+	}
+	var f models.AuditFilter
+	if filter != nil {
+		f = *filter
+	}
+	entries, last, err := s.repo.List(ctx, f, first, after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.fillActors(ctx, entries); err != nil {
+		return nil, nil, err
+	}
+	return entries, last, nil
+}
+
+// fillActors подставляет авторов записей, которых хранилище журнала не знает.
+func (s *AuditService) fillActors(ctx context.Context, entries []*models.AuditEntry) error {
+	users := make(map[string]*models.User)
+	for _, e := range entries {
+		if e.Actor == nil || e.Actor.Username != "" {
+			continue
+		}
+		u, ok := users[e.Actor.ID]
+		if !ok {
+			var err error
+			if u, err = s.users.GetByID(ctx, e.Actor.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			users[e.Actor.ID] = u
+		}
+		if u != nil {
+			e.Actor = u
+		}
+	}
+	return nil
+}
+
+// load состояние цели до мутации, nil для неизвестных типов и отсутствующих целей.
+func (s *AuditService) load(ctx context.Context, targetType, targetID string) (any, error) {
+	if targetID == "" {
+		return nil, nil
+	}
+	var (
+		target any
+		err    error
+	)
+	switch targetType {
+	case "Post":
+		target, err = s.posts.GetByID(ctx, targetID)
+	case "User":
+		target, err = s.users.GetByID(ctx, targetID)
+	case "Report":
+		target, err = s.reports.GetByID(ctx, targetID)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return target, nil
+}
+
+func (s *AuditService) entry(ctx context.Context, action, targetID string, before, after any) (*models.AuditEntry, error) {
+	e := &models.AuditEntry{Action: action}
+	if viewer, ok := auth.ViewerFrom(ctx); ok {
+		e.Actor = &models.User{ID: viewer.UserID}
+	}
+	if typ, id, ok := entityRef(after); ok {
+		e.TargetType, targetID = &typ, id
+	}
+	if targetID != "" {
+		e.TargetID = &targetID
+	}
+	if info := reqctx.From(ctx); info.RequestID != "" || info.IP != "" {
+		e.RequestID = optional(info.RequestID)
+		e.IP = optional(info.IP)
+	}
+
+	diff, err := Diff(before, after)
+	if err != nil {
+		return nil, err
+	}
+	e.Diff = &diff
+	return e, nil
+}
+
+// Diff JSON изменившихся полей вида {"поле": {"before": ..., "after": ...}}.
+// Результат не-объект записывается как {"result": значение}.
+func Diff(before, after any) (string, error) {
+	b, err := toFields(before)
+	if err != nil {
+		return "", err
+	}
+	a, err := toFields(after)
+	if err != nil {
+		return "", err
+	}
+	if a == nil {
+		out, err := json.Marshal(map[string]any{"result": after})
+		if err != nil {
+			return "", fmt.Errorf("журнал мутаций: %w", err)
+		}
+		return string(out), nil
+	}
+
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make(map[string]map[string]any)
+	for _, k := range keys {
+		if reflect.DeepEqual(b[k], a[k]) {
+			continue
+		}
+		changes[k] = map[string]any{"before": b[k], "after": a[k]}
+	}
+	out, err := json.Marshal(changes)
+	if err != nil {
+		return "", fmt.Errorf("журнал мутаций: %w", err)
+	}
+	return string(out), nil
+}
+
+// toFields поля объекта по его JSON, nil если значение не объект.
+func toFields(v any) (map[string]any, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("журнал мутаций: %w", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// entityRef тип и id сущности - результата мутации.
+func entityRef(v any) (string, string, bool) {
+	switch e := v.(type) {
+	case *models.Post:
+		if e != nil {
+			return "Post", e.ID, true
+		}
+	case *models.Comment:
+		if e != nil {
+			return "Comment", e.ID, true
+		}
+	case *models.User:
+		if e != nil {
+			return "User", e.ID, true
+		}
+	case *models.Report:
+		if e != nil {
+			return "Report", e.ID, true
+		}
+	case *models.Ban:
+		if e != nil {
+			return "Ban", e.ID, true
+		}
+	}
+	return "", "", false
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/reqctx"
+)
+
+// Тест на запись мутаций в журнал.
+func TestAuditService_Mutation(t *testing.T) {
+	errRun := errors.New("мутация упала")
+
+	tests := []struct {
+		name        string
+		role        models.Role
+		runErr      error
+		wantLogged  bool
+		wantListErr error
+	}{
+		{name: "Успешная мутация", role: models.RoleAdmin, wantLogged: true},
+		{name: "Неудачная мутация не пишется", role: models.RoleAdmin, runErr: errRun},
+		{name: "Журнал только для администратора", role: models.RoleModerator, wantLogged: true, wantListErr: ErrForbidden},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			svc := env.auditService()
+
+			ctx := auth.WithViewer(context.Background(), auth.Viewer{UserID: "bob", Role: tc.role})
+			ctx = reqctx.With(ctx, reqctx.Info{RequestID: "req-1", IP: "127.0.0.1"})
+
+			post := env.createPost(t, "bob", "")
+
+			_, err := svc.Mutation(ctx, "setCommentsEnabled", "Post", post.ID, func(ctx context.Context) (any, error) {
+				if tc.runErr != nil {
+					return nil, tc.runErr
+				}
+				return env.posts.SetCommentsEnabled(ctx, post.ID, false)
+			})
+			if !errors.Is(err, tc.runErr) {
+				t.Fatalf("ожидалась ошибка %v, а получили %v", tc.runErr, err)
+			}
+
+			entries, _, err := env.audit.List(ctx, models.AuditFilter{}, 10, nil)
+			if err != nil {
+				t.Fatalf("журнал: %v", err)
+			}
+			if !tc.wantLogged {
+				if len(entries) != 0 {
+					t.Fatalf("ожидался пустой журнал, а получили %d", len(entries))
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("ожидалась одна запись, а получили %d", len(entries))
+			}
+			e := entries[0]
+			if e.Actor == nil || e.Actor.ID != "bob" || e.Action != "setCommentsEnabled" {
+				t.Fatalf("неверная запись: %+v", e)
+			}
+			if e.TargetType == nil || *e.TargetType != "Post" || e.TargetID == nil || *e.TargetID != post.ID {
+				t.Fatalf("неверная цель записи: %+v", e)
+			}
+			if e.RequestID == nil || *e.RequestID != "req-1" || e.IP == nil || *e.IP != "127.0.0.1" {
+				t.Fatalf("неверные данные запроса: %+v", e)
+			}
+
+			var diff map[string]map[string]any
+			if err := json.Unmarshal([]byte(*e.Diff), &diff); err != nil {
+				t.Fatalf("diff не JSON: %v", err)
+			}
+			if len(diff) != 1 || diff["commentsEnabled"]["before"] != true || diff["commentsEnabled"]["after"] != false {
+				t.Fatalf("неверный diff: %s", *e.Diff)
+			}
+
+			_, _, err = svc.List(ctx, nil, 10, nil)
+			if !errors.Is(err, tc.wantListErr) {
+				t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantListErr, err)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package service
 
 import (
 	"errors"
-	"sync"
 
 	"github.com/RoGogDBD/GQLGo/internal/logger"
 	"github.com/RoGogDBD/GQLGo/internal/models"
@@ -13,24 +12,17 @@ var (
 	ErrSendFailed     = errors.New("не удалось отправить коммент")
 	ErrEmptyPostID    = errors.New("пустой postID")
 	ErrPostIDMismatch = errors.New("postID комментария не совпадает с postID публикации")
+	ErrNotifierClosed = errors.New("рассылка комментариев остановлена")
 )
 
-type (
-	CommentNotifier struct {
-		mu       sync.RWMutex
-		byPostID map[string][]commentSubscriber
-		logger   logger.Logger
-	}
-
-	commentSubscriber struct {
-		stream chan *models.Comment
-		done   chan struct{}
-	}
-)
+type CommentNotifier struct {
+	byPostID *Notifier[*models.Comment]
+	logger   logger.Logger
+}
 
 func NewCommentNotifier(logger logger.Logger) *CommentNotifier {
 	return &CommentNotifier{
-		byPostID: make(map[string][]commentSubscriber),
+		byPostID: NewNotifier[*models.Comment](),
 		logger:   logger,
 	}
 }
@@ -39,35 +31,26 @@ func (n *CommentNotifier) Subscribe(postID string) (chan *models.Comment, func()
 	if postID == "" {
 		return nil, nil, ErrEmptyPostID
 	}
-	sub := commentSubscriber{
-		stream: make(chan *models.Comment, 1),
-		done:   make(chan struct{}),
+	if n.byPostID.Closed() {
+		return nil, nil, ErrNotifierClosed
 	}
+	stream, unSub := n.byPostID.Subscribe(postID)
+	return stream, unSub, nil
+}
 
-	n.mu.Lock()
-	n.byPostID[postID] = append(n.byPostID[postID], sub)
-	n.mu.Unlock()
+// Close завершает подписки на комментарии, например при остановке сервера.
+func (n *CommentNotifier) Close() {
+	n.byPostID.Close()
+}
 
-	unSub := func() {
-		n.mu.Lock()
-		subscribers := n.byPostID[postID]
-		for i, s := range subscribers {
-			if s.stream == sub.stream {
-				close(s.done)
-				close(s.stream)
-				subscribers = append(subscribers[:i], subscribers[i+1:]...)
-				break
-			}
-		}
-		if len(subscribers) == 0 {
-			delete(n.byPostID, postID)
-		} else {
-			n.byPostID[postID] = subscribers
-		}
-		n.mu.Unlock()
-	}
+// Subscribers число подписчиков по постам.
+func (n *CommentNotifier) Subscribers() map[string]int {
+	return n.byPostID.Subscribers()
+}
 
-	return sub.stream, unSub, nil
+// Dropped сколько комментариев не доставлено подписчикам.
+func (n *CommentNotifier) Dropped() uint64 {
+	return n.byPostID.Dropped()
 }
 
 func (n *CommentNotifier) Publish(postID string, c *models.Comment) error {
@@ -85,31 +68,5 @@ func (n *CommentNotifier) Publish(postID string, c *models.Comment) error {
 		return err
 	}
 
-	n.mu.RLock()
-	subscribers := append([]commentSubscriber(nil), n.byPostID[postID]...)
-	n.mu.RUnlock()
-
-	for _, sub := range subscribers {
-		if !n.trySend(sub, c) {
-			errs = append(errs, ErrSendFailed)
-		}
-	}
-	return errors.Join(errs...)
-}
-
-func (n *CommentNotifier) trySend(sub commentSubscriber, c *models.Comment) (sent bool) {
-	defer func() {
-		if recover() != nil {
-			sent = false
-		}
-	}()
-
-	select {
-	case <-sub.done:
-		return false
-	case sub.stream <- c:
-		return true
-	default:
-		return false
-	}
+	return n.byPostID.Publish(postID, c)
 }
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/RoGogDBD/GQLGo/internal/filter"
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+	"github.com/RoGogDBD/GQLGo/internal/markup"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+	"github.com/RoGogDBD/GQLGo/internal/utils/graph"
+)
+
+type CommentService struct {
+	posts         *PostService
+	repo          repository.CommentRepo
+	tx            repository.Transactor
+	notifier      *CommentNotifier
+	notifications *NotificationService
+	moderation    *ModerationService
+	logger        logger.Logger
+}
+
+func NewCommentService(posts *PostService, repo repository.CommentRepo, tx repository.Transactor, notifier *CommentNotifier, notifications *NotificationService, moderation *ModerationService, logger logger.Logger) *CommentService {
+	return &CommentService{
+		posts:         posts,
+		repo:          repo,
+		tx:            tx,
+		notifier:      notifier,
+		notifications: notifications,
+		moderation:    moderation,
+		logger:        logger,
+	}
+}
+
+// Add проверяет и сохраняет комментарий, рендерит тело и после фиксации рассылает подписчикам.
+// Проверка поста и родителя и запись идут в одной транзакции с блокировкой поста,
+// поэтому комментарий не появится после выключения комментариев.
+func (s *CommentService) Add(ctx context.Context, input models.AddCommentInput) (*models.Comment, error) {
+	if input.PostID == "" {
+		return nil, fmt.Errorf("требуется id поста")
+	}
+	if input.AuthorID == "" {
+		return nil, fmt.Errorf("требуется id автора")
+	}
+
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		return nil, fmt.Errorf("требуется тело коммента")
+	}
+	if len(body) > 2000 {
+		return nil, fmt.Errorf("тело комментария длинное (<= 2000)")
+	}
+
+	if err := s.moderation.CheckAuthor(ctx, input.AuthorID); err != nil {
+		return nil, err
+	}
+
+	state, reasons, err := s.moderation.Screen(ctx, filter.Content{
+		Kind:     filter.KindComment,
+		AuthorID: input.AuthorID,
+		Text:     body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		post    *models.Post
+		comment *models.Comment
+	)
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if post, err = s.posts.GetForUpdate(ctx, input.PostID); err != nil {
+			return err
+		}
+		if !post.CommentsEnabled {
+			return fmt.Errorf("комментарии отключены")
+		}
+
+		depth, err := graph.ResolveCommentDepth(ctx, s.repo, input.PostID, input.ParentID)
+		if err != nil {
+			return err
+		}
+
+		comment, err = s.repo.Create(ctx, &models.Comment{
+			PostID:          input.PostID,
+			Author:          &models.User{ID: input.AuthorID},
+			Body:            body,
+			BodyHTML:        markup.Render(body),
+			ParentID:        input.ParentID,
+			Depth:           int32(depth),
+			ModerationState: state,
+		})
+		if err != nil || state != models.ModerationStatePending {
+			return err
+		}
+		// Без жалобы комментарий на модерации не попадет в очередь и останется скрытым.
+		return s.moderation.Flag(ctx, models.ReportTargetTypeComment, comment.ID, reasons)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if state == models.ModerationStatePending {
+		// на модерации: без рассылки подписчикам и уведомлений
+		return comment, nil
+	}
+	// Мутация может идти во внешней транзакции: рассылаем только после ее фиксации.
+	repository.AfterCommit(ctx, func() {
+		if s.notifier != nil {
+			if err := s.notifier.Publish(input.PostID, comment); err != nil {
+				s.logger.Ctx(ctx).With("post_id", input.PostID).Errorf("comment notifier publish: %v", err)
+			}
+		}
+		s.notifications.CommentAdded(ctx, post, comment, s.parentAuthorID(ctx, input.ParentID))
+	})
+	return comment, nil
+}
+
+// parentAuthorID автор родительского комментария для уведомления об ответе.
+func (s *CommentService) parentAuthorID(ctx context.Context, parentID *string) string {
+	if s.notifications == nil || parentID == nil || *parentID == "" {
+		return ""
+	}
+	parent, err := s.repo.GetByID(ctx, *parentID)
+	if err != nil {
+		s.logger.Ctx(ctx).With("comment_id", *parentID).Errorf("родительский комментарий: %v", err)
+		return ""
+	}
+	if parent.Author == nil {
+		return ""
+	}
+	return parent.Author.ID
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+)
+
+type commentRepoStub struct {
+	created *models.Comment
+}
+
+func (s *commentRepoStub) GetByID(context.Context, string) (*models.Comment, error) {
+	return nil, nil
+}
+
+func (s *commentRepoStub) GetMeta(context.Context, string) (string, int, error) {
+	return "p1", 0, nil
+}
+
+func (s *commentRepoStub) Create(_ context.Context, c *models.Comment) (*models.Comment, error) {
+	cp := *c
+	cp.ID = "c1"
+	s.created = &cp
+	return &cp, nil
+}
+
+func (s *commentRepoStub) ListByParent(context.Context, string, *string, int32, *string, models.CommentOrder, auth.Viewer) ([]*models.Comment, *string, error) {
+	return nil, nil, nil
+}
+
+func (s *commentRepoStub) SetModerationState(context.Context, string, models.ModerationState) (*models.Comment, error) {
+	return nil, nil
+}
+
+func (s *commentRepoStub) HideByAuthor(context.Context, string) (int, error) {
+	return 0, nil
+}
+
+type loggerStub struct{}
+
+func (loggerStub) Debugf(string, ...any)               {}
+func (loggerStub) Infof(string, ...any)                {}
+func (loggerStub) Warnf(string, ...any)                {}
+func (loggerStub) Errorf(string, ...any)               {}
+func (l loggerStub) With(...any) logger.Logger         { return l }
+func (l loggerStub) Ctx(context.Context) logger.Logger { return l }
+
+// Тест на добавление комментария и рендер тела.
+func TestCommentService_Add(t *testing.T) {
+	published := &models.Post{ID: "p1", Status: models.PostStatusPublished, CommentsEnabled: true, Author: &models.User{ID: "u"}}
+	disabled := &models.Post{ID: "p1", Status: models.PostStatusPublished, CommentsEnabled: false, Author: &models.User{ID: "u"}}
+	draft := &models.Post{ID: "p1", Status: models.PostStatusDraft, CommentsEnabled: true, Author: &models.User{ID: "u"}}
+
+	tests := []struct {
+		name  string
+		post  *models.Post
+		input models.AddCommentInput
+		err   bool
+		html  string
+	}{
+		{
+			name:  "Пустое тело",
+			post:  published,
+			input: models.AddCommentInput{PostID: "p1", AuthorID: "u", Body: "  "},
+			err:   true,
+		},
+		{
+			name:  "Комментарии выключены",
+			post:  disabled,
+			input: models.AddCommentInput{PostID: "p1", AuthorID: "u", Body: "b"},
+			err:   true,
+		},
+		{
+			name:  "Черновик чужого поста",
+			post:  draft,
+			input: models.AddCommentInput{PostID: "p1", AuthorID: "u", Body: "b"},
+			err:   true,
+		},
+		{
+			name:  "Рендер markdown",
+			post:  published,
+			input: models.AddCommentInput{PostID: "p1", AuthorID: "u", Body: "**b**"},
+			html:  "<strong>b</strong>",
+		},
+		{
+			name:  "XSS",
+			post:  published,
+			input: models.AddCommentInput{PostID: "p1", AuthorID: "u", Body: "<script>alert(1)</script>"},
+			html:  "",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &commentRepoStub{}
+			posts := NewPostService(&postRepoStub{post: tc.post}, &tagRepoStub{}, repository.NewMemoryTransactor(repository.NewMemoryStorageWithTTL(0)), nil, nil, nil)
+			svc := NewCommentService(posts, repo, repository.NewMemoryTransactor(repository.NewMemoryStorageWithTTL(0)), NewCommentNotifier(loggerStub{}), nil, nil, loggerStub{})
+
+			c, err := svc.Add(context.Background(), tc.input)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("ожидалась ошибка")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if !strings.Contains(c.BodyHTML, tc.html) || strings.Contains(c.BodyHTML, "<script") {
+				t.Fatalf("неожиданный html %q", c.BodyHTML)
+			}
+		})
+	}
+}
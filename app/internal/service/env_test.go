@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/filter"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+)
+
+// testUsers пользователи, которых окружение тестов кладет в хранилище.
+var testUsers = []*models.User{
+	{ID: "alice", Username: "alice"},
+	{ID: "bob", Username: "bob"},
+	{ID: "mod", Username: "mod", Role: models.RoleModerator},
+	{ID: "mod2", Username: "mod2", Role: models.RoleModerator},
+}
+
+var moderatorViewer = auth.Viewer{UserID: "mod", Role: models.RoleModerator}
+
+// testEnv общее окружение тестов сервисов: хранилище в памяти с пользователями и репозитории.
+// Репозитории и фильтры можно подменить до вызова конструкторов сервисов.
+type testEnv struct {
+	st       *repository.MemoryStorage
+	tx       repository.Transactor
+	users    repository.UserRepo
+	posts    repository.PostRepo
+	comments repository.CommentRepo
+	tags     repository.TagRepo
+	reports  repository.ReportRepo
+	bans     repository.BanRepo
+	audit    repository.AuditRepo
+	filters  *filter.Pipeline
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	st := repository.NewMemoryStorageWithTTL(0)
+	st.SeedUsers(testUsers...)
+	return &testEnv{
+		st:       st,
+		tx:       repository.NewMemoryTransactor(st),
+		users:    repository.NewMemoryUserRepo(st),
+		posts:    repository.NewMemoryPostRepo(st),
+		comments: repository.NewMemoryCommentRepo(st),
+		tags:     repository.NewMemoryTagRepo(st),
+		reports:  repository.NewMemoryReportRepo(st),
+		bans:     repository.NewMemoryBanRepo(st),
+		audit:    repository.NewMemoryAuditRepo(10),
+	}
+}
+
+// createPost пост автора с открытыми комментариями прямо в репозитории, без проверок сервиса.
+func (e *testEnv) createPost(t *testing.T, authorID string, status models.PostStatus) *models.Post {
+	t.Helper()
+	post, err := e.posts.Create(context.Background(), &models.Post{
+		Author:          &models.User{ID: authorID},
+		Title:           "t",
+		Body:            "b",
+		CommentsEnabled: true,
+		Status:          status,
+	})
+	if err != nil {
+		t.Fatalf("при создинии поста: %v", err)
+	}
+	return post
+}
+
+func (e *testEnv) moderationService() *ModerationService {
+	return NewModerationService(e.reports, e.bans, e.posts, e.comments, e.users, e.tx, e.filters, loggerStub{})
+}
+
+func (e *testEnv) postService(moderation *ModerationService) *PostService {
+	return NewPostService(e.posts, e.tags, e.tx, nil, nil, moderation)
+}
+
+func (e *testEnv) commentService(posts *PostService, moderation *ModerationService) *CommentService {
+	return NewCommentService(posts, e.comments, e.tx, nil, nil, moderation, loggerStub{})
+}
+
+func (e *testEnv) auditService() *AuditService {
+	return NewAuditService(e.audit, e.tx, e.posts, e.users, e.reports)
+}
+
+func (e *testEnv) readerService(posts *PostService) *ReaderService {
+	return NewReaderService(posts, repository.NewMemoryBookmarkRepo(e.st), repository.NewMemoryReadStateRepo(e.st))
+}
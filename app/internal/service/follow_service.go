@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+)
+
+var (
+	ErrUserNotFound = repository.NotFound("пользователь не найден")
+	ErrSelfFollow   = errors.New("нельзя подписаться на себя")
+)
+
+// FollowService подписки пользователей и лента.
+type FollowService struct {
+	repo  repository.FollowRepo
+	users repository.UserRepo
+}
+
+func NewFollowService(repo repository.FollowRepo, users repository.UserRepo) *FollowService {
+	return &FollowService{repo: repo, users: users}
+}
+
+// Follow подписывает текущего пользователя на userID и возвращает этого пользователя.
+func (s *FollowService) Follow(ctx context.Context, userID string) (*models.User, error) {
+	viewer, user, err := s.target(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Follow(ctx, viewer.UserID, user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Unfollow отписывает текущего пользователя от userID.
+func (s *FollowService) Unfollow(ctx context.Context, userID string) (*models.User, error) {
+	viewer, user, err := s.target(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Unfollow(ctx, viewer.UserID, user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Feed лента текущего пользователя.
+func (s *FollowService) Feed(ctx context.Context, first int32, after *string) ([]*models.Post, *string, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return nil, nil, ErrUnauthorized
+	}
+	return s.repo.Feed(ctx, viewer.UserID, first, after)
+}
+
+func (s *FollowService) Followers(ctx context.Context, userID string, first int32, after *string) ([]*models.User, *string, error) {
+	return s.repo.ListFollowers(ctx, userID, first, after)
+}
+
+func (s *FollowService) Following(ctx context.Context, userID string, first int32, after *string) ([]*models.User, *string, error) {
+	return s.repo.ListFollowing(ctx, userID, first, after)
+}
+
+func (s *FollowService) CountFollowers(ctx context.Context, userID string) (int, error) {
+	return s.repo.CountFollowers(ctx, userID)
+}
+
+func (s *FollowService) CountFollowing(ctx context.Context, userID string) (int, error) {
+	return s.repo.CountFollowing(ctx, userID)
+}
+
+// target проверяет текущего пользователя и существование userID.
+func (s *FollowService) target(ctx context.Context, userID string) (auth.Viewer, *models.User, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return viewer, nil, ErrUnauthorized
+	}
+	if userID == "" {
+		return viewer, nil, ErrUserNotFound
+	}
+	if viewer.IsAuthor(userID) {
+		return viewer, nil, ErrSelfFollow
+	}
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return viewer, nil, repository.NotFoundAs(err, ErrUserNotFound)
+	}
+	return viewer, user, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+)
+
+// Тест на проверки подписки.
+func TestFollowService_Follow(t *testing.T) {
+	tests := []struct {
+		name    string
+		viewer  string
+		target  string
+		wantErr error
+	}{
+		{name: "Подписка", viewer: "alice", target: "bob"},
+		{name: "Без пользователя", target: "bob", wantErr: ErrUnauthorized},
+		{name: "На себя", viewer: "alice", target: "alice", wantErr: ErrSelfFollow},
+		{name: "Несуществующий", viewer: "alice", target: "nobody", wantErr: ErrUserNotFound},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			st := repository.NewMemoryStorageWithTTL(0)
+			st.SeedUsers(
+				&models.User{ID: "alice", Username: "alice"},
+				&models.User{ID: "bob", Username: "bob"},
+			)
+			repo := repository.NewMemoryFollowRepo(st)
+			svc := NewFollowService(repo, repository.NewMemoryUserRepo(st))
+			ctx := auth.WithViewer(context.Background(), auth.Viewer{UserID: tc.viewer})
+
+			user, err := svc.Follow(ctx, tc.target)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("подписка: %v", err)
+			}
+			if user.ID != tc.target {
+				t.Fatalf("ожидался пользователь %s, а получили %s", tc.target, user.ID)
+			}
+			if n, _ := repo.CountFollowing(ctx, tc.viewer); n != 1 {
+				t.Fatalf("ожидалась 1 подписка, а получили %d", n)
+			}
+		})
+	}
+}
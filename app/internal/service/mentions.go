@@ -0,0 +1,39 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxMentions сколько упоминаний обрабатывается в одном тексте.
+	maxMentions = 20
+	// maxUsernameLength длина как у users.username.
+	maxUsernameLength = 15
+)
+
+// mentionPattern @username не внутри слова или email.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([\p{L}\p{N}_]+)`)
+
+// ParseMentions имена упомянутых пользователей без повторов.
+func ParseMentions(body string) []string {
+	matches := mentionPattern.FindAllStringSubmatch(body, -1)
+	names := make([]string, 0, len(matches))
+	seen := make(map[string]struct{}, len(matches))
+	for _, m := range matches {
+		if utf8.RuneCountInString(m[1]) > maxUsernameLength {
+			continue
+		}
+		key := strings.ToLower(m[1])
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		names = append(names, m[1])
+		if len(names) == maxMentions {
+			break
+		}
+	}
+	return names
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"slices"
+	"testing"
+)
+
+// Тест на разбор упоминаний.
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "Одно упоминание", body: "привет @asd!", want: []string{"asd"}},
+		{name: "Начало строки", body: "@asd и @wevbwb", want: []string{"asd", "wevbwb"}},
+		{name: "Повтор в другом регистре", body: "@asd @ASD", want: []string{"asd"}},
+		{name: "Email не упоминание", body: "пиши на me@asd.ru", want: []string{}},
+		{name: "Двойная собака", body: "@@asd", want: []string{}},
+		{name: "Кириллица", body: "спасибо @вася", want: []string{"вася"}},
+		{name: "Без упоминаний", body: "просто текст", want: []string{}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := ParseMentions(tc.body)
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("ожидалось %v, а получили %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/filter"
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+)
+
+var (
+	ErrUserSuspended    = errors.New("пользователь заблокирован")
+	ErrReportNotFound   = repository.NotFound("жалоба не найдена")
+	ErrTargetNotFound   = repository.NotFound("контент для жалобы не найден")
+	ErrAlreadyReported  = errors.New("жалоба на этот контент уже отправлена")
+	ErrInvalidReason    = errors.New("неверная причина жалобы")
+	ErrInvalidAction    = errors.New("неверное действие модерации")
+	ErrReportNoteLength = errors.New("комментарий к жалобе длинный (<= 500)")
+	ErrContentRejected  = errors.New("контент отклонен фильтрами")
+	ErrBanReason        = errors.New("требуется причина блокировки (<= 500)")
+	ErrBanUntil         = errors.New("срок блокировки уже прошел")
+	ErrSelfBan          = errors.New("нельзя заблокировать себя")
+)
+
+const (
+	// maxReportNoteLength как у reports.note.
+	maxReportNoteLength = 500
+	// maxBanReasonLength как у bans.reason.
+	maxBanReasonLength = 500
+)
+
+// BanError пишет заблокированный пользователь, блокировка уходит в extensions ошибки.
+type BanError struct {
+	Ban *models.Ban
+}
+
+func (e *BanError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrUserSuspended, e.Ban.Reason)
+}
+
+func (e *BanError) Unwrap() error {
+	return ErrUserSuspended
+}
+
+// ModerationService жалобы на контент и действия модераторов.
+type ModerationService struct {
+	reports  repository.ReportRepo
+	bans     repository.BanRepo
+	posts    repository.PostRepo
+	comments repository.CommentRepo
+	users    repository.UserRepo
+	tx       repository.Transactor
+	filters  *filter.Pipeline
+	logger   logger.Logger
+}
+
+func NewModerationService(reports repository.ReportRepo, bans repository.BanRepo, posts repository.PostRepo, comments repository.CommentRepo, users repository.UserRepo, tx repository.Transactor, filters *filter.Pipeline, logger logger.Logger) *ModerationService {
+	return &ModerationService{reports: reports, bans: bans, posts: posts, comments: comments, users: users, tx: tx, filters: filters, logger: logger}
+}
+
+// Report жалоба текущего пользователя на пост или комментарий, тип определяется по id.
+func (s *ModerationService) Report(ctx context.Context, targetID string, reason models.ReportReason, note *string) (*models.Report, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	if !reason.IsValid() {
+		return nil, ErrInvalidReason
+	}
+	if note != nil {
+		trimmed := strings.TrimSpace(*note)
+		if len([]rune(trimmed)) > maxReportNoteLength {
+			return nil, ErrReportNoteLength
+		}
+		note = &trimmed
+		if trimmed == "" {
+			note = nil
+		}
+	}
+
+	targetType, err := s.target(ctx, targetID, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.reports.Create(ctx, &models.Report{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reporter:   &models.User{ID: viewer.UserID},
+		Reason:     reason,
+		Note:       note,
+	})
+	if errors.Is(err, repository.ErrAlreadyExist) {
+		return nil, ErrAlreadyReported
+	}
+	return report, err
+}
+
+// Reports очередь жалоб, только для модераторов.
+func (s *ModerationService) Reports(ctx context.Context, status *models.ReportStatus, first int32, after *string) ([]*models.Report, *string, error) {
+	if _, err := requireModerator(ctx); err != nil {
+		return nil, nil, err
+	}
+	return s.reports.List(ctx, status, first, after)
+}
+
+// Resolve применяет действие к контенту из жалобы и закрывает ее в одной транзакции:
+// если жалобу уже закрыли, действие откатывается.
+func (s *ModerationService) Resolve(ctx context.Context, id string, action models.ModerationAction) (*models.Report, error) {
+	moderator, err := requireModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !action.IsValid() {
+		return nil, ErrInvalidAction
+	}
+
+	var report *models.Report
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if report, err = s.reports.GetByID(ctx, id); err != nil {
+			return repository.NotFoundAs(err, ErrReportNotFound)
+		}
+		if report.Status != models.ReportStatusOpen {
+			return repository.ErrReportClosed
+		}
+
+		status := models.ReportStatusResolved
+		if action == models.ModerationActionDismiss {
+			status = models.ReportStatusDismissed
+			if err := s.approve(ctx, report); err != nil {
+				return err
+			}
+		} else if err := s.apply(ctx, report, action, moderator); err != nil {
+			return err
+		}
+
+		report, err = s.reports.Resolve(ctx, id, status, action, moderator.UserID, time.Now().UTC())
+		return repository.NotFoundAs(err, ErrReportNotFound)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// CheckAuthor запрещает заблокированным пользователям писать, возвращает *BanError.
+func (s *ModerationService) CheckAuthor(ctx context.Context, userID string) error {
+	ban, err := s.ActiveBan(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if ban != nil {
+		return &BanError{Ban: ban}
+	}
+	return nil
+}
+
+// ActiveBan действующая блокировка пользователя.
+func (s *ModerationService) ActiveBan(ctx context.Context, userID string) (*models.Ban, error) {
+	if s == nil || userID == "" {
+		return nil, nil
+	}
+	return s.bans.Active(ctx, userID, time.Now().UTC())
+}
+
+// ViewBan блокировка пользователя, видна модераторам и самому пользователю.
+func (s *ModerationService) ViewBan(ctx context.Context, userID string) (*models.Ban, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok || (!viewer.IsModerator() && !viewer.IsAuthor(userID)) {
+		return nil, nil
+	}
+	return s.ActiveBan(ctx, userID)
+}
+
+// Ban блокирует пользователя до until (бессрочно при nil), по желанию скрывает весь его контент.
+// Блокировка и скрытие идут в одной транзакции: при ошибке не остается блокировки без скрытия.
+func (s *ModerationService) Ban(ctx context.Context, userID string, until *time.Time, reason string, hideContent bool) (*models.Ban, error) {
+	moderator, err := requireModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len([]rune(reason)) > maxBanReasonLength {
+		return nil, ErrBanReason
+	}
+	if until != nil && !until.After(time.Now()) {
+		return nil, ErrBanUntil
+	}
+
+	var ban *models.Ban
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if ban, err = s.ban(ctx, moderator, userID, until, reason); err != nil {
+			return err
+		}
+		if !hideContent {
+			return nil
+		}
+		if _, err := s.posts.HideByAuthor(ctx, userID); err != nil {
+			return err
+		}
+		_, err = s.comments.HideByAuthor(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+// Unban снимает блокировку, скрытый контент остается скрытым.
+func (s *ModerationService) Unban(ctx context.Context, userID string) (*models.User, error) {
+	moderator, err := requireModerator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, repository.NotFoundAs(err, ErrUserNotFound)
+	}
+	if _, err := s.bans.Lift(ctx, userID, moderator.UserID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// ban заменяет действующую блокировку пользователя новой.
+// Модераторов и администраторов блокирует только администратор.
+func (s *ModerationService) ban(ctx context.Context, moderator auth.Viewer, userID string, until *time.Time, reason string) (*models.Ban, error) {
+	if userID == moderator.UserID {
+		return nil, ErrSelfBan
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, repository.NotFoundAs(err, ErrUserNotFound)
+	}
+	if u.Role != "" && u.Role != models.RoleUser && moderator.Role != models.RoleAdmin {
+		return nil, ErrForbidden
+	}
+
+	if _, err := s.bans.Lift(ctx, userID, moderator.UserID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return s.bans.Create(ctx, &models.Ban{
+		User:     &models.User{ID: userID},
+		Reason:   reason,
+		Until:    until,
+		BannedBy: &models.User{ID: moderator.UserID},
+	})
+}
+
+// Screen проверяет контент фильтрами перед сохранением.
+// В режиме reject возвращает ErrContentRejected, в режиме score - состояние PENDING.
+func (s *ModerationService) Screen(ctx context.Context, c filter.Content) (models.ModerationState, []string, error) {
+	if s == nil {
+		return models.ModerationStateVisible, nil, nil
+	}
+	verdict, err := s.filters.Run(ctx, c)
+	var rejected *filter.RejectedError
+	if errors.As(err, &rejected) {
+		return "", nil, fmt.Errorf("%w: %s", ErrContentRejected, strings.Join(rejected.Reasons, "; "))
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return verdict.State, verdict.Reasons, nil
+}
+
+// Flag ставит контент на модерации в очередь системной жалобой.
+// Вызывается в транзакции создания контента: без жалобы контент никто не проверит.
+func (s *ModerationService) Flag(ctx context.Context, targetType models.ReportTargetType, targetID string, reasons []string) error {
+	if s == nil {
+		return nil
+	}
+	note := []rune(strings.Join(reasons, "; "))
+	if len(note) > maxReportNoteLength {
+		note = note[:maxReportNoteLength]
+	}
+	text := string(note)
+
+	_, err := s.reports.Create(ctx, &models.Report{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     models.ReportReasonSpam,
+		Note:       &text,
+	})
+	if err != nil && !errors.Is(err, repository.ErrAlreadyExist) {
+		return fmt.Errorf("системная жалоба: %w", err)
+	}
+	return nil
+}
+
+// approve одобряет контент на модерации при отклонении жалобы.
+func (s *ModerationService) approve(ctx context.Context, report *models.Report) error {
+	post, comment, err := s.content(ctx, report)
+	if err != nil {
+		return err
+	}
+	switch {
+	case comment != nil && comment.ModerationState == models.ModerationStatePending:
+		_, err = s.comments.SetModerationState(ctx, comment.ID, models.ModerationStateVisible)
+	case comment == nil && post.ModerationState == models.ModerationStatePending:
+		_, err = s.posts.SetModerationState(ctx, post.ID, models.ModerationStateVisible)
+	}
+	return err
+}
+
+// apply выполняет действие модератора над контентом жалобы.
+func (s *ModerationService) apply(ctx context.Context, report *models.Report, action models.ModerationAction, moderator auth.Viewer) error {
+	post, comment, err := s.content(ctx, report)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case models.ModerationActionHideContent:
+		if comment != nil {
+			_, err = s.comments.SetModerationState(ctx, comment.ID, models.ModerationStateHidden)
+		} else {
+			_, err = s.posts.SetModerationState(ctx, post.ID, models.ModerationStateHidden)
+		}
+	case models.ModerationActionLockComments:
+		_, err = s.posts.SetCommentsEnabled(ctx, post.ID, false)
+	case models.ModerationActionSuspendUser:
+		author := post.Author
+		if comment != nil {
+			author = comment.Author
+		}
+		if author == nil || author.ID == "" {
+			return ErrUserNotFound
+		}
+		_, err = s.ban(ctx, moderator, author.ID, nil, fmt.Sprintf("жалоба %s", report.Reason))
+	default:
+		return ErrInvalidAction
+	}
+	return err
+}
+
+// target определяет тип контента по id: сначала пост, затем комментарий.
+func (s *ModerationService) target(ctx context.Context, targetID string, viewer auth.Viewer) (models.ReportTargetType, error) {
+	if targetID == "" {
+		return "", ErrTargetNotFound
+	}
+
+	post, err := s.posts.GetByID(ctx, targetID)
+	switch {
+	case err == nil:
+		if !repository.PostVisible(post, viewer) {
+			return "", ErrTargetNotFound
+		}
+		return models.ReportTargetTypePost, nil
+	case !errors.Is(err, repository.ErrNotFound):
+		return "", err
+	}
+
+	comment, err := s.comments.GetByID(ctx, targetID)
+	if err != nil {
+		return "", repository.NotFoundAs(err, ErrTargetNotFound)
+	}
+	if !repository.CommentVisible(comment, viewer) {
+		return "", ErrTargetNotFound
+	}
+	post, err = s.posts.GetByID(ctx, comment.PostID)
+	if err != nil {
+		return "", repository.NotFoundAs(err, ErrTargetNotFound)
+	}
+	if !repository.PostVisible(post, viewer) {
+		return "", ErrTargetNotFound
+	}
+	return models.ReportTargetTypeComment, nil
+}
+
+// content пост и комментарий (для жалобы на комментарий) из жалобы.
+func (s *ModerationService) content(ctx context.Context, report *models.Report) (*models.Post, *models.Comment, error) {
+	postID := report.TargetID
+	var comment *models.Comment
+	if report.TargetType == models.ReportTargetTypeComment {
+		c, err := s.comments.GetByID(ctx, report.TargetID)
+		if err != nil {
+			return nil, nil, repository.NotFoundAs(err, ErrTargetNotFound)
+		}
+		comment, postID = c, c.PostID
+	}
+
+	post, err := s.posts.GetByID(ctx, postID)
+	if err != nil {
+		return nil, nil, repository.NotFoundAs(err, ErrTargetNotFound)
+	}
+	return post, comment, nil
+}
+
+// requireModerator текущий пользователь, если он модератор.
+func requireModerator(ctx context.Context) (auth.Viewer, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return viewer, ErrUnauthorized
+	}
+	if !viewer.IsModerator() {
+		return viewer, ErrForbidden
+	}
+	return viewer, nil
+}
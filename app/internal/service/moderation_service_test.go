@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/filter"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+)
+
+// Тест на действия модератора по жалобе на комментарий.
+func TestModerationService_Resolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		moderator auth.Viewer
+		action    models.ModerationAction
+		wantErr   error
+		status    models.ReportStatus
+		hidden    bool
+		locked    bool
+		suspended bool
+	}{
+		{name: "Отклонить", moderator: moderatorViewer, action: models.ModerationActionDismiss, status: models.ReportStatusDismissed},
+		{name: "Скрыть", moderator: moderatorViewer, action: models.ModerationActionHideContent, status: models.ReportStatusResolved, hidden: true},
+		{name: "Закрыть комментарии", moderator: moderatorViewer, action: models.ModerationActionLockComments, status: models.ReportStatusResolved, locked: true},
+		{name: "Заблокировать автора", moderator: moderatorViewer, action: models.ModerationActionSuspendUser, status: models.ReportStatusResolved, suspended: true},
+		{name: "Не модератор", moderator: auth.Viewer{UserID: "carol"}, action: models.ModerationActionDismiss, wantErr: ErrForbidden},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			svc := env.moderationService()
+			comments := env.commentService(env.postService(svc), svc)
+			ctx := context.Background()
+
+			post := env.createPost(t, "alice", "")
+			comment, err := comments.Add(ctx, models.AddCommentInput{PostID: post.ID, AuthorID: "bob", Body: "spam"})
+			if err != nil {
+				t.Fatalf("создание комментария: %v", err)
+			}
+
+			aliceCtx := auth.WithViewer(ctx, auth.Viewer{UserID: "alice"})
+			report, err := svc.Report(aliceCtx, comment.ID, models.ReportReasonSpam, nil)
+			if err != nil {
+				t.Fatalf("жалоба: %v", err)
+			}
+			if report.TargetType != models.ReportTargetTypeComment {
+				t.Fatalf("ожидался тип COMMENT, а получили %s", report.TargetType)
+			}
+			if _, err := svc.Report(aliceCtx, comment.ID, models.ReportReasonSpam, nil); !errors.Is(err, ErrAlreadyReported) {
+				t.Fatalf("ожидалась ошибка повторной жалобы, а получили %v", err)
+			}
+
+			resolved, err := svc.Resolve(auth.WithViewer(ctx, tc.moderator), report.ID, tc.action)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("решение по жалобе: %v", err)
+			}
+			if resolved.Status != tc.status {
+				t.Fatalf("ожидался статус %s, а получили %s", tc.status, resolved.Status)
+			}
+
+			list, _, err := env.comments.ListByParent(ctx, post.ID, nil, 10, nil, models.CommentOrderNewest, auth.Viewer{UserID: "alice"})
+			if err != nil {
+				t.Fatalf("список комментариев: %v", err)
+			}
+			if tc.hidden != (len(list) == 0) {
+				t.Fatalf("ожидалось скрытие %v, а получили %d комментариев", tc.hidden, len(list))
+			}
+			modList, _, err := env.comments.ListByParent(ctx, post.ID, nil, 10, nil, models.CommentOrderNewest, moderatorViewer)
+			if err != nil {
+				t.Fatalf("список комментариев: %v", err)
+			}
+			if len(modList) != 1 {
+				t.Fatalf("модератор должен видеть комментарий, а получили %d", len(modList))
+			}
+
+			p, _ := env.posts.GetByID(ctx, post.ID)
+			if tc.locked == p.CommentsEnabled {
+				t.Fatalf("ожидалось закрытие комментариев %v", tc.locked)
+			}
+
+			_, err = comments.Add(ctx, models.AddCommentInput{PostID: post.ID, AuthorID: "bob", Body: "еще"})
+			if tc.suspended && !errors.Is(err, ErrUserSuspended) {
+				t.Fatalf("ожидалась блокировка автора, а получили %v", err)
+			}
+
+			if _, err := svc.Resolve(auth.WithViewer(ctx, tc.moderator), report.ID, tc.action); !errors.Is(err, repository.ErrReportClosed) {
+				t.Fatalf("ожидалась ошибка закрытой жалобы, а получили %v", err)
+			}
+		})
+	}
+}
+
+// Тест на фильтрацию комментариев перед сохранением.
+func TestModerationService_Screen(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    filter.Mode
+		action  models.ModerationAction
+		wantErr error
+		visible bool
+	}{
+		{name: "Отклонение", mode: filter.ModeReject, wantErr: ErrContentRejected},
+		{name: "Одобрение модератором", mode: filter.ModeScore, action: models.ModerationActionDismiss, visible: true},
+		{name: "Скрытие модератором", mode: filter.ModeScore, action: models.ModerationActionHideContent},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.filters = filter.NewPipeline(tc.mode, 0, filter.NewBlockList(map[string][]string{"ru": {"казино"}}, 1))
+			svc := env.moderationService()
+			comments := env.commentService(env.postService(svc), svc)
+			ctx := context.Background()
+
+			post := env.createPost(t, "alice", "")
+			comment, err := comments.Add(ctx, models.AddCommentInput{PostID: post.ID, AuthorID: "bob", Body: "Заходи в казино"})
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("создание комментария: %v", err)
+			}
+			if comment.ModerationState != models.ModerationStatePending {
+				t.Fatalf("ожидалось состояние PENDING, а получили %s", comment.ModerationState)
+			}
+
+			for _, viewer := range []auth.Viewer{{UserID: "alice"}, {UserID: "bob"}} {
+				list, _, _ := env.comments.ListByParent(ctx, post.ID, nil, 10, nil, models.CommentOrderNewest, viewer)
+				if want := viewer.UserID == "bob"; want != (len(list) == 1) {
+					t.Fatalf("%s: видимость комментария на модерации %v, а получили %d", viewer.UserID, want, len(list))
+				}
+			}
+
+			open := models.ReportStatusOpen
+			reports, _, err := svc.Reports(auth.WithViewer(ctx, moderatorViewer), &open, 10, nil)
+			if err != nil || len(reports) != 1 || reports[0].Reporter != nil || reports[0].TargetID != comment.ID {
+				t.Fatalf("ожидалась системная жалоба на комментарий, а получили %v %v", reports, err)
+			}
+			if _, err := svc.Resolve(auth.WithViewer(ctx, moderatorViewer), reports[0].ID, tc.action); err != nil {
+				t.Fatalf("решение по жалобе: %v", err)
+			}
+
+			list, _, _ := env.comments.ListByParent(ctx, post.ID, nil, 10, nil, models.CommentOrderNewest, auth.Viewer{UserID: "alice"})
+			if tc.visible != (len(list) == 1) {
+				t.Fatalf("ожидалась видимость %v, а получили %d комментариев", tc.visible, len(list))
+			}
+		})
+	}
+}
+
+// failingReportRepo не может сохранить жалобу.
+type failingReportRepo struct {
+	repository.ReportRepo
+}
+
+func (failingReportRepo) Create(context.Context, *models.Report) (*models.Report, error) {
+	return nil, errors.New("жалобы недоступны")
+}
+
+// Тест на откат контента на модерации, если системная жалоба не сохранилась.
+func TestModerationService_FlagRollback(t *testing.T) {
+	env := newTestEnv(t)
+	env.filters = filter.NewPipeline(filter.ModeScore, 0, filter.NewBlockList(map[string][]string{"ru": {"казино"}}, 1))
+	env.reports = failingReportRepo{env.reports}
+	svc := env.moderationService()
+	posts := env.postService(svc)
+	comments := env.commentService(posts, svc)
+	ctx := context.Background()
+	post := env.createPost(t, "alice", "")
+
+	if _, err := comments.Add(ctx, models.AddCommentInput{PostID: post.ID, AuthorID: "bob", Body: "Заходи в казино"}); err == nil {
+		t.Fatalf("ожидалась ошибка жалобы для комментария")
+	}
+	list, _, err := env.comments.ListByParent(ctx, post.ID, nil, 10, nil, models.CommentOrderNewest, moderatorViewer)
+	if err != nil {
+		t.Fatalf("список комментариев: %v", err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("комментарий на модерации остался без жалобы: %d", len(list))
+	}
+
+	if _, err := posts.Create(ctx, models.CreatePostInput{AuthorID: "bob", Title: "казино", Body: "Заходи в казино"}); err == nil {
+		t.Fatalf("ожидалась ошибка жалобы для поста")
+	}
+	all, _, err := env.posts.List(ctx, 10, nil, moderatorViewer)
+	if err != nil {
+		t.Fatalf("список постов: %v", err)
+	}
+	if len(all) != 1 {
+		t.Fatalf("пост на модерации остался без жалобы: %d постов", len(all))
+	}
+}
+
+// closedReportRepo жалобу закрыли параллельно: Resolve всегда отвечает ErrReportClosed.
+type closedReportRepo struct {
+	repository.ReportRepo
+}
+
+func (closedReportRepo) Resolve(context.Context, string, models.ReportStatus, models.ModerationAction, string, time.Time) (*models.Report, error) {
+	return nil, repository.ErrReportClosed
+}
+
+// Тест на откат действия, если жалобу не удалось закрыть.
+func TestModerationService_ResolveRollback(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	post := env.createPost(t, "bob", "")
+	report, err := env.reports.Create(ctx, &models.Report{TargetType: models.ReportTargetTypePost, TargetID: post.ID, Reason: models.ReportReasonSpam})
+	if err != nil {
+		t.Fatalf("жалоба: %v", err)
+	}
+	env.reports = closedReportRepo{env.reports}
+	svc := env.moderationService()
+
+	_, err = svc.Resolve(auth.WithViewer(ctx, moderatorViewer), report.ID, models.ModerationActionHideContent)
+	if !errors.Is(err, repository.ErrReportClosed) {
+		t.Fatalf("ожидалась ошибка %v, а получили %v", repository.ErrReportClosed, err)
+	}
+	if p, _ := env.posts.GetByID(ctx, post.ID); p.ModerationState != models.ModerationStateVisible {
+		t.Fatalf("пост скрыт без закрытия жалобы: %s", p.ModerationState)
+	}
+}
+
+// Тест на блокировку пользователя.
+func TestModerationService_Ban(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		moderator auth.Viewer
+		userID    string
+		until     *time.Time
+		reason    string
+		hide      bool
+		wantErr   error
+	}{
+		{name: "Бессрочно", moderator: moderatorViewer, userID: "bob", reason: "спам"},
+		{name: "До даты со скрытием", moderator: moderatorViewer, userID: "bob", until: &future, reason: "спам", hide: true},
+		{name: "Срок в прошлом", moderator: moderatorViewer, userID: "bob", until: &past, reason: "спам", wantErr: ErrBanUntil},
+		{name: "Без причины", moderator: moderatorViewer, userID: "bob", reason: "  ", wantErr: ErrBanReason},
+		{name: "Себя", moderator: moderatorViewer, userID: "mod", reason: "спам", wantErr: ErrSelfBan},
+		{name: "Модератора модератором", moderator: moderatorViewer, userID: "mod2", reason: "спам", wantErr: ErrForbidden},
+		{name: "Не модератор", moderator: auth.Viewer{UserID: "alice"}, userID: "bob", reason: "спам", wantErr: ErrForbidden},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			svc := env.moderationService()
+			ctx := context.Background()
+			post := env.createPost(t, "bob", "")
+
+			modCtx := auth.WithViewer(ctx, tc.moderator)
+			ban, err := svc.Ban(modCtx, tc.userID, tc.until, tc.reason, tc.hide)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("блокировка: %v", err)
+			}
+			if ban.Reason != tc.reason || ban.BannedBy == nil || ban.BannedBy.ID != tc.moderator.UserID {
+				t.Fatalf("неверная блокировка: %+v", ban)
+			}
+
+			var banErr *BanError
+			err = svc.CheckAuthor(ctx, tc.userID)
+			if !errors.As(err, &banErr) || !errors.Is(err, ErrUserSuspended) || banErr.Ban.Reason != tc.reason {
+				t.Fatalf("ожидалась ошибка блокировки, а получили %v", err)
+			}
+
+			p, _ := env.posts.GetByID(ctx, post.ID)
+			if tc.hide != (p.ModerationState == models.ModerationStateHidden) {
+				t.Fatalf("ожидалось скрытие контента %v, а получили %s", tc.hide, p.ModerationState)
+			}
+
+			if _, err := svc.Unban(modCtx, tc.userID); err != nil {
+				t.Fatalf("разблокировка: %v", err)
+			}
+			if err := svc.CheckAuthor(ctx, tc.userID); err != nil {
+				t.Fatalf("после разблокировки ошибка не ожидалась: %v", err)
+			}
+		})
+	}
+}
+
+// failingHideCommentRepo не может скрыть комментарии автора.
+type failingHideCommentRepo struct {
+	repository.CommentRepo
+}
+
+func (failingHideCommentRepo) HideByAuthor(context.Context, string) (int, error) {
+	return 0, errors.New("скрытие недоступно")
+}
+
+// Тест на откат блокировки, если контент не удалось скрыть.
+func TestModerationService_BanRollback(t *testing.T) {
+	env := newTestEnv(t)
+	env.comments = failingHideCommentRepo{env.comments}
+	svc := env.moderationService()
+	ctx := context.Background()
+	post := env.createPost(t, "bob", "")
+
+	if _, err := svc.Ban(auth.WithViewer(ctx, moderatorViewer), "bob", nil, "спам", true); err == nil {
+		t.Fatalf("ожидалась ошибка скрытия")
+	}
+	if err := svc.CheckAuthor(ctx, "bob"); err != nil {
+		t.Fatalf("блокировка осталась после отката: %v", err)
+	}
+	if p, _ := env.posts.GetByID(ctx, post.ID); p.ModerationState != models.ModerationStateVisible {
+		t.Fatalf("пост скрыт после отката: %s", p.ModerationState)
+	}
+}
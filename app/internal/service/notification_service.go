@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+)
+
+// NotificationService упоминания, ответы и комментарии к постам в одном потоке уведомлений.
+type NotificationService struct {
+	repo     repository.NotificationRepo
+	users    repository.UserRepo
+	notifier *Notifier[*models.Notification]
+	logger   logger.Logger
+}
+
+func NewNotificationService(repo repository.NotificationRepo, users repository.UserRepo, logger logger.Logger) *NotificationService {
+	return &NotificationService{
+		repo:     repo,
+		users:    users,
+		notifier: NewNotifier[*models.Notification](),
+		logger:   logger,
+	}
+}
+
+// PostPublished уведомляет упомянутых в опубликованном посте.
+func (s *NotificationService) PostPublished(ctx context.Context, post *models.Post) {
+	if s == nil || post == nil || post.Author == nil {
+		return
+	}
+	recipients := map[string]models.NotificationKind{}
+	s.collectMentions(ctx, post.Author.ID, post.ID, nil, post.Body, recipients)
+	s.deliver(ctx, post.Author.ID, post.ID, nil, recipients)
+}
+
+// CommentAdded уведомляет упомянутых, автора родительского комментария и автора поста.
+// Каждый получатель получает одно уведомление: упоминание важнее ответа, ответ важнее комментария.
+func (s *NotificationService) CommentAdded(ctx context.Context, post *models.Post, comment *models.Comment, parentAuthorID string) {
+	if s == nil || post == nil || comment == nil || comment.Author == nil {
+		return
+	}
+	actorID := comment.Author.ID
+	commentID := comment.ID
+
+	recipients := map[string]models.NotificationKind{}
+	if post.Author != nil && post.Author.ID != "" {
+		recipients[post.Author.ID] = models.NotificationKindPostComment
+	}
+	if parentAuthorID != "" {
+		recipients[parentAuthorID] = models.NotificationKindReply
+	}
+	s.collectMentions(ctx, actorID, post.ID, &commentID, comment.Body, recipients)
+	s.deliver(ctx, actorID, post.ID, &commentID, recipients)
+}
+
+// Subscribe подписка на новые уведомления пользователя.
+func (s *NotificationService) Subscribe(userID string) (chan *models.Notification, func()) {
+	return s.notifier.Subscribe(userID)
+}
+
+func (s *NotificationService) List(ctx context.Context, userID string, first int32, after *string, unreadOnly bool) ([]*models.Notification, *string, error) {
+	return s.repo.List(ctx, userID, first, after, unreadOnly)
+}
+
+func (s *NotificationService) CountUnread(ctx context.Context, userID string) (int, error) {
+	return s.repo.CountUnread(ctx, userID)
+}
+
+func (s *NotificationService) MarkRead(ctx context.Context, userID string, ids []string) (int, error) {
+	return s.repo.MarkRead(ctx, userID, ids, time.Now().UTC())
+}
+
+// collectMentions сохраняет упоминания и добавляет упомянутых в получатели.
+func (s *NotificationService) collectMentions(ctx context.Context, actorID, postID string, commentID *string, body string, recipients map[string]models.NotificationKind) {
+	names := ParseMentions(body)
+	if len(names) == 0 {
+		return
+	}
+	users, err := s.users.GetByUsernames(ctx, names)
+	if err != nil {
+		s.logger.Ctx(ctx).Errorf("упоминания: %v", err)
+		return
+	}
+
+	mentions := make([]*models.Mention, 0, len(users))
+	for _, u := range users {
+		mentions = append(mentions, &models.Mention{
+			UserID:    u.ID,
+			AuthorID:  actorID,
+			PostID:    postID,
+			CommentID: commentID,
+		})
+		recipients[u.ID] = models.NotificationKindMention
+	}
+	if err := s.repo.AddMentions(ctx, mentions); err != nil {
+		s.logger.Ctx(ctx).With("post_id", postID).Errorf("сохранение упоминаний: %v", err)
+	}
+}
+
+func (s *NotificationService) deliver(ctx context.Context, actorID, postID string, commentID *string, recipients map[string]models.NotificationKind) {
+	for userID, kind := range recipients {
+		if userID == actorID {
+			continue
+		}
+		n, err := s.repo.Create(ctx, &models.Notification{
+			Kind:      kind,
+			UserID:    userID,
+			Actor:     &models.User{ID: actorID},
+			PostID:    postID,
+			CommentID: commentID,
+		})
+		if err != nil {
+			s.logger.Ctx(ctx).With("user_id", userID).Errorf("создание уведомления: %v", err)
+			continue
+		}
+		repository.AfterCommit(ctx, func() { _ = s.notifier.Publish(userID, n) })
+	}
+}
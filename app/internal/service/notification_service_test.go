@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+)
+
+// Тест на получателей уведомлений о комментарии.
+func TestNotificationService_CommentAdded(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		parent string
+		want   map[string]models.NotificationKind
+	}{
+		{
+			name: "Комментарий к посту",
+			body: "текст",
+			want: map[string]models.NotificationKind{"author": models.NotificationKindPostComment},
+		},
+		{
+			name:   "Ответ",
+			body:   "текст",
+			parent: "bob",
+			want: map[string]models.NotificationKind{
+				"author": models.NotificationKindPostComment,
+				"bob":    models.NotificationKindReply,
+			},
+		},
+		{
+			name:   "Упоминание важнее ответа",
+			body:   "@Bob смотри",
+			parent: "bob",
+			want: map[string]models.NotificationKind{
+				"author": models.NotificationKindPostComment,
+				"bob":    models.NotificationKindMention,
+			},
+		},
+		{
+			name: "Себе не уведомляем",
+			body: "@carol @alice",
+			want: map[string]models.NotificationKind{
+				"author": models.NotificationKindPostComment,
+				"alice":  models.NotificationKindMention,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			st := repository.NewMemoryStorageWithTTL(0)
+			st.SeedUsers(
+				&models.User{ID: "alice", Username: "alice"},
+				&models.User{ID: "bob", Username: "bob"},
+				&models.User{ID: "carol", Username: "carol"},
+			)
+			repo := repository.NewMemoryNotificationRepo(st)
+			svc := NewNotificationService(repo, repository.NewMemoryUserRepo(st), loggerStub{})
+			ctx := context.Background()
+
+			post := &models.Post{ID: "p1", Author: &models.User{ID: "author"}}
+			comment := &models.Comment{ID: "c1", PostID: "p1", Author: &models.User{ID: "carol"}, Body: tc.body}
+			svc.CommentAdded(ctx, post, comment, tc.parent)
+
+			got := map[string]models.NotificationKind{}
+			for _, userID := range []string{"author", "alice", "bob", "carol"} {
+				list, _, err := svc.List(ctx, userID, 10, nil, false)
+				if err != nil {
+					t.Fatalf("список уведомлений: %v", err)
+				}
+				if len(list) > 1 {
+					t.Fatalf("ожидалось одно уведомление для %s, а получили %d", userID, len(list))
+				}
+				for _, n := range list {
+					got[userID] = n.Kind
+				}
+			}
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("ожидалось %v, а получили %v", tc.want, got)
+			}
+			for userID, kind := range tc.want {
+				if got[userID] != kind {
+					t.Fatalf("для %s ожидалось %s, а получили %s", userID, kind, got[userID])
+				}
+			}
+		})
+	}
+}
+
+// Тест на прочтение уведомлений.
+func TestNotificationService_MarkRead(t *testing.T) {
+	st := repository.NewMemoryStorageWithTTL(0)
+	svc := NewNotificationService(repository.NewMemoryNotificationRepo(st), repository.NewMemoryUserRepo(st), loggerStub{})
+	ctx := context.Background()
+
+	events, unsubscribe := svc.Subscribe("author")
+	defer unsubscribe()
+
+	post := &models.Post{ID: "p1", Author: &models.User{ID: "author"}}
+	svc.CommentAdded(ctx, post, &models.Comment{ID: "c1", PostID: "p1", Author: &models.User{ID: "u"}, Body: "a"}, "")
+
+	select {
+	case n := <-events:
+		if n.Kind != models.NotificationKindPostComment {
+			t.Fatalf("ожидалось POST_COMMENT, а получили %s", n.Kind)
+		}
+	default:
+		t.Fatalf("ожидалось событие notificationAdded")
+	}
+
+	if cnt, _ := svc.CountUnread(ctx, "author"); cnt != 1 {
+		t.Fatalf("ожидалось 1 непрочитанное, а получили %d", cnt)
+	}
+	if n, err := svc.MarkRead(ctx, "author", nil); err != nil || n != 1 {
+		t.Fatalf("ожидалось 1 прочитанное, а получили %d (%v)", n, err)
+	}
+	unread, _, err := svc.List(ctx, "author", 10, nil, true)
+	if err != nil {
+		t.Fatalf("список уведомлений: %v", err)
+	}
+	if len(unread) != 0 {
+		t.Fatalf("ожидалось 0 непрочитанных, а получили %d", len(unread))
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Notifier рассылает события подписчикам топика без блокировки отправителя.
+	Notifier[T any] struct {
+		mu      sync.RWMutex
+		byTopic map[string][]subscriber[T]
+		closed  bool
+		// dropped события, не доставленные подписчику.
+		dropped atomic.Uint64
+	}
+
+	subscriber[T any] struct {
+		stream chan T
+		done   chan struct{}
+	}
+)
+
+func NewNotifier[T any]() *Notifier[T] {
+	return &Notifier[T]{
+		byTopic: make(map[string][]subscriber[T]),
+	}
+}
+
+// Subscribe подписка на топик, вторым значением возвращается отписка.
+func (n *Notifier[T]) Subscribe(topic string) (chan T, func()) {
+	sub := subscriber[T]{
+		stream: make(chan T, 1),
+		done:   make(chan struct{}),
+	}
+
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		close(sub.done)
+		close(sub.stream)
+		return sub.stream, func() {}
+	}
+	n.byTopic[topic] = append(n.byTopic[topic], sub)
+	n.mu.Unlock()
+
+	unSub := func() {
+		n.mu.Lock()
+		subscribers := n.byTopic[topic]
+		for i, s := range subscribers {
+			if s.stream == sub.stream {
+				close(s.done)
+				close(s.stream)
+				subscribers = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+		if len(subscribers) == 0 {
+			delete(n.byTopic, topic)
+		} else {
+			n.byTopic[topic] = subscribers
+		}
+		n.mu.Unlock()
+	}
+
+	return sub.stream, unSub
+}
+
+// Close завершает все подписки, новые подписки сразу закрыты.
+func (n *Notifier[T]) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	n.closed = true
+	for _, subscribers := range n.byTopic {
+		for _, s := range subscribers {
+			close(s.done)
+			close(s.stream)
+		}
+	}
+	n.byTopic = make(map[string][]subscriber[T])
+}
+
+// Closed был ли вызван Close.
+func (n *Notifier[T]) Closed() bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.closed
+}
+
+// Subscribers число подписчиков по топикам.
+func (n *Notifier[T]) Subscribers() map[string]int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	out := make(map[string]int, len(n.byTopic))
+	for topic, subscribers := range n.byTopic {
+		out[topic] = len(subscribers)
+	}
+	return out
+}
+
+// Dropped сколько событий не доставлено: буфер подписчика полон или подписка закрыта.
+func (n *Notifier[T]) Dropped() uint64 {
+	return n.dropped.Load()
+}
+
+// Publish отправляет событие всем подписчикам топика.
+func (n *Notifier[T]) Publish(topic string, v T) error {
+	n.mu.RLock()
+	subscribers := append([]subscriber[T](nil), n.byTopic[topic]...)
+	n.mu.RUnlock()
+
+	var errs []error
+	for _, sub := range subscribers {
+		if !trySend(sub, v) {
+			n.dropped.Add(1)
+			errs = append(errs, ErrSendFailed)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func trySend[T any](sub subscriber[T], v T) (sent bool) {
+	defer func() {
+		if recover() != nil {
+			sent = false
+		}
+	}()
+
+	select {
+	case <-sub.done:
+		return false
+	case sub.stream <- v:
+		return true
+	default:
+		return false
+	}
+}
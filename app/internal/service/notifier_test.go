@@ -0,0 +1,53 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/RoGogDBD/GQLGo/internal/models"
+)
+
+// Тест на остановку рассылки: подписки закрываются, новые не принимаются.
+func TestCommentNotifier_Close(t *testing.T) {
+	n := NewCommentNotifier(nil)
+	stream, unSub, err := n.Subscribe("p1")
+	if err != nil {
+		t.Fatalf("подписка: %v", err)
+	}
+
+	n.Close()
+	n.Close()
+	if _, ok := <-stream; ok {
+		t.Fatalf("ожидался закрытый канал подписки")
+	}
+	unSub()
+
+	if _, _, err := n.Subscribe("p1"); !errors.Is(err, ErrNotifierClosed) {
+		t.Fatalf("ожидалась ошибка %v, а получили %v", ErrNotifierClosed, err)
+	}
+	if err := n.Publish("p1", &models.Comment{PostID: "p1"}); err != nil {
+		t.Fatalf("публикация без подписчиков: %v", err)
+	}
+}
+
+// Тест на состояние рассылки для метрик: подписчики по топикам и потерянные события.
+func TestNotifier_Stats(t *testing.T) {
+	n := NewNotifier[int]()
+	_, unSub := n.Subscribe("a")
+	n.Subscribe("a")
+	n.Subscribe("b")
+
+	if got := n.Subscribers(); got["a"] != 2 || got["b"] != 1 {
+		t.Fatalf("ожидалось a=2 b=1, а получили %v", got)
+	}
+	// Буфер подписчика на одно событие: второе теряется у каждого из двух.
+	_ = n.Publish("a", 1)
+	_ = n.Publish("a", 2)
+	if got := n.Dropped(); got != 2 {
+		t.Fatalf("ожидалось 2 потерянных события, а получили %d", got)
+	}
+	unSub()
+	if got := n.Subscribers(); got["a"] != 1 {
+		t.Fatalf("после отписки ожидался один подписчик, а получили %v", got)
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+)
+
+// PostScheduler периодически публикует отложенные посты.
+type PostScheduler struct {
+	posts    *PostService
+	interval time.Duration
+	logger   logger.Logger
+}
+
+func NewPostScheduler(posts *PostService, interval time.Duration, logger logger.Logger) *PostScheduler {
+	return &PostScheduler{
+		posts:    posts,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run работает, пока не отменен ctx.
+func (s *PostScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.posts.PublishDue(ctx, time.Now().UTC())
+		if err != nil && ctx.Err() == nil {
+			s.logger.Errorf("post scheduler: %v", err)
+		}
+		if n > 0 {
+			s.logger.Infof("post scheduler: опубликовано постов %d", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
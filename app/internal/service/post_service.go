@@ -2,21 +2,46 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/filter"
+	"github.com/RoGogDBD/GQLGo/internal/markup"
 	"github.com/RoGogDBD/GQLGo/internal/models"
 	"github.com/RoGogDBD/GQLGo/internal/repository"
 )
 
+var (
+	ErrPostNotFound  = repository.NotFound("пост не найден")
+	ErrUnauthorized  = errors.New("требуется пользователь")
+	ErrForbidden     = errors.New("нет доступа")
+	ErrInvalidStatus = errors.New("неверный статус поста")
+	ErrNoPublishAt   = errors.New("для отложенной публикации требуется publishAt")
+	ErrPastPublishAt = errors.New("publishAt должен быть в будущем")
+	ErrSubsDisabled  = errors.New("subscriptions отключены")
+)
+
+// publishBatchSize сколько отложенных постов публикуется за один запрос.
+const publishBatchSize = 100
+
 type PostService struct {
-	repo repository.PostRepo
+	repo          repository.PostRepo
+	tags          repository.TagRepo
+	tx            repository.Transactor
+	published     *Notifier[*models.Post]
+	notifications *NotificationService
+	moderation    *ModerationService
 }
 
-func NewPostService(repo repository.PostRepo) *PostService {
-	return &PostService{repo: repo}
+func NewPostService(repo repository.PostRepo, tags repository.TagRepo, tx repository.Transactor, published *Notifier[*models.Post], notifications *NotificationService, moderation *ModerationService) *PostService {
+	return &PostService{repo: repo, tags: tags, tx: tx, published: published, notifications: notifications, moderation: moderation}
 }
 
+// Create проверяет и сохраняет пост. Пост, его теги и системная жалоба на пост
+// на модерации пишутся в одной транзакции: пост не остается без них.
 func (s *PostService) Create(ctx context.Context, in models.CreatePostInput) (*models.Post, error) {
 	if in.AuthorID == "" {
 		return nil, fmt.Errorf("требуется id автора")
@@ -35,6 +60,222 @@ func (s *PostService) Create(ctx context.Context, in models.CreatePostInput) (*m
 	if len(body) > 2000 {
 		return nil, fmt.Errorf("тело длинное (<= 2000 симв.)")
 	}
+	tags, err := NormalizeTags(in.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.moderation.CheckAuthor(ctx, in.AuthorID); err != nil {
+		return nil, err
+	}
+	state, reasons, err := s.moderation.Screen(ctx, filter.Content{
+		Kind:     filter.KindPost,
+		AuthorID: in.AuthorID,
+		Text:     title + "\n" + body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	status, err := initialStatus(in, now)
+	if err != nil {
+		return nil, err
+	}
+
+	commentsEnabled := true
+	if in.CommentsEnabled != nil {
+		commentsEnabled = *in.CommentsEnabled
+	}
+	post := &models.Post{
+		Title:           title,
+		Body:            body,
+		BodyHTML:        markup.Render(body),
+		Author:          &models.User{ID: in.AuthorID},
+		CommentsEnabled: commentsEnabled,
+		Status:          status,
+		ModerationState: state,
+		PublishAt:       in.PublishAt,
+	}
+	if status == models.PostStatusPublished {
+		post.PublishedAt = &now
+	}
+
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if post, err = s.repo.Create(ctx, post); err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			if err := s.tags.SetPostTags(ctx, post.ID, tags); err != nil {
+				return err
+			}
+		}
+		if state == models.ModerationStatePending {
+			return s.moderation.Flag(ctx, models.ReportTargetTypePost, post.ID, reasons)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if post.Status == models.PostStatusPublished {
+		s.notifyPublished(ctx, post)
+	}
+	return post, nil
+}
 
-	return s.repo.Create(ctx, in)
+// Get возвращает пост, если пользователь может его видеть, иначе ErrPostNotFound.
+func (s *PostService) Get(ctx context.Context, id string) (*models.Post, error) {
+	return visiblePost(ctx)(s.repo.GetByID(ctx, id))
+}
+
+// GetForUpdate как Get, но блокирует пост до конца транзакции из контекста.
+func (s *PostService) GetForUpdate(ctx context.Context, id string) (*models.Post, error) {
+	return visiblePost(ctx)(s.repo.GetForUpdate(ctx, id))
+}
+
+// visiblePost пропускает пост, только если пользователь из ctx может его видеть.
+// Скрытый пост неотличим от отсутствующего.
+func visiblePost(ctx context.Context) func(*models.Post, error) (*models.Post, error) {
+	return func(post *models.Post, err error) (*models.Post, error) {
+		if err != nil {
+			return nil, repository.NotFoundAs(err, ErrPostNotFound)
+		}
+		viewer, _ := auth.ViewerFrom(ctx)
+		if !repository.PostVisible(post, viewer) {
+			return nil, ErrPostNotFound
+		}
+		return post, nil
+	}
+}
+
+// SetTags заменяет теги поста автора.
+func (s *PostService) SetTags(ctx context.Context, postID string, raw []string) (*models.Post, error) {
+	tags, err := NormalizeTags(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.authorPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tags.SetPostTags(ctx, postID, tags); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// Publish публикует черновик или отложенный пост автора.
+func (s *PostService) Publish(ctx context.Context, postID string) (*models.Post, error) {
+	if _, err := s.authorPost(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	from := []models.PostStatus{models.PostStatusDraft, models.PostStatusScheduled}
+	post, err := s.repo.UpdateStatus(ctx, postID, from, models.PostStatusPublished, time.Now().UTC())
+	if err != nil {
+		return nil, repository.NotFoundAs(err, ErrPostNotFound)
+	}
+	s.notifyPublished(ctx, post)
+	return post, nil
+}
+
+// Archive убирает пост автора в архив.
+func (s *PostService) Archive(ctx context.Context, postID string) (*models.Post, error) {
+	if _, err := s.authorPost(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	from := []models.PostStatus{models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusPublished}
+	post, err := s.repo.UpdateStatus(ctx, postID, from, models.PostStatusArchived, time.Now().UTC())
+	if err != nil {
+		return nil, repository.NotFoundAs(err, ErrPostNotFound)
+	}
+	return post, nil
+}
+
+// PublishDue публикует все отложенные посты, время которых пришло.
+func (s *PostService) PublishDue(ctx context.Context, now time.Time) (int, error) {
+	total := 0
+	for {
+		posts, err := s.repo.PublishDue(ctx, now, publishBatchSize)
+		if err != nil {
+			return total, err
+		}
+		for _, p := range posts {
+			s.notifyPublished(ctx, p)
+		}
+		total += len(posts)
+		if len(posts) < publishBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// SubscribePublished подписка на опубликованные посты.
+func (s *PostService) SubscribePublished() (chan *models.Post, func(), error) {
+	if s.published == nil {
+		return nil, nil, ErrSubsDisabled
+	}
+	stream, unSub := s.published.Subscribe("")
+	return stream, unSub, nil
+}
+
+// authorPost пост, которым может управлять текущий пользователь.
+func (s *PostService) authorPost(ctx context.Context, postID string) (*models.Post, error) {
+	if postID == "" {
+		return nil, fmt.Errorf("требуется id поста")
+	}
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	post, err := s.repo.GetByID(ctx, postID)
+	if err != nil {
+		return nil, repository.NotFoundAs(err, ErrPostNotFound)
+	}
+	if post.Author == nil || !viewer.IsAuthor(post.Author.ID) {
+		return nil, ErrForbidden
+	}
+	return post, nil
+}
+
+// notifyPublished упоминания и событие публикации после фиксации транзакции из ctx.
+// Медленные подписчики пропускают событие, отправителя не блокируем.
+func (s *PostService) notifyPublished(ctx context.Context, p *models.Post) {
+	if p.ModerationState == models.ModerationStatePending || p.ModerationState == models.ModerationStateHidden {
+		return
+	}
+	repository.AfterCommit(ctx, func() {
+		s.notifications.PostPublished(ctx, p)
+		if s.published != nil {
+			_ = s.published.Publish("", p)
+		}
+	})
+}
+
+// initialStatus статус нового поста: по умолчанию опубликован, с publishAt - отложен.
+func initialStatus(in models.CreatePostInput, now time.Time) (models.PostStatus, error) {
+	status := models.PostStatusPublished
+	if in.PublishAt != nil {
+		status = models.PostStatusScheduled
+	}
+	if in.Status != nil {
+		status = *in.Status
+	}
+
+	switch status {
+	case models.PostStatusPublished, models.PostStatusDraft:
+		return status, nil
+	case models.PostStatusScheduled:
+		if in.PublishAt == nil {
+			return "", ErrNoPublishAt
+		}
+		if !in.PublishAt.After(now) {
+			return "", ErrPastPublishAt
+		}
+		return status, nil
+	default:
+		return "", ErrInvalidStatus
+	}
 }
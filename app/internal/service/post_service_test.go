@@ -2,26 +2,41 @@ package service
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/RoGogDBD/GQLGo/internal/auth"
 	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
 )
 
 type postRepoStub struct {
 	createCalled bool
+	post         *models.Post
 }
 
 func (s *postRepoStub) GetByID(context.Context, string) (*models.Post, error) {
-	return nil, nil
+	if s.post == nil {
+		return nil, repository.ErrNotFound
+	}
+	return s.post, nil
+}
+
+func (s *postRepoStub) GetForUpdate(ctx context.Context, id string) (*models.Post, error) {
+	return s.GetByID(ctx, id)
 }
 
-func (s *postRepoStub) Create(_ context.Context, in models.CreatePostInput) (*models.Post, error) {
+func (s *postRepoStub) Create(_ context.Context, p *models.Post) (*models.Post, error) {
 	s.createCalled = true
-	return &models.Post{ID: "p1", Title: in.Title, Body: in.Body, Author: &models.User{ID: in.AuthorID}}, nil
+	cp := *p
+	cp.ID = "p1"
+	return &cp, nil
 }
 
-func (s *postRepoStub) List(context.Context, int32, *string) ([]*models.Post, *string, error) {
+func (s *postRepoStub) List(context.Context, int32, *string, auth.Viewer) ([]*models.Post, *string, error) {
 	return nil, nil, nil
 }
 
@@ -29,6 +44,52 @@ func (s *postRepoStub) SetCommentsEnabled(context.Context, string, bool) (*model
 	return nil, nil
 }
 
+func (s *postRepoStub) UpdateStatus(_ context.Context, _ string, from []models.PostStatus, to models.PostStatus, _ time.Time) (*models.Post, error) {
+	if s.post == nil {
+		return nil, repository.ErrNotFound
+	}
+	if !slices.Contains(from, s.post.Status) {
+		return nil, repository.ErrStatusConflict
+	}
+	cp := *s.post
+	cp.Status = to
+	return &cp, nil
+}
+
+func (s *postRepoStub) PublishDue(context.Context, time.Time, int) ([]*models.Post, error) {
+	return nil, nil
+}
+
+func (s *postRepoStub) SetModerationState(context.Context, string, models.ModerationState) (*models.Post, error) {
+	return s.post, nil
+}
+
+func (s *postRepoStub) HideByAuthor(context.Context, string) (int, error) {
+	return 0, nil
+}
+
+type tagRepoStub struct {
+	setCalled bool
+	err       error
+}
+
+func (s *tagRepoStub) SetPostTags(context.Context, string, []*models.Tag) error {
+	s.setCalled = true
+	return s.err
+}
+
+func (s *tagRepoStub) ListByPost(context.Context, string) ([]*models.Tag, error) {
+	return nil, nil
+}
+
+func (s *tagRepoStub) List(context.Context, int32, *string) ([]*models.Tag, *string, error) {
+	return nil, nil, nil
+}
+
+func (s *tagRepoStub) ListPosts(context.Context, string, int32, *string, auth.Viewer) ([]*models.Post, *string, error) {
+	return nil, nil, nil
+}
+
 // Тест на базовую валидацию входных данных при создании поста.
 func TestPostService_Create_Table(t *testing.T) {
 	tests := []struct {
@@ -36,6 +97,7 @@ func TestPostService_Create_Table(t *testing.T) {
 		input models.CreatePostInput
 		err   bool
 		call  bool
+		tags  bool
 	}{
 		{
 			name:  "Нет автора",
@@ -62,19 +124,58 @@ func TestPostService_Create_Table(t *testing.T) {
 			input: models.CreatePostInput{AuthorID: "u", Title: "t", Body: strings.Repeat("a", 2001)},
 			err:   true,
 		},
+		{
+			name:  "Слишком много тегов",
+			input: models.CreatePostInput{AuthorID: "u", Title: "t", Body: "b", Tags: []string{"a", "b", "c", "d", "e", "f"}},
+			err:   true,
+		},
+		{
+			name:  "Неверный тег",
+			input: models.CreatePostInput{AuthorID: "u", Title: "t", Body: "b", Tags: []string{"!!!"}},
+			err:   true,
+		},
+		{
+			name:  "Отложенный без publishAt",
+			input: models.CreatePostInput{AuthorID: "u", Title: "t", Body: "b", Status: statusPtr(models.PostStatusScheduled)},
+			err:   true,
+		},
+		{
+			name:  "Отложенный в прошлом",
+			input: models.CreatePostInput{AuthorID: "u", Title: "t", Body: "b", PublishAt: timePtr(time.Now().Add(-time.Hour))},
+			err:   true,
+		},
+		{
+			name:  "Создание в архиве",
+			input: models.CreatePostInput{AuthorID: "u", Title: "t", Body: "b", Status: statusPtr(models.PostStatusArchived)},
+			err:   true,
+		},
+		{
+			name:  "Черновик",
+			input: models.CreatePostInput{AuthorID: "u", Title: "t", Body: "b", Status: statusPtr(models.PostStatusDraft)},
+			err:   false,
+			call:  true,
+		},
 		{
 			name:  "Успешное создание",
 			input: models.CreatePostInput{AuthorID: "u", Title: "t", Body: "b"},
 			err:   false,
 			call:  true,
 		},
+		{
+			name:  "Успешное создание с тегами",
+			input: models.CreatePostInput{AuthorID: "u", Title: "t", Body: "b", Tags: []string{"Go", "go"}},
+			err:   false,
+			call:  true,
+			tags:  true,
+		},
 	}
 
 	for _, tc := range tests {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			repo := &postRepoStub{}
-			svc := NewPostService(repo)
+			tags := &tagRepoStub{}
+			svc := NewPostService(repo, tags, repository.NewMemoryTransactor(repository.NewMemoryStorageWithTTL(0)), nil, nil, nil)
 
 			_, err := svc.Create(context.Background(), tc.input)
 			if tc.err && err == nil {
@@ -89,6 +190,157 @@ func TestPostService_Create_Table(t *testing.T) {
 			if !tc.call && repo.createCalled {
 				t.Fatalf("ошибка - repo.Create")
 			}
+			if tc.tags != tags.setCalled {
+				t.Fatalf("ожидался tags.SetPostTags=%v, а получили %v", tc.tags, tags.setCalled)
+			}
+		})
+	}
+}
+
+// Тест на откат поста, если теги не сохранились.
+func TestPostService_Create_TagsRollback(t *testing.T) {
+	env := newTestEnv(t)
+	tagsErr := errors.New("теги недоступны")
+	env.tags = &tagRepoStub{err: tagsErr}
+	svc := env.postService(nil)
+
+	_, err := svc.Create(context.Background(), models.CreatePostInput{AuthorID: "alice", Title: "t", Body: "b", Tags: []string{"go"}})
+	if !errors.Is(err, tagsErr) {
+		t.Fatalf("ожидалась ошибка тегов, а получили %v", err)
+	}
+	posts, _, err := env.posts.List(context.Background(), 10, nil, auth.Viewer{UserID: "alice", Role: models.RoleUser})
+	if err != nil {
+		t.Fatalf("список постов: %v", err)
+	}
+	if len(posts) != 0 {
+		t.Fatalf("пост остался без тегов: %v", posts)
+	}
+}
+
+// Тест на публикацию поста и событие о публикации.
+func TestPostService_Publish(t *testing.T) {
+	tests := []struct {
+		name   string
+		post   *models.Post
+		viewer string
+		err    error
+		event  bool
+	}{
+		{
+			name: "Аноним",
+			post: &models.Post{ID: "p1", Status: models.PostStatusDraft, Author: &models.User{ID: "u"}},
+			err:  ErrUnauthorized,
+		},
+		{
+			name:   "Чужой пост",
+			post:   &models.Post{ID: "p1", Status: models.PostStatusDraft, Author: &models.User{ID: "u"}},
+			viewer: "other",
+			err:    ErrForbidden,
+		},
+		{
+			name:   "Нет поста",
+			viewer: "u",
+			err:    ErrPostNotFound,
+		},
+		{
+			name:   "Уже опубликован",
+			post:   &models.Post{ID: "p1", Status: models.PostStatusPublished, Author: &models.User{ID: "u"}},
+			viewer: "u",
+			err:    repository.ErrStatusConflict,
+		},
+		{
+			name:   "Публикация черновика",
+			post:   &models.Post{ID: "p1", Status: models.PostStatusDraft, Author: &models.User{ID: "u"}},
+			viewer: "u",
+			event:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			published := NewNotifier[*models.Post]()
+			svc := NewPostService(&postRepoStub{post: tc.post}, &tagRepoStub{}, repository.NewMemoryTransactor(repository.NewMemoryStorageWithTTL(0)), published, nil, nil)
+			events, unsubscribe, err := svc.SubscribePublished()
+			if err != nil {
+				t.Fatalf("подписка: %v", err)
+			}
+			defer unsubscribe()
+
+			ctx := context.Background()
+			if tc.viewer != "" {
+				ctx = auth.WithViewer(ctx, auth.Viewer{UserID: tc.viewer})
+			}
+
+			post, err := svc.Publish(ctx, "p1")
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("ожидалось %v, а получили %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if post.Status != models.PostStatusPublished {
+				t.Fatalf("ожидался статус PUBLISHED, а получили %s", post.Status)
+			}
+
+			select {
+			case <-events:
+				if !tc.event {
+					t.Fatalf("событие не ожидалось")
+				}
+			default:
+				if tc.event {
+					t.Fatalf("ожидалось событие о публикации")
+				}
+			}
+		})
+	}
+}
+
+func statusPtr(s models.PostStatus) *models.PostStatus { return &s }
+func timePtr(t time.Time) *time.Time                   { return &t }
+
+// Тест на замену тегов: только автор поста.
+func TestPostService_SetTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		viewer  string
+		status  models.PostStatus
+		wantErr error
+	}{
+		{name: "Аноним", status: models.PostStatusPublished, wantErr: ErrUnauthorized},
+		{name: "Чужой пост", viewer: "alice", status: models.PostStatusPublished, wantErr: ErrForbidden},
+		{name: "Чужой черновик", viewer: "alice", status: models.PostStatusDraft, wantErr: ErrForbidden},
+		{name: "Свой черновик", viewer: "bob", status: models.PostStatusDraft},
+		{name: "Свой пост", viewer: "bob", status: models.PostStatusPublished},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			svc := env.postService(nil)
+			post := env.createPost(t, "bob", tc.status)
+
+			ctx := context.Background()
+			if tc.viewer != "" {
+				ctx = auth.WithViewer(ctx, auth.Viewer{UserID: tc.viewer})
+			}
+			_, err := svc.SetTags(ctx, post.ID, []string{"Go"})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, err)
+			}
+
+			tags, err := env.tags.ListByPost(context.Background(), post.ID)
+			if err != nil {
+				t.Fatalf("теги поста: %v", err)
+			}
+			if want := tc.wantErr == nil; want != (len(tags) == 1) {
+				t.Fatalf("ожидалась замена тегов %v, а получили %d тегов", want, len(tags))
+			}
 		})
 	}
 }
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+	"github.com/RoGogDBD/GQLGo/internal/repository"
+)
+
+// ReaderService закладки и прочтение постов текущим пользователем.
+type ReaderService struct {
+	posts     *PostService
+	bookmarks repository.BookmarkRepo
+	reads     repository.ReadStateRepo
+}
+
+func NewReaderService(posts *PostService, bookmarks repository.BookmarkRepo, reads repository.ReadStateRepo) *ReaderService {
+	return &ReaderService{posts: posts, bookmarks: bookmarks, reads: reads}
+}
+
+// Bookmark добавляет видимый пользователю пост в закладки.
+func (s *ReaderService) Bookmark(ctx context.Context, postID string) (*models.Post, error) {
+	viewer, post, err := s.viewerPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.bookmarks.Add(ctx, viewer.UserID, post.ID); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// RemoveBookmark удаляет пост из закладок, даже если пост уже скрыт.
+func (s *ReaderService) RemoveBookmark(ctx context.Context, postID string) (*models.Post, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	if err := s.bookmarks.Remove(ctx, viewer.UserID, postID); err != nil {
+		return nil, err
+	}
+	return s.posts.Get(ctx, postID)
+}
+
+func (s *ReaderService) Bookmarks(ctx context.Context, userID string, first int32, after *string) ([]*models.Post, *string, error) {
+	viewer, _ := auth.ViewerFrom(ctx)
+	return s.bookmarks.List(ctx, userID, first, after, viewer)
+}
+
+// Bookmarked пост в закладках текущего пользователя, false для анонима.
+func (s *ReaderService) Bookmarked(ctx context.Context, postID string) (bool, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return false, nil
+	}
+	return s.bookmarks.Has(ctx, viewer.UserID, postID)
+}
+
+// MarkPostRead отмечает все текущие комментарии поста прочитанными.
+func (s *ReaderService) MarkPostRead(ctx context.Context, postID string) (*models.Post, error) {
+	viewer, post, err := s.viewerPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reads.MarkRead(ctx, viewer.UserID, post.ID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// LastReadAt когда текущий пользователь читал пост, nil для анонима.
+func (s *ReaderService) LastReadAt(ctx context.Context, postID string) (*time.Time, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return nil, nil
+	}
+	return s.reads.LastReadAt(ctx, viewer.UserID, postID)
+}
+
+// UnreadCommentCount чужие комментарии после прочтения, 0 для анонима.
+func (s *ReaderService) UnreadCommentCount(ctx context.Context, postID string) (int, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return 0, nil
+	}
+	return s.reads.CountUnread(ctx, postID, viewer)
+}
+
+// IsNew комментарий появился после прочтения поста текущим пользователем.
+// Свои, невидимые пользователю комментарии и комментарии для анонима новыми не считаются.
+func (s *ReaderService) IsNew(ctx context.Context, c *models.Comment) (bool, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok || c.Author == nil || viewer.IsAuthor(c.Author.ID) || !repository.CommentVisible(c, viewer) {
+		return false, nil
+	}
+	at, err := s.reads.LastReadAt(ctx, viewer.UserID, c.PostID)
+	if err != nil {
+		return false, err
+	}
+	return at == nil || c.CreatedAt.After(*at), nil
+}
+
+// viewerPost текущий пользователь и видимый ему пост.
+func (s *ReaderService) viewerPost(ctx context.Context, postID string) (auth.Viewer, *models.Post, error) {
+	viewer, ok := auth.ViewerFrom(ctx)
+	if !ok {
+		return viewer, nil, ErrUnauthorized
+	}
+	post, err := s.posts.Get(ctx, postID)
+	if err != nil {
+		return viewer, nil, err
+	}
+	return viewer, post, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RoGogDBD/GQLGo/internal/auth"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+)
+
+// Тест на закладки и флаг новых комментариев.
+func TestReaderService_BookmarkIsNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		viewer  string
+		status  models.PostStatus
+		wantErr error
+		isNew   bool
+	}{
+		{name: "Закладка на пост", viewer: "alice", status: models.PostStatusPublished, isNew: true},
+		{name: "Аноним", status: models.PostStatusPublished, wantErr: ErrUnauthorized},
+		{name: "Чужой черновик", viewer: "alice", status: models.PostStatusDraft, wantErr: ErrPostNotFound},
+		{name: "Свой комментарий не новый", viewer: "bob", status: models.PostStatusPublished, isNew: false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			svc := env.readerService(env.postService(nil))
+			ctx := auth.WithViewer(context.Background(), auth.Viewer{UserID: tc.viewer})
+			post := env.createPost(t, "bob", tc.status)
+
+			_, err := svc.Bookmark(ctx, post.ID)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("закладка: %v", err)
+			}
+
+			list, _, err := svc.Bookmarks(ctx, tc.viewer, 10, nil)
+			if err != nil {
+				t.Fatalf("список закладок: %v", err)
+			}
+			if len(list) != 1 || list[0].ID != post.ID {
+				t.Fatalf("ожидалась одна закладка, а получили %d", len(list))
+			}
+
+			comment := &models.Comment{PostID: post.ID, Author: &models.User{ID: "bob"}, CreatedAt: post.CreatedAt}
+			isNew, err := svc.IsNew(ctx, comment)
+			if err != nil {
+				t.Fatalf("флаг нового: %v", err)
+			}
+			if isNew != tc.isNew {
+				t.Fatalf("ожидалось isNew=%v, а получили %v", tc.isNew, isNew)
+			}
+
+			if _, err := svc.MarkPostRead(ctx, post.ID); err != nil {
+				t.Fatalf("прочтение: %v", err)
+			}
+			if isNew, _ := svc.IsNew(ctx, comment); isNew {
+				t.Fatalf("после прочтения комментарий не должен быть новым")
+			}
+		})
+	}
+}
+
+// Тест на флаг новых для скрытых комментариев: виден только модератору.
+func TestReaderService_IsNewHidden(t *testing.T) {
+	tests := []struct {
+		name   string
+		viewer auth.Viewer
+		state  models.ModerationState
+		isNew  bool
+	}{
+		{name: "Видимый", viewer: auth.Viewer{UserID: "alice"}, state: models.ModerationStateVisible, isNew: true},
+		{name: "Скрытый", viewer: auth.Viewer{UserID: "alice"}, state: models.ModerationStateHidden},
+		{name: "На модерации", viewer: auth.Viewer{UserID: "alice"}, state: models.ModerationStatePending},
+		{name: "Скрытый для модератора", viewer: moderatorViewer, state: models.ModerationStateHidden, isNew: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			svc := env.readerService(env.postService(nil))
+			post := env.createPost(t, "bob", "")
+
+			comment := &models.Comment{PostID: post.ID, Author: &models.User{ID: "bob"}, CreatedAt: post.CreatedAt, ModerationState: tc.state}
+			isNew, err := svc.IsNew(auth.WithViewer(context.Background(), tc.viewer), comment)
+			if err != nil {
+				t.Fatalf("флаг нового: %v", err)
+			}
+			if isNew != tc.isNew {
+				t.Fatalf("ожидалось isNew=%v, а получили %v", tc.isNew, isNew)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/RoGogDBD/GQLGo/internal/models"
+)
+
+const (
+	MaxPostTags  = 5
+	maxTagLength = 32
+)
+
+var (
+	ErrTooManyTags = errors.New("слишком много тегов")
+	ErrInvalidTag  = errors.New("неверный тег")
+)
+
+// TagSlug приводит тег к slug: нижний регистр, буквы и цифры через дефис.
+func TagSlug(raw string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(raw)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// NormalizeTags нормализует теги поста, убирает дубликаты и проверяет лимит.
+func NormalizeTags(raw []string) ([]*models.Tag, error) {
+	tags := make([]*models.Tag, 0, len(raw))
+	seen := make(map[string]struct{}, len(raw))
+	for _, r := range raw {
+		name := strings.ToLower(strings.Join(strings.Fields(r), " "))
+		slug := TagSlug(name)
+		if slug == "" || utf8.RuneCountInString(name) > maxTagLength || utf8.RuneCountInString(slug) > maxTagLength {
+			return nil, ErrInvalidTag
+		}
+		if _, ok := seen[slug]; ok {
+			continue
+		}
+		seen[slug] = struct{}{}
+		tags = append(tags, &models.Tag{Name: name, Slug: slug})
+	}
+	if len(tags) > MaxPostTags {
+		return nil, ErrTooManyTags
+	}
+	return tags, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Тест на нормализацию тегов поста.
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []string
+		slugs []string
+		err   error
+	}{
+		{
+			name:  "Нижний регистр и slug",
+			input: []string{"  Go Lang ", "GraphQL"},
+			slugs: []string{"go-lang", "graphql"},
+		},
+		{
+			name:  "Дубликаты",
+			input: []string{"go", "GO", " go "},
+			slugs: []string{"go"},
+		},
+		{
+			name:  "Кириллица",
+			input: []string{"Новости Дня!"},
+			slugs: []string{"новости-дня"},
+		},
+		{
+			name:  "Пустой тег",
+			input: []string{"  "},
+			err:   ErrInvalidTag,
+		},
+		{
+			name:  "Длинный тег",
+			input: []string{strings.Repeat("a", 33)},
+			err:   ErrInvalidTag,
+		},
+		{
+			name:  "Больше лимита",
+			input: []string{"a", "b", "c", "d", "e", "f"},
+			err:   ErrTooManyTags,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tags, err := NormalizeTags(tc.input)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("ожидалось %v, а получили %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if len(tags) != len(tc.slugs) {
+				t.Fatalf("ожидалось %d тегов, а получили %d", len(tc.slugs), len(tags))
+			}
+			for i, tag := range tags {
+				if tag.Slug != tc.slugs[i] {
+					t.Fatalf("ожидался slug %q, а получили %q", tc.slugs[i], tag.Slug)
+				}
+			}
+		})
+	}
+}
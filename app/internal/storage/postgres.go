@@ -3,67 +3,153 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
-	"github.com/RoGogDBD/GQLGo/internal/config/migrate"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
 	"github.com/uptrace/bun/driver/pgdriver"
 )
 
-// Параметры для конфигурации бд.
+// Параметры пула по умолчанию.
 const (
-	contextTimeout  = 5 * time.Second
-	connMaxLifetime = 5 * time.Minute
-	connMaxIdleTime = 5 * time.Minute
-
-	maxOpenConns = 25
-	maxIdleConns = 10
+	DefaultMaxOpenConns    = 25
+	DefaultMaxIdleConns    = 10
+	DefaultConnMaxLifetime = 5 * time.Minute
+	DefaultConnMaxIdleTime = 5 * time.Minute
+	DefaultConnectTimeout  = 5 * time.Second
 )
 
+// PoolConfig пул подключений и таймауты БД.
+type PoolConfig struct {
+	// MaxOpenConns 0 - без ограничения.
+	MaxOpenConns    int           `cfg:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `cfg:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `cfg:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `cfg:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	// ConnectTimeout ожидание первого ping при открытии, 0 - по умолчанию.
+	ConnectTimeout time.Duration `cfg:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+	// StatementTimeout предел одного запроса на стороне Postgres, 0 - без предела.
+	StatementTimeout time.Duration `cfg:"statement_timeout" env:"DB_STATEMENT_TIMEOUT"`
+}
+
+// DefaultPoolConfig пул по умолчанию.
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    DefaultMaxOpenConns,
+		MaxIdleConns:    DefaultMaxIdleConns,
+		ConnMaxLifetime: DefaultConnMaxLifetime,
+		ConnMaxIdleTime: DefaultConnMaxIdleTime,
+		ConnectTimeout:  DefaultConnectTimeout,
+	}
+}
+
 // DBStorage хранилище с подключением к БД.
 type DBStorage struct {
 	sqldb *sql.DB
 	db    *bun.DB
+
+	replicaSQL *sql.DB
+	replica    *bun.DB
+	pool       PoolConfig
 }
 
-// NewDataStorage создает подключение к БД.
-func NewDataStorage(dsn string) (*DBStorage, error) {
-	if err := migrate.RunMigrations(dsn); err != nil {
-		return nil, fmt.Errorf("run migrations: %w", err)
+// NewDataStorage создает подключение к БД, миграции применяются отдельно.
+func NewDataStorage(dsn string, pool PoolConfig) (*DBStorage, error) {
+	sqldb, err := openPostgres(dsn, pool)
+	if err != nil {
+		return nil, err
 	}
+	return &DBStorage{
+		sqldb: sqldb,
+		db:    bun.NewDB(sqldb, pgdialect.New()),
+		pool:  pool,
+	}, nil
+}
 
-	sqldb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn)))
+// AttachReplica открывает отдельный пул реплики только для чтения с теми же настройками.
+func (s *DBStorage) AttachReplica(dsn string) error {
+	sqldb, err := openPostgres(dsn, s.pool)
+	if err != nil {
+		return fmt.Errorf("replica: %w", err)
+	}
+	s.replicaSQL = sqldb
+	s.replica = bun.NewDB(sqldb, pgdialect.New())
+	return nil
+}
 
-	// Конфиг.
-	sqldb.SetMaxOpenConns(maxOpenConns)
-	sqldb.SetMaxIdleConns(maxIdleConns)
-	sqldb.SetConnMaxLifetime(connMaxLifetime)
-	sqldb.SetConnMaxIdleTime(connMaxIdleTime)
+func openPostgres(dsn string, pool PoolConfig) (*sql.DB, error) {
+	opts := []pgdriver.Option{pgdriver.WithDSN(dsn)}
+	if pool.StatementTimeout > 0 {
+		opts = append(opts, withConnParam("statement_timeout", pool.StatementTimeout.Milliseconds()))
+	}
+	sqldb := sql.OpenDB(pgdriver.NewConnector(opts...))
+	if err := setupPool(sqldb, pool); err != nil {
+		_ = sqldb.Close()
+		return nil, err
+	}
+	return sqldb, nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), contextTimeout)
+// withConnParam добавляет параметр сессии, не затирая параметры из DSN.
+func withConnParam(key string, value any) pgdriver.Option {
+	return func(cfg *pgdriver.Config) {
+		if cfg.ConnParams == nil {
+			cfg.ConnParams = map[string]any{}
+		}
+		cfg.ConnParams[key] = value
+	}
+}
+
+// setupPool настраивает пул и проверяет подключение.
+func setupPool(sqldb *sql.DB, pool PoolConfig) error {
+	sqldb.SetMaxOpenConns(pool.MaxOpenConns)
+	sqldb.SetMaxIdleConns(pool.MaxIdleConns)
+	sqldb.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	sqldb.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+
+	timeout := pool.ConnectTimeout
+	if timeout <= 0 {
+		timeout = DefaultConnectTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	if err := sqldb.PingContext(ctx); err != nil {
-		_ = sqldb.Close()
-		return nil, fmt.Errorf("db ping: %w", err)
+		return fmt.Errorf("db ping: %w", err)
 	}
-
-	db := bun.NewDB(sqldb, pgdialect.New())
-
-	return &DBStorage{
-		sqldb: sqldb,
-		db:    db,
-	}, nil
+	return nil
 }
 
-// Close закрывает соединение с БД.
+// Close закрывает соединения с БД и репликой.
 func (s *DBStorage) Close() error {
-	return s.sqldb.Close()
+	var replicaErr error
+	if s.replicaSQL != nil {
+		replicaErr = s.replicaSQL.Close()
+	}
+	return errors.Join(s.sqldb.Close(), replicaErr)
+}
+
+// Ping проверяет подключение к БД и реплике.
+func (s *DBStorage) Ping(ctx context.Context) error {
+	if err := s.sqldb.PingContext(ctx); err != nil {
+		return fmt.Errorf("db ping: %w", err)
+	}
+	if s.replicaSQL != nil {
+		if err := s.replicaSQL.PingContext(ctx); err != nil {
+			return fmt.Errorf("replica ping: %w", err)
+		}
+	}
+	return nil
 }
 
 // DB возвращает объект БД.
 func (s *DBStorage) DB() *bun.DB {
 	return s.db
 }
+
+// Replica реплика для чтения, nil если не подключена.
+func (s *DBStorage) Replica() *bun.DB {
+	return s.replica
+}
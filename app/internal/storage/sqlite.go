@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	_ "modernc.org/sqlite"
+)
+
+// Параметры подключения SQLite: внешние ключи, WAL и ожидание блокировки.
+// Транзакции сразу берут блокировку на запись, чтобы не ловить SQLITE_BUSY при upgrade.
+const sqliteParams = "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate"
+
+// NewSQLiteStorage открывает файл SQLite, миграции применяются отдельно.
+// StatementTimeout к SQLite не применяется.
+func NewSQLiteStorage(path string, pool PoolConfig) (*DBStorage, error) {
+	sqldb, err := sql.Open("sqlite", "file:"+path+sqliteParams)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite open: %w", err)
+	}
+	if err := setupPool(sqldb, pool); err != nil {
+		_ = sqldb.Close()
+		return nil, err
+	}
+
+	return &DBStorage{
+		sqldb: sqldb,
+		db:    bun.NewDB(sqldb, sqlitedialect.New()),
+		pool:  pool,
+	}, nil
+}
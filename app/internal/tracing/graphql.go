@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Extension спан на каждую операцию GraphQL и, если Resolvers, на каждый резолвер.
+type Extension struct {
+	// Resolvers спаны резолверов полей: подробно, но много спанов на списках.
+	Resolvers bool
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = Extension{}
+
+func (Extension) ExtensionName() string {
+	return "Tracing"
+}
+
+func (Extension) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse спан операции, у подписки - на каждое событие.
+func (Extension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	opCtx := graphql.GetOperationContext(ctx)
+	name, typ := "anonymous", ""
+	if opCtx.Operation != nil {
+		typ = string(opCtx.Operation.Operation)
+		if opCtx.Operation.Name != "" {
+			name = opCtx.Operation.Name
+		}
+	}
+	ctx, span := Tracer().Start(ctx, "graphql "+typ+" "+name, trace.WithAttributes(
+		attribute.String("graphql.operation.name", name),
+		attribute.String("graphql.operation.type", typ),
+	))
+	defer span.End()
+
+	resp := next(ctx)
+	if resp != nil && len(resp.Errors) > 0 {
+		span.SetStatus(codes.Error, resp.Errors[0].Message)
+		span.SetAttributes(attribute.Int("graphql.errors", len(resp.Errors)))
+	}
+	return resp
+}
+
+// InterceptField спан резолвера, простые поля структур пропускаются.
+func (e Extension) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if !e.Resolvers || fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+	ctx, span := Tracer().Start(ctx, fc.Object+"."+fc.Field.Name, trace.WithAttributes(
+		attribute.String("graphql.field.path", fc.Path().String()),
+	))
+	defer span.End()
+
+	res, err := next(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return res, err
+}
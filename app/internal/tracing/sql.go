@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/uptrace/bun"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxStatement длиннее запрос обрезается в атрибуте спана.
+const maxStatement = 2048
+
+type spanKey struct{}
+
+// QueryHook спан на каждый SQL запрос bun: db.AddQueryHook(tracing.QueryHook{}).
+type QueryHook struct{}
+
+var _ bun.QueryHook = QueryHook{}
+
+func (QueryHook) BeforeQuery(ctx context.Context, event *bun.QueryEvent) context.Context {
+	op := event.Operation()
+	ctx, span := Tracer().Start(ctx, "db "+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", dbSystem(event.DB)),
+		attribute.String("db.operation", op),
+		attribute.String("db.statement", truncate(event.Query, maxStatement)),
+	))
+	// Спан в Stash, а не в контексте: без выборки SpanFromContext вернул бы родителя.
+	if event.Stash == nil {
+		event.Stash = make(map[any]any)
+	}
+	event.Stash[spanKey{}] = span
+	return ctx
+}
+
+func (QueryHook) AfterQuery(_ context.Context, event *bun.QueryEvent) {
+	span, ok := event.Stash[spanKey{}].(trace.Span)
+	if !ok {
+		return
+	}
+	if event.Err != nil && !errors.Is(event.Err, sql.ErrNoRows) {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Err.Error())
+	}
+	if event.Result != nil {
+		if n, err := event.Result.RowsAffected(); err == nil {
+			span.SetAttributes(attribute.Int64("db.rows_affected", n))
+		}
+	}
+	span.End()
+}
+
+func dbSystem(db *bun.DB) string {
+	if db == nil {
+		return ""
+	}
+	switch name := db.Dialect().Name().String(); name {
+	case "pg":
+		return "postgresql"
+	default:
+		return name
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
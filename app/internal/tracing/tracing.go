@@ -0,0 +1,132 @@
+// Package tracing трассировка OpenTelemetry: провайдер и экспорт, операции и резолверы GraphQL, запросы SQL.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/RoGogDBD/GQLGo/internal/version"
+)
+
+// Экспорт спанов.
+const (
+	// ExporterNone спаны не пишутся, контекст трассировки все равно передается дальше.
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// instrumentation имя трассировщика сервиса.
+const instrumentation = "github.com/RoGogDBD/GQLGo"
+
+var ErrExporter = errors.New("неизвестный экспорт трассировки")
+
+// Config настройки трассировки.
+type Config struct {
+	// Exporter none, stdout или otlp.
+	Exporter string
+	// Endpoint адрес OTLP/HTTP коллектора, пусто - из OTEL_EXPORTER_OTLP_ENDPOINT.
+	Endpoint    string
+	ServiceName string
+	// SampleRatio доля новых трасс, входящие решения родителя соблюдаются.
+	SampleRatio float64
+}
+
+// Setup ставит глобальные провайдер и W3C trace-context, возвращает сброс спанов при остановке.
+func Setup(ctx context.Context, cfg Config, onError func(error)) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if onError != nil {
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(onError))
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("tracing stdout: %w", err)
+		}
+		exporter = exp
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("tracing otlp: %w", err)
+		}
+		exporter = exp
+	default:
+		return nil, fmt.Errorf("%q: %w", cfg.Exporter, ErrExporter)
+	}
+
+	tp := NewProvider(cfg, sdktrace.NewBatchSpanProcessor(exporter))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// NewProvider провайдер с ресурсом сервиса и выборкой из cfg, processor - куда уходят спаны.
+func NewProvider(cfg Config, processor sdktrace.SpanProcessor) *sdktrace.TracerProvider {
+	name := cfg.ServiceName
+	if name == "" {
+		name = "gqlgo"
+	}
+	res := resource.NewSchemaless(
+		attribute.String("service.name", name),
+		attribute.String("service.version", version.Get().Version),
+	)
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithSpanProcessor(processor),
+	)
+}
+
+// Tracer трассировщик сервиса из текущего глобального провайдера.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Extract контекст трассировки из carrier, например заголовков или init payload websocket.
+func Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// PayloadCarrier контекст трассировки в init payload websocket: браузер не может
+// передать заголовки при открытии сокета, поэтому traceparent приходит в payload.
+type PayloadCarrier map[string]any
+
+var _ propagation.TextMapCarrier = PayloadCarrier{}
+
+func (c PayloadCarrier) Get(key string) string {
+	s, _ := c[key].(string)
+	return s
+}
+
+func (c PayloadCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c PayloadCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,157 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	_ "modernc.org/sqlite"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// record ставит глобальный провайдер с записью спанов в память.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(NewProvider(Config{SampleRatio: 1}, rec))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prev)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return rec
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) map[string]sdktrace.ReadOnlySpan {
+	out := make(map[string]sdktrace.ReadOnlySpan, len(spans))
+	for _, s := range spans {
+		out[s.Name()] = s
+	}
+	return out
+}
+
+// Тест на спаны операции и резолверов: резолверы только по флагу и только настоящие.
+func TestExtension(t *testing.T) {
+	tests := []struct {
+		name      string
+		resolvers bool
+		field     *graphql.FieldContext
+		want      []string
+		notWant   []string
+	}{
+		{
+			name:  "Только операция",
+			field: &graphql.FieldContext{Object: "Query", Field: graphql.CollectedField{Field: &ast.Field{Name: "GetPost"}}, IsResolver: true},
+			want:  []string{"graphql query Feed"}, notWant: []string{"Query.GetPost"},
+		},
+		{
+			name: "С резолверами", resolvers: true,
+			field: &graphql.FieldContext{Object: "Query", Field: graphql.CollectedField{Field: &ast.Field{Name: "GetPost"}}, IsResolver: true},
+			want:  []string{"graphql query Feed", "Query.GetPost"},
+		},
+		{
+			name: "Поле структуры", resolvers: true,
+			field: &graphql.FieldContext{Object: "Post", Field: graphql.CollectedField{Field: &ast.Field{Name: "title"}}},
+			want:  []string{"graphql query Feed"}, notWant: []string{"Post.title"},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			rec := record(t)
+			ext := Extension{Resolvers: tc.resolvers}
+			ctx := graphql.WithOperationContext(context.Background(), &graphql.OperationContext{
+				Operation: &ast.OperationDefinition{Operation: ast.Query, Name: "Feed"},
+			})
+			ext.InterceptResponse(ctx, func(ctx context.Context) *graphql.Response {
+				_, _ = ext.InterceptField(graphql.WithFieldContext(ctx, tc.field), func(context.Context) (any, error) { return nil, nil })
+				return &graphql.Response{}
+			})
+
+			spans := spanNames(rec.Ended())
+			for _, name := range tc.want {
+				if _, ok := spans[name]; !ok {
+					t.Fatalf("ожидался спан %q, а получили %v", name, spans)
+				}
+			}
+			for _, name := range tc.notWant {
+				if _, ok := spans[name]; ok {
+					t.Fatalf("спан %q не ожидался", name)
+				}
+			}
+		})
+	}
+}
+
+// Тест на спаны SQL: запрос внутри трассы запроса, ошибка отмечается.
+func TestQueryHook(t *testing.T) {
+	rec := record(t)
+	sqldb, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("открытие: %v", err)
+	}
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() { _ = db.Close() })
+	db.AddQueryHook(QueryHook{})
+
+	ctx, parent := Tracer().Start(context.Background(), "запрос")
+	var n int
+	if err := db.NewSelect().ColumnExpr("1").Scan(ctx, &n); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	_, _ = db.ExecContext(ctx, "SELECT * FROM нет_таблицы")
+	parent.End()
+
+	var dbSpans []sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended() {
+		if s.Name() == "db SELECT" {
+			dbSpans = append(dbSpans, s)
+		}
+	}
+	if len(dbSpans) != 2 {
+		t.Fatalf("ожидалось 2 спана SQL, а получили %d", len(dbSpans))
+	}
+	for _, s := range dbSpans {
+		if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("спан SQL не внутри запроса")
+		}
+	}
+	if dbSpans[1].Status().Code.String() != "Error" {
+		t.Fatalf("ожидалась ошибка в спане, а получили %v", dbSpans[1].Status())
+	}
+}
+
+// Тест на контекст трассировки из заголовков и init payload websocket.
+func TestExtract(t *testing.T) {
+	record(t)
+	tests := []struct {
+		name    string
+		carrier propagation.TextMapCarrier
+		want    string
+	}{
+		{name: "Заголовки", carrier: propagation.HeaderCarrier{"Traceparent": {testTraceParent}}, want: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "Init payload", carrier: PayloadCarrier{"traceparent": testTraceParent, "userId": "u1"}, want: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "Без контекста", carrier: PayloadCarrier{"userId": "u1"}, want: trace.TraceID{}.String()},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := Extract(context.Background(), tc.carrier)
+			if got := trace.SpanContextFromContext(ctx).TraceID().String(); got != tc.want {
+				t.Fatalf("ожидался trace id %s, а получили %s", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package trusted
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// apolloFormat значение format в манифесте Apollo.
+const apolloFormat = "apollo-persisted-query-manifest"
+
+var ErrEmptyManifest = errors.New("в манифесте нет операций")
+
+// Manifest одобренные операции по хэшу. Каждая доступна и по id из манифеста,
+// и по sha256 текста, чтобы клиент мог прислать и хэш, и полный текст.
+type Manifest struct {
+	byHash map[string]string
+}
+
+// Load манифест из JSON файла сборки фронтенда, см. Parse.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse разбирает манифест в одном из форматов:
+//   - объект хэш -> текст, как persisted-documents.json у GraphQL Codegen;
+//   - манифест Apollo: {"format": "apollo-persisted-query-manifest", "operations": [{"id", "body"}]}.
+func Parse(data []byte) (*Manifest, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	docs := map[string]string{}
+	if format, ok := raw["format"]; ok {
+		var apollo struct {
+			Format     string `json:"format"`
+			Version    int    `json:"version"`
+			Operations []struct {
+				ID   string `json:"id"`
+				Body string `json:"body"`
+			} `json:"operations"`
+		}
+		if err := json.Unmarshal(data, &apollo); err != nil {
+			return nil, err
+		}
+		if apollo.Format != apolloFormat || apollo.Version != 1 {
+			return nil, fmt.Errorf("неизвестный формат манифеста %s", format)
+		}
+		for i, op := range apollo.Operations {
+			if op.ID == "" || op.Body == "" {
+				return nil, fmt.Errorf("операция %d: пустой id или body", i)
+			}
+			docs[op.ID] = op.Body
+		}
+	} else {
+		for hash, body := range raw {
+			var s string
+			if err := json.Unmarshal(body, &s); err != nil || s == "" {
+				return nil, fmt.Errorf("%s: ожидался текст операции", hash)
+			}
+			docs[hash] = s
+		}
+	}
+	if len(docs) == 0 {
+		return nil, ErrEmptyManifest
+	}
+
+	m := &Manifest{byHash: make(map[string]string, 2*len(docs))}
+	for id, body := range docs {
+		m.byHash[id] = body
+		m.byHash[Hash(body)] = body
+	}
+	return m, nil
+}
+
+// Lookup текст операции по хэшу.
+func (m *Manifest) Lookup(hash string) (string, bool) {
+	body, ok := m.byHash[hash]
+	return body, ok
+}
+
+// OperationNames имена операций из документов манифеста, по алфавиту.
+// Документы, которые не разбираются, пропускаются: их все равно отклонит валидация.
+func (m *Manifest) OperationNames() []string {
+	seen := map[string]struct{}{}
+	for _, body := range m.byHash {
+		doc, err := parser.ParseQuery(&ast.Source{Input: body})
+		if err != nil {
+			continue
+		}
+		for _, op := range doc.Operations {
+			if op.Name != "" {
+				seen[op.Name] = struct{}{}
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Hash sha256 текста операции в hex, как у APQ.
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
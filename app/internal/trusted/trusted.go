@@ -0,0 +1,97 @@
+package trusted
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+)
+
+// Mode режим доверенных документов.
+type Mode string
+
+const (
+	// ModeOff любые операции, как раньше.
+	ModeOff Mode = "off"
+	// ModeReport операции не из манифеста выполняются, но пишутся в лог.
+	ModeReport Mode = "report"
+	// ModeEnforce операции не из манифеста отклоняются.
+	ModeEnforce Mode = "enforce"
+)
+
+func (m Mode) IsValid() bool {
+	switch m {
+	case ModeOff, ModeReport, ModeEnforce:
+		return true
+	}
+	return false
+}
+
+// CodeNotTrusted код ошибки в extensions для отклоненной операции.
+const CodeNotTrusted = "OPERATION_NOT_TRUSTED"
+
+var ErrNotTrusted = errors.New("операция не из списка доверенных")
+
+// Extension проверяет операцию по манифесту до разбора запроса. Клиент присылает
+// хэш в extensions.persistedQuery.sha256Hash (как APQ) или полный текст; текст
+// операции из манифеста подставляется вместо присланного. Должно стоять раньше APQ.
+type Extension struct {
+	Manifest *Manifest
+	Mode     Mode
+	Logger   logger.Logger
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = Extension{}
+
+func (Extension) ExtensionName() string {
+	return "TrustedDocuments"
+}
+
+func (e Extension) Validate(graphql.ExecutableSchema) error {
+	if e.Manifest == nil {
+		return errors.New("TrustedDocuments: манифест не задан")
+	}
+	return nil
+}
+
+func (e Extension) MutateOperationParameters(ctx context.Context, params *graphql.RawParams) *gqlerror.Error {
+	hash := persistedHash(params.Extensions)
+	if hash == "" {
+		if params.Query == "" {
+			return nil
+		}
+		hash = Hash(params.Query)
+	}
+	if body, ok := e.Manifest.Lookup(hash); ok {
+		params.Query = body
+		// Текст уже из манифеста, APQ его не проверяет и не кэширует.
+		delete(params.Extensions, "persistedQuery")
+		return nil
+	}
+
+	if e.Mode == ModeReport {
+		if e.Logger != nil {
+			e.Logger.Ctx(ctx).With("hash", hash, "operation_name", params.OperationName).Warnf("%v", ErrNotTrusted)
+		}
+		return nil
+	}
+	gqlErr := gqlerror.Errorf("%v", ErrNotTrusted)
+	gqlErr.Extensions = map[string]any{"code": CodeNotTrusted}
+	return gqlErr
+}
+
+// persistedHash хэш из extensions.persistedQuery, пусто без него.
+func persistedHash(extensions map[string]any) string {
+	pq, ok := extensions["persistedQuery"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	hash, _ := pq["sha256Hash"].(string)
+	return hash
+}
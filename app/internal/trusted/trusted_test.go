@@ -0,0 +1,123 @@
+package trusted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+
+	"github.com/RoGogDBD/GQLGo/internal/logger"
+)
+
+const feedQuery = "query Feed { GetPosts(first: 10) { edges { node { id } } } }"
+
+// Тест на форматы манифеста.
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+		fails   bool
+	}{
+		{name: "GraphQL Codegen", data: fmt.Sprintf(`{"abc": %q}`, feedQuery)},
+		{name: "Apollo", data: fmt.Sprintf(`{"format": "apollo-persisted-query-manifest", "version": 1, "operations": [{"id": "abc", "name": "Feed", "type": "query", "body": %q}]}`, feedQuery)},
+		{name: "Неизвестный формат", data: `{"format": "relay", "version": 1, "operations": []}`, fails: true},
+		{name: "Не текст", data: `{"abc": 1}`, fails: true},
+		{name: "Пустой", data: `{}`, wantErr: ErrEmptyManifest, fails: true},
+		{name: "Не JSON", data: `abc`, fails: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := Parse([]byte(tc.data))
+			if tc.fails {
+				if err == nil {
+					t.Fatal("ожидалась ошибка")
+				}
+				if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+					t.Fatalf("ожидалась ошибка %v, а получили %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ошибка не ожидалась: %v", err)
+			}
+			for _, hash := range []string{"abc", Hash(feedQuery)} {
+				if body, ok := m.Lookup(hash); !ok || body != feedQuery {
+					t.Fatalf("операция %s не найдена", hash)
+				}
+			}
+			if names := m.OperationNames(); len(names) != 1 || names[0] != "Feed" {
+				t.Fatalf("ожидалось имя операции Feed, а получили %v", names)
+			}
+		})
+	}
+}
+
+// warnCounter считает предупреждения.
+type warnCounter struct {
+	logger.Logger
+	warns *int
+}
+
+func (w warnCounter) Warnf(string, ...any)              { *w.warns++ }
+func (w warnCounter) With(...any) logger.Logger         { return w }
+func (w warnCounter) Ctx(context.Context) logger.Logger { return w }
+
+// Тест на проверку операции по манифесту в режимах report и enforce.
+func TestExtension(t *testing.T) {
+	m, err := Parse([]byte(fmt.Sprintf(`{"abc": %q}`, feedQuery)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	persisted := func(hash string) map[string]any {
+		return map[string]any{"persistedQuery": map[string]any{"version": 1, "sha256Hash": hash}}
+	}
+
+	tests := []struct {
+		name      string
+		mode      Mode
+		params    graphql.RawParams
+		wantQuery string
+		wantErr   bool
+		wantWarns int
+	}{
+		{name: "Хэш из манифеста", mode: ModeEnforce, params: graphql.RawParams{Extensions: persisted("abc")}, wantQuery: feedQuery},
+		{name: "Текст из манифеста", mode: ModeEnforce, params: graphql.RawParams{Query: feedQuery}, wantQuery: feedQuery},
+		{name: "Подмена текста при известном хэше", mode: ModeEnforce, params: graphql.RawParams{Query: "{ __schema { types { name } } }", Extensions: persisted("abc")}, wantQuery: feedQuery},
+		{name: "Чужой текст", mode: ModeEnforce, params: graphql.RawParams{Query: "{ __typename }"}, wantErr: true},
+		{name: "Чужой хэш", mode: ModeEnforce, params: graphql.RawParams{Extensions: persisted("def")}, wantErr: true},
+		{name: "Отчет о чужом тексте", mode: ModeReport, params: graphql.RawParams{Query: "{ __typename }"}, wantQuery: "{ __typename }", wantWarns: 1},
+		{name: "Отчет без нарушений", mode: ModeReport, params: graphql.RawParams{Query: feedQuery}, wantQuery: feedQuery},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var warns int
+			ext := Extension{Manifest: m, Mode: tc.mode, Logger: warnCounter{Logger: logger.Nop(), warns: &warns}}
+			params := tc.params
+
+			gqlErr := ext.MutateOperationParameters(context.Background(), &params)
+			if tc.wantErr {
+				if gqlErr == nil || gqlErr.Extensions["code"] != CodeNotTrusted {
+					t.Fatalf("ожидалась ошибка %s, а получили %v", CodeNotTrusted, gqlErr)
+				}
+				return
+			}
+			if gqlErr != nil {
+				t.Fatalf("ошибка не ожидалась: %v", gqlErr)
+			}
+			if params.Query != tc.wantQuery {
+				t.Fatalf("ожидался текст %q, а получили %q", tc.wantQuery, params.Query)
+			}
+			if _, ok := params.Extensions["persistedQuery"]; ok {
+				t.Fatal("persistedQuery должен быть убран, чтобы APQ не кэшировал операцию")
+			}
+			if warns != tc.wantWarns {
+				t.Fatalf("ожидалось %d предупреждений, а получили %d", tc.wantWarns, warns)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package graph
+
+import (
+	"github.com/RoGogDBD/GQLGo/internal/markup"
+	"github.com/RoGogDBD/GQLGo/internal/models"
+)
+
+// FormatBody тело в запрошенном формате, HTML берется из кеша записи.
+func FormatBody(body, bodyHTML string, format *models.BodyFormat) string {
+	f := models.BodyFormatMarkdown
+	if format != nil {
+		f = *format
+	}
+	if bodyHTML == "" {
+		bodyHTML = markup.Render(body)
+	}
+
+	switch f {
+	case models.BodyFormatHTML:
+		return bodyHTML
+	case models.BodyFormatPlain:
+		return markup.Plain(bodyHTML)
+	default:
+		return body
+	}
+}
@@ -4,13 +4,14 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/RoGogDBD/GQLGo/internal/auth"
 	"github.com/RoGogDBD/GQLGo/internal/models"
 )
 
 type (
 	// CommentLister получение комментариев.
 	CommentLister interface {
-		ListByParent(ctx context.Context, postID string, parentID *string, first int32, after *string, order models.CommentOrder) ([]*models.Comment, *string, error)
+		ListByParent(ctx context.Context, postID string, parentID *string, first int32, after *string, order models.CommentOrder, viewer auth.Viewer) ([]*models.Comment, *string, error)
 	}
 
 	// CommentMetaGetter получение мета-данных комментария.
@@ -62,6 +63,90 @@ func NewUserConnection(list []*models.User, hasNext bool) *models.UserConnection
 	}
 }
 
+// NewTagConnection создает TagConnection, курсор - slug тега.
+func NewTagConnection(list []*models.Tag, hasNext bool) *models.TagConnection {
+	edges := make([]*models.TagEdge, 0, len(list))
+	for _, t := range list {
+		edges = append(edges, &models.TagEdge{
+			Cursor: t.Slug,
+			Node:   t,
+		})
+	}
+	var endCursor *string
+	if len(list) > 0 {
+		slug := list[len(list)-1].Slug
+		endCursor = &slug
+	}
+	return &models.TagConnection{
+		Edges:      edges,
+		PageInfo:   &models.PageInfo{HasNextPage: hasNext, EndCursor: endCursor},
+		TotalCount: int32(len(edges)),
+	}
+}
+
+// NewNotificationConnection создает NotificationConnection.
+func NewNotificationConnection(list []*models.Notification, hasNext bool) *models.NotificationConnection {
+	edges := make([]*models.NotificationEdge, 0, len(list))
+	for _, n := range list {
+		edges = append(edges, &models.NotificationEdge{
+			Cursor: n.ID,
+			Node:   n,
+		})
+	}
+	var endCursor *string
+	if len(list) > 0 {
+		id := list[len(list)-1].ID
+		endCursor = &id
+	}
+	return &models.NotificationConnection{
+		Edges:      edges,
+		PageInfo:   &models.PageInfo{HasNextPage: hasNext, EndCursor: endCursor},
+		TotalCount: int32(len(edges)),
+	}
+}
+
+// NewAuditConnection создает AuditConnection.
+func NewAuditConnection(list []*models.AuditEntry, hasNext bool) *models.AuditConnection {
+	edges := make([]*models.AuditEdge, 0, len(list))
+	for _, e := range list {
+		edges = append(edges, &models.AuditEdge{
+			Cursor: e.ID,
+			Node:   e,
+		})
+	}
+	var endCursor *string
+	if len(list) > 0 {
+		id := list[len(list)-1].ID
+		endCursor = &id
+	}
+	return &models.AuditConnection{
+		Edges:      edges,
+		PageInfo:   &models.PageInfo{HasNextPage: hasNext, EndCursor: endCursor},
+		TotalCount: int32(len(edges)),
+	}
+}
+
+// NewReportConnection создает ReportConnection.
+func NewReportConnection(list []*models.Report, hasNext bool) *models.ReportConnection {
+	edges := make([]*models.ReportEdge, 0, len(list))
+	for _, r := range list {
+		edges = append(edges, &models.ReportEdge{
+			Cursor: r.ID,
+			Node:   r,
+		})
+	}
+	var endCursor *string
+	if len(list) > 0 {
+		id := list[len(list)-1].ID
+		endCursor = &id
+	}
+	return &models.ReportConnection{
+		Edges:      edges,
+		PageInfo:   &models.PageInfo{HasNextPage: hasNext, EndCursor: endCursor},
+		TotalCount: int32(len(edges)),
+	}
+}
+
 // NewCommentConnection создает CommentConnection.
 func NewCommentConnection(list []*models.Comment, hasNext bool) *models.CommentConnection {
 	edges := make([]*models.CommentEdge, 0, len(list))
@@ -94,7 +179,8 @@ func ResolveCommentConnection(ctx context.Context, repo CommentLister, postID st
 		ord = *order
 	}
 
-	list, _, err := repo.ListByParent(ctx, postID, parentID, f+1, after, ord)
+	viewer, _ := auth.ViewerFrom(ctx)
+	list, _, err := repo.ListByParent(ctx, postID, parentID, f+1, after, ord, viewer)
 	if err != nil {
 		return nil, err
 	}
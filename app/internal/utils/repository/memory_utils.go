@@ -24,6 +24,14 @@ func ClonePost(p *models.Post) *models.Post {
 	return &c
 }
 
+func CloneTag(t *models.Tag) *models.Tag {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
 func SortedKeys[T any](m map[string]T) []string {
 	idList := make([]string, 0, len(m))
 	for id := range m {
@@ -52,29 +60,38 @@ func SortCommentIDs(ids []string, comments map[string]*models.Comment, order mod
 	})
 }
 
-func PaginateIDs(ids []string, after *string, first int32) []string {
+// PaginateIDs страница из first id после after, для которых keep истинно (nil - все).
+// Курсор ищется в ids без фильтра, как в Postgres: курсор на отфильтрованный
+// элемент остается валидным. ok false, если after нет в ids.
+func PaginateIDs(ids []string, after *string, first int32, keep func(id string) bool) ([]string, bool) {
 	if first <= 0 {
 		first = defaultPageSize
 	}
 
 	start := 0
 	if after != nil && *after != "" {
+		start = -1
 		for i, id := range ids {
 			if id == *after {
 				start = i + 1
 				break
 			}
 		}
+		if start < 0 {
+			return nil, false
+		}
 	}
 
-	end := start + int(first)
-	if end > len(ids) {
-		end = len(ids)
-	}
-	if start > len(ids) {
-		start = len(ids)
+	page := make([]string, 0, first)
+	for _, id := range ids[start:] {
+		if len(page) == int(first) {
+			break
+		}
+		if keep == nil || keep(id) {
+			page = append(page, id)
+		}
 	}
-	return ids[start:end]
+	return page, true
 }
 
 func RemoveID(ids []string, id string) []string {
@@ -0,0 +1,42 @@
+// Package version сведения о сборке. Задаются при сборке:
+//
+//	go build -ldflags "-X github.com/RoGogDBD/GQLGo/internal/version.Version=v1.2.0 \
+//		-X github.com/RoGogDBD/GQLGo/internal/version.Commit=$(git rev-parse HEAD) \
+//		-X github.com/RoGogDBD/GQLGo/internal/version.BuildTime=$(date -u +%FT%TZ)"
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info сведения о сборке для /version.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"buildTime,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get сведения о сборке. Без ldflags коммит и время берутся из данных VCS,
+// которые go build записывает сам.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	return info
+}
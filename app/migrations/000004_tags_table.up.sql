@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS tags(
+    id UUID PRIMARY KEY,
+    name VARCHAR(32) NOT NULL,
+    slug VARCHAR(32) NOT NULL UNIQUE,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS post_tags(
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS post_tags_tag_post_idx ON post_tags(tag_id, post_id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS posts_created_id_idx;
+DROP INDEX IF EXISTS posts_author_status_idx;
+DROP INDEX IF EXISTS posts_scheduled_publish_at_idx;
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_status_check;
+ALTER TABLE posts DROP COLUMN IF EXISTS published_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS status;
@@ -0,0 +1,13 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'PUBLISHED';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS publish_at timestamptz;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS published_at timestamptz;
+
+ALTER TABLE posts ADD CONSTRAINT posts_status_check
+    CHECK (status IN ('DRAFT', 'SCHEDULED', 'PUBLISHED', 'ARCHIVED'));
+
+UPDATE posts SET published_at = created_at WHERE status = 'PUBLISHED' AND published_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS posts_scheduled_publish_at_idx ON posts(publish_at) WHERE status = 'SCHEDULED';
+CREATE INDEX IF NOT EXISTS posts_author_status_idx ON posts(author_id, status);
+-- Список постов и посты по тегу идут по (created_at, id).
+CREATE INDEX IF NOT EXISTS posts_created_id_idx ON posts(created_at, id);
@@ -0,0 +1,11 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS body_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS body_html TEXT NOT NULL DEFAULT '';
+
+-- Старые записи рендерились без санитайзера: кладем экранированный текст.
+UPDATE posts SET body_html = '<p>' || replace(replace(replace(replace(replace(body,
+    '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;') || '</p>'
+WHERE body_html = '';
+
+UPDATE comments SET body_html = '<p>' || replace(replace(replace(replace(replace(body,
+    '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;') || '</p>'
+WHERE body_html = '';
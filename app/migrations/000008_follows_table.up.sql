@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS follows(
+    follower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    followee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (follower_id, followee_id),
+    CONSTRAINT follows_not_self CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX IF NOT EXISTS follows_followee_created_idx ON follows(followee_id, created_at DESC, follower_id DESC);
+CREATE INDEX IF NOT EXISTS follows_follower_created_idx ON follows(follower_id, created_at DESC, followee_id DESC);
+
+CREATE INDEX IF NOT EXISTS posts_feed_idx ON posts(author_id, published_at DESC, id DESC) WHERE status = 'PUBLISHED';
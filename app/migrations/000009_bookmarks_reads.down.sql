@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS comments_post_created_idx;
+DROP TABLE IF EXISTS post_reads;
+DROP TABLE IF EXISTS bookmarks;
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS comments_moderation_state_check;
+ALTER TABLE comments DROP COLUMN IF EXISTS moderation_state;
+UPDATE comments SET children_count = (
+    SELECT count(*) FROM comments AS ch WHERE ch.parent_id = comments.id
+);
+
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_moderation_state_check;
+ALTER TABLE posts DROP COLUMN IF EXISTS moderation_state;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
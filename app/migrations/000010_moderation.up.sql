@@ -0,0 +1,31 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'USER';
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('USER', 'MODERATOR', 'ADMIN'));
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS moderation_state VARCHAR(16) NOT NULL DEFAULT 'VISIBLE';
+ALTER TABLE posts ADD CONSTRAINT posts_moderation_state_check CHECK (moderation_state IN ('VISIBLE', 'HIDDEN'));
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS moderation_state VARCHAR(16) NOT NULL DEFAULT 'VISIBLE';
+ALTER TABLE comments ADD CONSTRAINT comments_moderation_state_check CHECK (moderation_state IN ('VISIBLE', 'HIDDEN'));
+
+-- children_count считает только видимые ответы.
+UPDATE comments SET children_count = (
+    SELECT count(*) FROM comments AS ch
+    WHERE ch.parent_id = comments.id AND ch.moderation_state = 'VISIBLE'
+);
+
+CREATE TABLE IF NOT EXISTS reports(
+    id UUID PRIMARY KEY,
+    target_type VARCHAR(16) NOT NULL CHECK (target_type IN ('POST', 'COMMENT')),
+    target_id UUID NOT NULL,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(16) NOT NULL CHECK (reason IN ('SPAM', 'ABUSE', 'HARASSMENT', 'OFF_TOPIC', 'OTHER')),
+    note VARCHAR(500),
+    status VARCHAR(16) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'RESOLVED', 'DISMISSED')),
+    action VARCHAR(16) CHECK (action IN ('DISMISS', 'HIDE_CONTENT', 'LOCK_COMMENTS', 'SUSPEND_USER')),
+    resolved_by UUID REFERENCES users(id),
+    resolved_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS reports_status_created_idx ON reports(status, created_at DESC, id DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS reports_open_reporter_target_idx ON reports(reporter_id, target_id) WHERE status = 'OPEN';
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS reports_open_system_target_idx;
+DELETE FROM reports WHERE reporter_id IS NULL;
+ALTER TABLE reports ALTER COLUMN reporter_id SET NOT NULL;
+
+UPDATE comments SET moderation_state = 'HIDDEN' WHERE moderation_state = 'PENDING';
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS comments_moderation_state_check;
+ALTER TABLE comments ADD CONSTRAINT comments_moderation_state_check CHECK (moderation_state IN ('VISIBLE', 'HIDDEN'));
+
+UPDATE posts SET moderation_state = 'HIDDEN' WHERE moderation_state = 'PENDING';
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_moderation_state_check;
+ALTER TABLE posts ADD CONSTRAINT posts_moderation_state_check CHECK (moderation_state IN ('VISIBLE', 'HIDDEN'));
@@ -0,0 +1,8 @@
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_moderation_state_check;
+ALTER TABLE posts ADD CONSTRAINT posts_moderation_state_check CHECK (moderation_state IN ('VISIBLE', 'PENDING', 'HIDDEN'));
+
+ALTER TABLE comments DROP CONSTRAINT IF EXISTS comments_moderation_state_check;
+ALTER TABLE comments ADD CONSTRAINT comments_moderation_state_check CHECK (moderation_state IN ('VISIBLE', 'PENDING', 'HIDDEN'));
+
+ALTER TABLE reports ALTER COLUMN reporter_id DROP NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS reports_open_system_target_idx ON reports(target_id) WHERE status = 'OPEN' AND reporter_id IS NULL;
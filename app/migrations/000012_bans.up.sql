@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS bans(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(500) NOT NULL,
+    until timestamptz,
+    banned_by UUID REFERENCES users(id),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    lifted_at timestamptz,
+    lifted_by UUID REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS bans_user_active_idx ON bans(user_id, created_at DESC) WHERE lifted_at IS NULL;
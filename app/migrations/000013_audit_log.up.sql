@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS audit_log(
+    id UUID PRIMARY KEY,
+    actor_id VARCHAR(64),
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32),
+    target_id VARCHAR(64),
+    diff JSONB,
+    request_id VARCHAR(128),
+    ip VARCHAR(64),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_idx ON audit_log(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log(actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_target_idx ON audit_log(target_type, target_id, created_at DESC);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log: записи нельзя изменять или удалять';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,9 @@
+// Package migrations SQL-миграции, встроенные в бинарник.
+package migrations
+
+import "embed"
+
+// FS миграции Postgres в корне и SQLite в каталоге sqlite.
+//
+//go:embed *.sql sqlite/*.sql
+var FS embed.FS
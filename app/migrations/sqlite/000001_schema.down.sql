@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS bans;
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS post_reads;
+DROP TABLE IF EXISTS bookmarks;
+DROP TABLE IF EXISTS follows;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS mentions;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS users;
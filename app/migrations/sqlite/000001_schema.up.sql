@@ -0,0 +1,176 @@
+-- Схема SQLite соответствует Postgres после миграции 000013.
+-- Время хранится текстом в UTC, как его пишет bun: так оно правильно сравнивается.
+
+CREATE TABLE IF NOT EXISTS users(
+    id TEXT PRIMARY KEY,
+    username VARCHAR(15),
+    role VARCHAR(16) NOT NULL DEFAULT 'USER' CHECK (role IN ('USER', 'MODERATOR', 'ADMIN'))
+);
+
+INSERT INTO users(id, username) VALUES
+('11111111-1111-1111-1111-111111111111', 'ASDASd'),
+('22222222-2222-2222-2222-222222222222', 'asd'),
+('33333333-3333-3333-3333-333333333333', 'wevbwb')
+ON CONFLICT (id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS posts(
+    id TEXT PRIMARY KEY,
+    title VARCHAR(100) NOT NULL,
+    body VARCHAR(2000) NOT NULL,
+    body_html TEXT NOT NULL DEFAULT '',
+    comments_enabled BOOLEAN NOT NULL DEFAULT true,
+    author_id TEXT NOT NULL REFERENCES users(id),
+    status VARCHAR(16) NOT NULL DEFAULT 'PUBLISHED' CHECK (status IN ('DRAFT', 'SCHEDULED', 'PUBLISHED', 'ARCHIVED')),
+    moderation_state VARCHAR(16) NOT NULL DEFAULT 'VISIBLE' CHECK (moderation_state IN ('VISIBLE', 'PENDING', 'HIDDEN')),
+    publish_at TIMESTAMP,
+    published_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS posts_scheduled_publish_at_idx ON posts(publish_at) WHERE status = 'SCHEDULED';
+CREATE INDEX IF NOT EXISTS posts_author_status_idx ON posts(author_id, status);
+CREATE INDEX IF NOT EXISTS posts_created_id_idx ON posts(created_at, id);
+CREATE INDEX IF NOT EXISTS posts_feed_idx ON posts(author_id, published_at DESC, id DESC) WHERE status = 'PUBLISHED';
+
+CREATE TABLE IF NOT EXISTS comments(
+    id TEXT PRIMARY KEY,
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    author_id TEXT NOT NULL REFERENCES users(id),
+    parent_id TEXT REFERENCES comments(id) ON DELETE CASCADE,
+    body VARCHAR(2000) NOT NULL,
+    body_html TEXT NOT NULL DEFAULT '',
+    depth INTEGER NOT NULL DEFAULT 0,
+    children_count INTEGER NOT NULL DEFAULT 0,
+    moderation_state VARCHAR(16) NOT NULL DEFAULT 'VISIBLE' CHECK (moderation_state IN ('VISIBLE', 'PENDING', 'HIDDEN')),
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS comments_post_parent_created_idx ON comments(post_id, parent_id, created_at, id);
+CREATE INDEX IF NOT EXISTS comments_post_created_idx ON comments(post_id, created_at);
+
+CREATE TABLE IF NOT EXISTS tags(
+    id TEXT PRIMARY KEY,
+    name VARCHAR(32) NOT NULL,
+    slug VARCHAR(32) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE TABLE IF NOT EXISTS post_tags(
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag_id TEXT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS post_tags_tag_post_idx ON post_tags(tag_id, post_id);
+
+CREATE TABLE IF NOT EXISTS mentions(
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    author_id TEXT NOT NULL REFERENCES users(id),
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id TEXT REFERENCES comments(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS mentions_user_created_idx ON mentions(user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS notifications(
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('MENTION', 'REPLY', 'POST_COMMENT')),
+    actor_id TEXT NOT NULL REFERENCES users(id),
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id TEXT REFERENCES comments(id) ON DELETE CASCADE,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_created_idx ON notifications(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS notifications_user_unread_idx ON notifications(user_id) WHERE read_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS follows(
+    follower_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    followee_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    PRIMARY KEY (follower_id, followee_id),
+    CONSTRAINT follows_not_self CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX IF NOT EXISTS follows_followee_created_idx ON follows(followee_id, created_at DESC, follower_id DESC);
+CREATE INDEX IF NOT EXISTS follows_follower_created_idx ON follows(follower_id, created_at DESC, followee_id DESC);
+
+CREATE TABLE IF NOT EXISTS bookmarks(
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS bookmarks_user_created_idx ON bookmarks(user_id, created_at DESC, post_id DESC);
+
+CREATE TABLE IF NOT EXISTS post_reads(
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    last_read_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE TABLE IF NOT EXISTS reports(
+    id TEXT PRIMARY KEY,
+    target_type VARCHAR(16) NOT NULL CHECK (target_type IN ('POST', 'COMMENT')),
+    target_id TEXT NOT NULL,
+    reporter_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(16) NOT NULL CHECK (reason IN ('SPAM', 'ABUSE', 'HARASSMENT', 'OFF_TOPIC', 'OTHER')),
+    note VARCHAR(500),
+    status VARCHAR(16) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'RESOLVED', 'DISMISSED')),
+    action VARCHAR(16) CHECK (action IN ('DISMISS', 'HIDE_CONTENT', 'LOCK_COMMENTS', 'SUSPEND_USER')),
+    resolved_by TEXT REFERENCES users(id),
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS reports_status_created_idx ON reports(status, created_at DESC, id DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS reports_open_reporter_target_idx ON reports(reporter_id, target_id) WHERE status = 'OPEN';
+CREATE UNIQUE INDEX IF NOT EXISTS reports_open_system_target_idx ON reports(target_id) WHERE status = 'OPEN' AND reporter_id IS NULL;
+
+CREATE TABLE IF NOT EXISTS bans(
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(500) NOT NULL,
+    until TIMESTAMP,
+    banned_by TEXT REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    lifted_at TIMESTAMP,
+    lifted_by TEXT REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS bans_user_active_idx ON bans(user_id, created_at DESC) WHERE lifted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS audit_log(
+    id TEXT PRIMARY KEY,
+    actor_id VARCHAR(64),
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32),
+    target_id VARCHAR(64),
+    diff TEXT,
+    request_id VARCHAR(128),
+    ip VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_idx ON audit_log(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log(actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_target_idx ON audit_log(target_type, target_id, created_at DESC);
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log: записи нельзя изменять или удалять');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log: записи нельзя изменять или удалять');
+END;